@@ -1,14 +1,35 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"greenbone-case-study/internal/db"
 	"greenbone-case-study/pkg/handlers"
+	"greenbone-case-study/pkg/logging"
+	"greenbone-case-study/pkg/metrics"
 	"greenbone-case-study/pkg/models"
 	"greenbone-case-study/pkg/notifications"
 	"greenbone-case-study/pkg/services"
-	"log"
+	"greenbone-case-study/pkg/tracing"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// readHeaderTimeout bounds how long clients may take to send request headers
+	readHeaderTimeout = 10 * time.Second
+	// flushTimeout bounds exporting the remaining spans at shutdown
+	flushTimeout = 5 * time.Second
 )
 
 func main() {
@@ -17,29 +38,309 @@ func main() {
 	dbURL := getEnv("DATABASE_URL", "computers.db")
 	notificationURL := getEnv("NOTIFICATION_URL", "http://localhost:9090")
 	port := getEnv("PORT", "8080")
+	policyFile := os.Getenv("POLICY_FILE")
+	bootstrapAPIKey := os.Getenv("BOOTSTRAP_API_KEY")
+	notificationHealthURL := os.Getenv("NOTIFICATION_HEALTH_URL")
+	traceExporter := getEnv("OTEL_TRACES_EXPORTER", tracing.ExporterNone)
+	migrationMode := getEnv("DB_MIGRATE", migrateAuto)
+	strictIPAddresses := getBool("IPAM_STRICT", false)
+	purgeRetention := getDuration("PURGE_RETENTION", services.DefaultPurgeRetention)
+	if purgeRetention <= 0 {
+		fatal("Invalid PURGE_RETENTION", fmt.Errorf("%s is not positive", purgeRetention))
+	}
+	allowedOrigins, err := allowedOriginsFromEnv()
+	if err != nil {
+		fatal("Invalid CORS_ALLOWED_ORIGINS", err)
+	}
+
+	// Log structured records to stderr; this must happen before components
+	// that keep a logger are created
+	if _, err := logging.Setup(logging.Config{
+		Level:  getEnv("LOG_LEVEL", "info"),
+		Format: getEnv("LOG_FORMAT", logging.FormatJSON),
+	}); err != nil {
+		fatal("Failed to set up logging", err)
+	}
+
+	// "migrate up|down|status" changes or shows the schema and exits
+	if len(os.Args) > 1 {
+		if os.Args[1] != "migrate" {
+			fatal("Unknown command", fmt.Errorf("%q, expected migrate", os.Args[1]))
+		}
+		if err := runMigrate(os.Args[2:], dbURL, dbType); err != nil {
+			fatal("Migration failed", err)
+		}
+		return
+	}
+
+	// Requests are cancelled after the request timeout; the server timeouts
+	// bound slow clients, and the drain period how long shutdown waits for
+	// requests and notification deliveries in progress
+	requestTimeout := getDuration("REQUEST_TIMEOUT", 30*time.Second)
+	readTimeout := getDuration("HTTP_READ_TIMEOUT", 30*time.Second)
+	writeTimeout := getDuration("HTTP_WRITE_TIMEOUT", 60*time.Second)
+	idleTimeout := getDuration("HTTP_IDLE_TIMEOUT", 120*time.Second)
+	drainPeriod := getDuration("SHUTDOWN_TIMEOUT", 30*time.Second)
+
+	// ctx is cancelled by SIGINT or SIGTERM, which starts the shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Export traces to an OTLP collector or stdout, if configured
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Config{
+		Exporter:    traceExporter,
+		ServiceName: "computer-management-api",
+	})
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
 
 	// Initialize database
-	database, err := db.InitDatabase(dbURL, dbType)
+	database, err := db.Open(dbURL, dbType)
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		fatal("Failed to connect to database", err)
+	}
+	if err := prepareSchema(ctx, database, migrationMode); err != nil {
+		fatal("Failed to prepare database schema", err)
 	}
 
 	// Initialize dependencies
 	computerRepo := models.NewComputerRepository(database)
+	employeeRepo := models.NewEmployeeRepository(database)
+	outboxRepo := models.NewOutboxRepository(database)
+	policyRepo := models.NewPolicyRepository(database)
+	subnetRepo := models.NewSubnetRepository(database)
+	auditRepo := models.NewAuditRepository(database)
+	apiKeyRepo := models.NewAPIKeyRepository(database)
 	notificationClient := notifications.NewNotificationClient(notificationURL)
-	computerService := services.NewComputerService(computerRepo, notificationClient)
+	computerService := services.NewTracedComputerService(services.NewComputerServiceWithOptions(computerRepo, employeeRepo, policyRepo,
+		services.ComputerServiceOptions{StrictIPAddresses: strictIPAddresses, PurgeRetention: purgeRetention}))
+	employeeService := services.NewEmployeeService(employeeRepo, computerRepo)
+	outboxService := services.NewOutboxService(outboxRepo)
+	policyService := services.NewPolicyService(policyRepo)
+	subnetService := services.NewSubnetService(subnetRepo, computerRepo)
+	auditService := services.NewAuditService(auditRepo)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo)
+	inventoryService := services.NewInventoryService(computerRepo, employeeRepo, policyRepo)
+	healthService := services.NewHealthService(healthChecks(database, notificationHealthURL)...)
+
+	// Report inventory gauges on /metrics
+	metrics.Registry.MustRegister(metrics.NewInventoryCollector(inventoryService))
+
+	// Apply assignment policies from the configuration file, if any
+	if policyFile != "" {
+		if err := loadPolicies(ctx, policyService, policyFile); err != nil {
+			fatal("Failed to load policies", err)
+		}
+	}
+
+	// Create or replace the admin key used to set up further API keys, if any
+	if bootstrapAPIKey != "" {
+		if err := apiKeyService.EnsureKey(ctx, "bootstrap", models.RoleAdmin, bootstrapAPIKey); err != nil {
+			fatal("Failed to set up bootstrap API key", err)
+		}
+	}
+
+	// Accept JWTs from the SSO provider if it is configured
+	tokenAuthenticator, err := jwtAuthenticatorFromEnv()
+	if err != nil {
+		fatal("Failed to configure JWT authentication", err)
+	}
+
+	// Deliver queued notifications in the background. The dispatcher is
+	// stopped by Shutdown rather than the signal, so that deliveries in
+	// progress can finish during the drain period.
+	dispatcher := services.NewOutboxDispatcher(outboxRepo, notificationClient)
+	go dispatcher.Run(context.Background())
 
 	// Setup routes
-	router := handlers.SetupRoutes(computerService)
+	router := handlers.SetupRoutes(computerService, employeeService, outboxService, policyService, subnetService, auditService, apiKeyService, healthService, tokenAuthenticator, requestTimeout, allowedOrigins)
 
 	// Start server
-	log.Printf("Starting server on port %s", port)
-	log.Printf("Database type: %s", dbType)
-	log.Printf("Notification URL: %s", notificationURL)
+	slog.Info("Starting server",
+		"port", port,
+		"database_type", dbType,
+		"notification_url", notificationURL,
+		"trace_exporter", traceExporter,
+		"request_timeout", requestTimeout,
+		"strict_ip_addresses", strictIPAddresses,
+		"purge_retention", purgeRetention,
+		"cors_allowed_origins", allowedOrigins,
+	)
+
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           router,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
 
-	if err := http.ListenAndServe(":"+port, router); err != nil {
-		log.Fatal("Server failed to start:", err)
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- server.ListenAndServe() }()
+
+	select {
+	case err := <-serverErr:
+		fatal("Server failed to start", err)
+	case <-ctx.Done():
+	}
+
+	// A second signal terminates the process without waiting
+	stop()
+	slog.Info("Shutting down server", "drain_period", drainPeriod)
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), drainPeriod)
+	defer cancel()
+
+	// Stop accepting connections and wait for requests in progress; their
+	// notifications are already stored in the outbox
+	if err := server.Shutdown(drainCtx); err != nil {
+		slog.Warn("Closing connections with requests in progress", "error", err)
+		server.Close()
+	}
+	if err := dispatcher.Shutdown(drainCtx); err != nil {
+		slog.Warn("Cancelled notification deliveries in progress, they are retried after restart", "error", err)
+	}
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Warn("Failed to flush traces", "error", err)
+	}
+
+	if err := db.Close(database); err != nil {
+		slog.Warn("Failed to close database connections", "error", err)
+	}
+	slog.Info("Server stopped")
+}
+
+// loadPolicies applies the JSON array of policies in path
+func loadPolicies(ctx context.Context, service models.PolicyService, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var policies []models.AssignmentPolicy
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return err
+	}
+	return service.ApplyPolicies(ctx, policies)
+}
+
+// healthChecks returns the readiness checks: the database and its schema are
+// required, the notification service is probed at notificationHealthURL if it
+// is set. Notifications wait in the outbox while it is down, so it is optional.
+func healthChecks(database *gorm.DB, notificationHealthURL string) []models.HealthCheck {
+	checks := []models.HealthCheck{
+		{Name: "database", Required: true, Check: func(ctx context.Context) error {
+			return db.Ping(ctx, database)
+		}},
+		{Name: "schema", Required: true, Check: func(ctx context.Context) error {
+			return db.CheckSchema(ctx, database)
+		}},
+	}
+	if notificationHealthURL != "" {
+		checks = append(checks, models.HealthCheck{Name: "notifications", Check: func(ctx context.Context) error {
+			return notifications.Probe(ctx, notificationHealthURL)
+		}})
+	}
+	return checks
+}
+
+// jwtAuthenticatorFromEnv creates the JWT authenticator configured by the
+// JWT_* variables, or returns nil if neither JWT_JWKS_URL nor JWT_KEY_FILE is set
+func jwtAuthenticatorFromEnv() (models.TokenAuthenticator, error) {
+	config := services.JWTConfig{
+		JWKSURL:   os.Getenv("JWT_JWKS_URL"),
+		KeyFile:   os.Getenv("JWT_KEY_FILE"),
+		Issuer:    os.Getenv("JWT_ISSUER"),
+		Audience:  os.Getenv("JWT_AUDIENCE"),
+		RoleClaim: os.Getenv("JWT_ROLE_CLAIM"),
+	}
+	if config.JWKSURL == "" && config.KeyFile == "" {
+		return nil, nil
+	}
+
+	// JWT_ROLE_MAPPING lists claim values and roles, e.g. "it-admins=admin,helpdesk=operator"
+	if mapping := os.Getenv("JWT_ROLE_MAPPING"); mapping != "" {
+		config.RoleMapping = make(map[string]string)
+		for _, pair := range strings.Split(mapping, ",") {
+			value, role, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid JWT_ROLE_MAPPING entry %q, expected value=role", pair)
+			}
+			config.RoleMapping[strings.TrimSpace(value)] = strings.TrimSpace(role)
+		}
+	}
+
+	if leeway := os.Getenv("JWT_LEEWAY"); leeway != "" {
+		d, err := time.ParseDuration(leeway)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT_LEEWAY: %w", err)
+		}
+		config.Leeway = d
+	}
+
+	return services.NewJWTAuthenticator(config)
+}
+
+// allowedOriginsFromEnv returns the origins listed in CORS_ALLOWED_ORIGINS,
+// e.g. "https://inventory.example.com,http://localhost:3000", or "*" for any
+func allowedOriginsFromEnv() ([]string, error) {
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		origin = strings.TrimSpace(origin)
+		switch origin {
+		case "":
+			continue
+		case "*":
+			origins = append(origins, origin)
+			continue
+		}
+		// Browsers send origins as scheme and host only
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+			return nil, fmt.Errorf("invalid origin %q, expected scheme://host[:port]", origin)
+		}
+		origins = append(origins, u.Scheme+"://"+u.Host)
+	}
+	return origins, nil
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+// getDuration returns the duration in the environment variable key, such as
+// "30s", or defaultValue if it is not set
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		fatal("Invalid "+key, err)
+	}
+	return d
+}
+
+// getBool returns the boolean in the environment variable key, such as
+// "true", or defaultValue if it is not set
+func getBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		fatal("Invalid "+key, err)
 	}
+	return b
 }
 
 func getEnv(key, defaultValue string) string {
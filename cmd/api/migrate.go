@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"greenbone-case-study/internal/db"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Startup modes of DB_MIGRATE
+const (
+	// migrateAuto applies pending migrations at startup
+	migrateAuto = "auto"
+	// migrateCheck refuses to start until migrations are applied with
+	// "migrate up", for deployments that migrate in a separate step
+	migrateCheck = "check"
+)
+
+// prepareSchema applies pending migrations or checks that there are none,
+// depending on mode
+func prepareSchema(ctx context.Context, database *gorm.DB, mode string) error {
+	switch mode {
+	case migrateAuto:
+		_, err := db.MigrateUp(ctx, database)
+		return err
+	case migrateCheck:
+		if err := db.CheckSchema(ctx, database); err != nil {
+			return fmt.Errorf("%w; run \"migrate up\" first", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported DB_MIGRATE mode %q, expected %s or %s", mode, migrateAuto, migrateCheck)
+	}
+}
+
+// runMigrate runs the migrate subcommand: "up" applies pending migrations,
+// "down [n]" reverts the latest n migrations (1 by default) and "status"
+// lists all migrations
+func runMigrate(args []string, dbURL, dbType string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [n]|status")
+	}
+
+	database, err := db.Open(dbURL, dbType)
+	if err != nil {
+		return err
+	}
+	defer db.Close(database)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp(ctx, database)
+		for _, migration := range applied {
+			fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("Schema is up to date")
+		}
+		return err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		}
+		reverted, err := db.MigrateDown(ctx, database, steps)
+		for _, migration := range reverted {
+			fmt.Printf("Reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(reverted) == 0 {
+			fmt.Println("No migrations to revert")
+		}
+		return err
+
+	case "status":
+		states, err := db.MigrationStatus(ctx, database)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, state := range states {
+			appliedAt := "pending"
+			if state.AppliedAt != nil {
+				appliedAt = state.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", state.Version, state.Name, appliedAt)
+		}
+		return w.Flush()
+
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", args[0])
+	}
+}
@@ -0,0 +1,39 @@
+// Package callbacks registers gorm callbacks around each of gorm's
+// operations, for plugins that observe every statement
+package callbacks
+
+import "gorm.io/gorm"
+
+// registrar is a position in one of gorm's callback chains
+type registrar interface {
+	Register(name string, fn func(*gorm.DB)) error
+}
+
+// RegisterAround registers a callback before and after every other callback
+// of each of gorm's operations, named <prefix>:before_<operation> and
+// <prefix>:after_<operation>. before and after return the callback for an
+// operation.
+func RegisterAround(db *gorm.DB, prefix string, before, after func(operation string) func(*gorm.DB)) error {
+	callbacks := db.Callback()
+	operations := []struct {
+		name          string
+		before, after registrar
+	}{
+		{"create", callbacks.Create().Before("*"), callbacks.Create().After("*")},
+		{"query", callbacks.Query().Before("*"), callbacks.Query().After("*")},
+		{"update", callbacks.Update().Before("*"), callbacks.Update().After("*")},
+		{"delete", callbacks.Delete().Before("*"), callbacks.Delete().After("*")},
+		{"row", callbacks.Row().Before("*"), callbacks.Row().After("*")},
+		{"raw", callbacks.Raw().Before("*"), callbacks.Raw().After("*")},
+	}
+
+	for _, operation := range operations {
+		if err := operation.before.Register(prefix+":before_"+operation.name, before(operation.name)); err != nil {
+			return err
+		}
+		if err := operation.after.Register(prefix+":after_"+operation.name, after(operation.name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
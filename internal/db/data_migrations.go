@@ -0,0 +1,37 @@
+package db
+
+import (
+	"greenbone-case-study/pkg/models"
+
+	"gorm.io/gorm"
+)
+
+// dataMigrations complete the migrations of the same version, on every
+// dialect. They must not use the models, whose schema may be newer.
+var dataMigrations = map[int]func(tx *gorm.DB) error{
+	2: backfillIPNumbers,
+}
+
+// backfillIPNumbers sets the IP number of computers stored before it existed.
+// Unparsable addresses, kept by the upgrade from AutoMigrate, keep an empty
+// number.
+func backfillIPNumbers(tx *gorm.DB) error {
+	var computers []struct {
+		ID        uint
+		IPAddress string
+	}
+	return tx.Table("computers").Select("id", "ip_address").Where("ip_number = ''").
+		FindInBatches(&computers, 500, func(batch *gorm.DB, _ int) error {
+			for _, computer := range computers {
+				number := models.IPNumber(computer.IPAddress)
+				if number == "" {
+					continue
+				}
+				err := tx.Table("computers").Where("id = ?", computer.ID).UpdateColumn("ip_number", number).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
@@ -1,38 +1,105 @@
 package db
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"strings"
+	"time"
 
-	"greenbone-case-study/pkg/models"
+	"greenbone-case-study/pkg/metrics"
+	"greenbone-case-study/pkg/tracing"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-// InitDatabase initializes the database connection
-func InitDatabase(databaseURL string, dbType string) (*gorm.DB, error) {
+// slowQueryThreshold is the duration above which statements are logged as slow
+const slowQueryThreshold = 200 * time.Millisecond
+
+// Open connects to the database without changing its schema
+func Open(databaseURL string, dbType string) (*gorm.DB, error) {
 	var db *gorm.DB
 	var err error
 
+	// Driver errors such as unique violations are translated to gorm errors.
+	// Failed and slow statements are logged with the context they ran in.
+	config := &gorm.Config{TranslateError: true, Logger: newGormLogger(slowQueryThreshold)}
+
 	switch dbType {
 	case "postgres":
-		db, err = gorm.Open(postgres.Open(databaseURL), &gorm.Config{})
+		db, err = gorm.Open(postgres.Open(databaseURL), config)
 	case "sqlite":
-		db, err = gorm.Open(sqlite.Open(databaseURL), &gorm.Config{})
+		db, err = gorm.Open(sqlite.Open(withSQLiteOptions(databaseURL)), config)
 	default:
-		log.Fatal("Unsupported database type")
+		return nil, fmt.Errorf("unsupported database type %q", dbType)
 	}
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Auto-migrate the schema
-	err = db.AutoMigrate(&models.Computer{})
-	if err != nil {
+	// Record statement durations for the Prometheus metrics and trace statements
+	if err := db.Use(metrics.NewGormPlugin()); err != nil {
 		return nil, err
 	}
+	if err := db.Use(tracing.NewGormPlugin()); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
 
+// InitDatabase connects to the database and applies pending migrations
+func InitDatabase(databaseURL string, dbType string) (*gorm.DB, error) {
+	db, err := Open(databaseURL, dbType)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := MigrateUp(context.Background(), db); err != nil {
+		return nil, err
+	}
 	return db, nil
 }
+
+// sqliteOptions are added to SQLite connection strings: SQLite disables
+// foreign keys by default, and transactions must take the write lock when they
+// begin so a count followed by a write cannot interleave with another writer
+var sqliteOptions = [][2]string{
+	{"_foreign_keys", "on"},
+	{"_txlock", "immediate"},
+	{"_busy_timeout", "5000"},
+}
+
+// withSQLiteOptions adds sqliteOptions that are not already set to a DSN
+func withSQLiteOptions(dsn string) string {
+	for _, option := range sqliteOptions {
+		if strings.Contains(dsn, option[0]+"=") {
+			continue
+		}
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		dsn += separator + option[0] + "=" + option[1]
+	}
+	return dsn
+}
+
+// Close closes the connections of the pool of db
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// Ping checks that the database accepts connections
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
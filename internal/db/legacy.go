@@ -0,0 +1,209 @@
+package db
+
+import (
+	"greenbone-case-study/pkg/models"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The legacy models freeze the schema of the first migration, which
+// AutoMigrate created before versioned migrations. Later changes of the models
+// are made by migrations and must not be applied here.
+
+type legacyEmployee struct {
+	Abbreviation string `gorm:"primaryKey;size:3"`
+	Name         string `gorm:"not null;size:100"`
+	Email        string `gorm:"size:254"`
+	Department   string `gorm:"size:100"`
+	Active       bool   `gorm:"not null;default:true"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (legacyEmployee) TableName() string { return "employees" }
+
+type legacyComputer struct {
+	ID                   uint            `gorm:"primaryKey;autoIncrement"`
+	MACAddress           string          `gorm:"not null;size:17;uniqueIndex:idx_computers_mac_address_active,where:deleted_at IS NULL"`
+	ComputerName         string          `gorm:"not null;size:100"`
+	IPAddress            string          `gorm:"not null;size:45"`
+	EmployeeAbbreviation *string         `gorm:"size:3"`
+	Description          string          `gorm:"size:500"`
+	Version              uint            `gorm:"not null;default:1"`
+	Employee             *legacyEmployee `gorm:"foreignKey:EmployeeAbbreviation;references:Abbreviation;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	DeletedAt            gorm.DeletedAt `gorm:"index"`
+}
+
+func (legacyComputer) TableName() string { return "computers" }
+
+type legacyOutboxMessage struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement"`
+	Kind          string    `gorm:"not null;size:50"`
+	Payload       string    `gorm:"not null;type:text"`
+	Status        string    `gorm:"not null;size:20;index:idx_outbox_due,priority:1"`
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"not null;index:idx_outbox_due,priority:2"`
+	LastError     string    `gorm:"size:1000"`
+	RequestID     string    `gorm:"size:128"`
+	TraceParent   string    `gorm:"size:55"`
+	DeliveredAt   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (legacyOutboxMessage) TableName() string { return "outbox_messages" }
+
+type legacyAssignmentPolicy struct {
+	ID         uint                    `gorm:"primaryKey;autoIncrement"`
+	Scope      string                  `gorm:"not null;size:20;uniqueIndex:idx_policy_scope"`
+	Subject    string                  `gorm:"not null;size:100;uniqueIndex:idx_policy_scope"`
+	Thresholds []legacyPolicyThreshold `gorm:"foreignKey:PolicyID;constraint:OnDelete:CASCADE"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (legacyAssignmentPolicy) TableName() string { return "assignment_policies" }
+
+type legacyPolicyThreshold struct {
+	ID       uint   `gorm:"primaryKey;autoIncrement"`
+	PolicyID uint   `gorm:"not null;index"`
+	Count    int    `gorm:"not null"`
+	Action   string `gorm:"not null;size:10"`
+	Level    string `gorm:"size:20"`
+}
+
+func (legacyPolicyThreshold) TableName() string { return "policy_thresholds" }
+
+type legacyAuditEntry struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	EntityType string    `gorm:"not null;size:50;index:idx_audit_entity,priority:1"`
+	EntityID   uint      `gorm:"not null;index:idx_audit_entity,priority:2"`
+	Action     string    `gorm:"not null;size:20"`
+	Actor      string    `gorm:"not null;size:255;index"`
+	RequestID  string    `gorm:"size:128"`
+	Changes    string    `gorm:"not null;type:text"`
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+func (legacyAuditEntry) TableName() string { return "audit_entries" }
+
+type legacyAPIKey struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	Name       string `gorm:"not null;size:100;uniqueIndex"`
+	Role       string `gorm:"not null;size:20"`
+	Prefix     string `gorm:"not null;size:16"`
+	Hash       string `gorm:"not null;size:64;uniqueIndex"`
+	CreatedAt  time.Time
+	RotatedAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func (legacyAPIKey) TableName() string { return "api_keys" }
+
+// upgradeLegacySchema brings a schema created by AutoMigrate, before
+// versioned migrations, up to the first migration. Employees are created
+// before the computers foreign key so existing assignments can be backfilled.
+func upgradeLegacySchema(db *gorm.DB) error {
+	err := db.AutoMigrate(&legacyEmployee{})
+	if err != nil {
+		return err
+	}
+	err = migrateEmployeeAbbreviations(db)
+	if err != nil {
+		return err
+	}
+	err = migrateMACAddressUniqueness(db)
+	if err != nil {
+		return err
+	}
+	err = db.AutoMigrate(&legacyComputer{}, &legacyOutboxMessage{}, &legacyAssignmentPolicy{},
+		&legacyPolicyThreshold{}, &legacyAuditEntry{}, &legacyAPIKey{})
+	if err != nil {
+		return err
+	}
+	return migrateAddresses(db)
+}
+
+// migrateEmployeeAbbreviations creates employee rows for abbreviations that
+// are only referenced by computers
+func migrateEmployeeAbbreviations(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&legacyComputer{}) {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("UPDATE computers SET employee_abbreviation = NULL WHERE employee_abbreviation = ''").Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Exec(`INSERT INTO employees (abbreviation, name, active, created_at, updated_at)
+			SELECT DISTINCT c.employee_abbreviation, c.employee_abbreviation, ?, ?, ?
+			FROM computers c
+			WHERE c.employee_abbreviation IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM employees e WHERE e.abbreviation = c.employee_abbreviation)`,
+			true, now, now).Error
+	})
+}
+
+// migrateMACAddressUniqueness drops the unique constraint on mac_address that
+// predates soft delete, so AutoMigrate can replace it with a unique index over
+// computers that are not deleted. On SQLite AutoMigrate rebuilds the table itself.
+func migrateMACAddressUniqueness(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" || !db.Migrator().HasTable(&legacyComputer{}) {
+		return nil
+	}
+
+	for _, constraint := range []string{"computers_mac_address_key", "uni_computers_mac_address"} {
+		if err := db.Exec("ALTER TABLE computers DROP CONSTRAINT IF EXISTS " + constraint).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateAddresses rewrites MAC and IP addresses stored before they were
+// canonicalised. Rows that cannot be parsed or would duplicate another
+// computer's MAC address are logged and left unchanged. Each row is updated
+// in a savepoint, as a failed statement aborts the whole transaction of the
+// migration on PostgreSQL.
+func migrateAddresses(db *gorm.DB) error {
+	var computers []legacyComputer
+	return db.Unscoped().Select("id", "mac_address", "ip_address").
+		FindInBatches(&computers, 500, func(tx *gorm.DB, batch int) error {
+			for _, computer := range computers {
+				mac, err := models.ParseMACAddress(computer.MACAddress)
+				if err != nil {
+					slog.Warn("Keeping MAC address", "computer_id", computer.ID, "error", err)
+					mac = computer.MACAddress
+				}
+				ip, err := models.ParseIPAddress(computer.IPAddress)
+				if err != nil {
+					slog.Warn("Keeping IP address", "computer_id", computer.ID, "error", err)
+					ip = computer.IPAddress
+				}
+				if mac == computer.MACAddress && ip == computer.IPAddress {
+					continue
+				}
+
+				err = tx.Session(&gorm.Session{NewDB: true}).Transaction(func(tx *gorm.DB) error {
+					return tx.Unscoped().Model(&legacyComputer{}).Where("id = ?", computer.ID).
+						UpdateColumns(map[string]interface{}{
+							"mac_address": mac,
+							"ip_address":  ip,
+							"version":     gorm.Expr("version + 1"),
+						}).Error
+				})
+				if err != nil {
+					slog.Warn("Failed to canonicalise addresses", "computer_id", computer.ID, "error", err)
+				}
+			}
+			return nil
+		}).Error
+}
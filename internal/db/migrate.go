@@ -0,0 +1,330 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationFiles holds the SQL migrations of each dialect, named
+// <version>_<name>.up.sql and <version>_<name>.down.sql
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationTable records the applied migrations
+const migrationTable = "schema_migrations"
+
+// migrationLockID identifies the PostgreSQL advisory lock that serialises
+// migrations of several instances
+const migrationLockID = 7316202411
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ErrSchemaNotMigrated is returned by CheckSchema if migrations are pending
+var ErrSchemaNotMigrated = errors.New("database schema is not migrated")
+
+// Migration is a numbered schema change with the SQL to apply and revert it.
+// Data, if set, runs after Up in the same transaction, for data changes
+// SQL cannot express.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+	Data    func(tx *gorm.DB) error
+}
+
+// MigrationState is a migration and when it was applied, if it was
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// appliedMigration is a row of migrationTable
+type appliedMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null;size:100"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName names the table of applied migrations
+func (appliedMigration) TableName() string {
+	return migrationTable
+}
+
+// Migrations returns the migrations of the dialect of db, ordered by version
+func Migrations(db *gorm.DB) ([]Migration, error) {
+	dialect := db.Dialector.Name()
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for %s: %w", dialect, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: match[2], Data: dataMigrations[version]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, migration.Name, match[2])
+		}
+
+		data, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MigrationStatus returns every known migration and when it was applied
+func MigrationStatus(ctx context.Context, db *gorm.DB) ([]MigrationState, error) {
+	migrations, err := Migrations(db)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, len(migrations))
+	for i, migration := range migrations {
+		states[i] = MigrationState{Migration: migration}
+		if row, exists := applied[migration.Version]; exists {
+			appliedAt := row.AppliedAt
+			states[i].AppliedAt = &appliedAt
+		}
+	}
+	return states, nil
+}
+
+// MigrateUp applies all pending migrations in order, each in its own
+// transaction, and returns the ones it applied. Databases created by
+// AutoMigrate before versioned migrations are upgraded and baselined first.
+func MigrateUp(ctx context.Context, db *gorm.DB) ([]Migration, error) {
+	migrations, err := Migrations(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationTable(ctx, db, migrations); err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range migrations {
+		ran := false
+		err := migrationTransaction(ctx, db, func(tx *gorm.DB) error {
+			var count int64
+			if err := tx.Model(&appliedMigration{}).Where("version = ?", migration.Version).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+
+			if err := execScript(tx, migration.Up); err != nil {
+				return err
+			}
+			if migration.Data != nil {
+				if err := migration.Data(tx); err != nil {
+					return err
+				}
+			}
+			ran = true
+			return tx.Create(&appliedMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		if ran {
+			slog.InfoContext(ctx, "Applied migration", "version", migration.Version, "name", migration.Name)
+			applied = append(applied, migration)
+		}
+	}
+	return applied, nil
+}
+
+// MigrateDown reverts the latest steps applied migrations, each in its own
+// transaction, and returns the ones it reverted
+func MigrateDown(ctx context.Context, db *gorm.DB, steps int) ([]Migration, error) {
+	states, err := MigrationStatus(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(states) - 1; i >= 0 && len(reverted) < steps; i-- {
+		migration := states[i].Migration
+		if states[i].AppliedAt == nil {
+			continue
+		}
+
+		err := migrationTransaction(ctx, db, func(tx *gorm.DB) error {
+			if err := execScript(tx, migration.Down); err != nil {
+				return err
+			}
+			return tx.Where("version = ?", migration.Version).Delete(&appliedMigration{}).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("reverting migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		slog.InfoContext(ctx, "Reverted migration", "version", migration.Version, "name", migration.Name)
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
+
+// CheckSchema returns an error wrapping ErrSchemaNotMigrated if migrations of
+// this binary are pending, or if the database has migrations it does not know,
+// which means it was migrated by a newer version
+func CheckSchema(ctx context.Context, db *gorm.DB) error {
+	migrations, err := Migrations(db)
+	if err != nil {
+		return err
+	}
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[int]bool, len(migrations))
+	pending := 0
+	for _, migration := range migrations {
+		known[migration.Version] = true
+		if _, exists := applied[migration.Version]; !exists {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: %d of %d migrations pending", ErrSchemaNotMigrated, pending, len(migrations))
+	}
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("%w: migration %d is unknown to this version", ErrSchemaNotMigrated, version)
+		}
+	}
+	return nil
+}
+
+// appliedMigrations returns the rows of migrationTable by version, or none if
+// the table does not exist yet
+func appliedMigrations(ctx context.Context, db *gorm.DB) (map[int]appliedMigration, error) {
+	db = db.WithContext(ctx)
+	if !db.Migrator().HasTable(&appliedMigration{}) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return map[int]appliedMigration{}, nil
+	}
+
+	var rows []appliedMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]appliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// ensureMigrationTable creates migrationTable. If the database already has
+// tables created by AutoMigrate, they are brought up to the schema of the
+// first migration, which is then recorded as applied.
+func ensureMigrationTable(ctx context.Context, db *gorm.DB, migrations []Migration) error {
+	db = db.WithContext(ctx)
+	if db.Migrator().HasTable(&appliedMigration{}) {
+		return nil
+	}
+
+	return migrationTransaction(ctx, db, func(tx *gorm.DB) error {
+		if tx.Migrator().HasTable(&appliedMigration{}) {
+			return nil
+		}
+		if err := tx.Migrator().CreateTable(&appliedMigration{}); err != nil {
+			return err
+		}
+		if len(migrations) == 0 || !tx.Migrator().HasTable("computers") {
+			return nil
+		}
+
+		baseline := migrations[0]
+		slog.InfoContext(ctx, "Upgrading schema created by AutoMigrate", "baseline_version", baseline.Version)
+		if err := upgradeLegacySchema(tx); err != nil {
+			return fmt.Errorf("failed to upgrade schema created by AutoMigrate: %w", err)
+		}
+		return tx.Create(&appliedMigration{Version: baseline.Version, Name: baseline.Name, AppliedAt: time.Now().UTC()}).Error
+	})
+}
+
+// migrationTransaction runs fn in a transaction. On PostgreSQL it holds an
+// advisory lock, so concurrent instances migrate one after the other; SQLite
+// transactions take the write lock when they begin.
+func migrationTransaction(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLockID).Error; err != nil {
+				return err
+			}
+		}
+		return fn(tx)
+	})
+}
+
+// execScript runs the statements of a migration file one by one. Statements
+// end with a semicolon at the end of a line; comment lines are skipped.
+func execScript(tx *gorm.DB, script string) error {
+	var statement strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		statement.WriteString(line)
+		statement.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			if err := tx.Exec(statement.String()).Error; err != nil {
+				return err
+			}
+			statement.Reset()
+		}
+	}
+	if strings.TrimSpace(statement.String()) != "" {
+		return tx.Exec(statement.String()).Error
+	}
+	return nil
+}
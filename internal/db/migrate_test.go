@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"greenbone-case-study/pkg/models"
+	"net/netip"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// schemaModels must all be backed by the schema the migrations create
+var schemaModels = []interface{}{
+	&models.Employee{},
+	&models.Computer{},
+	&models.OutboxMessage{},
+	&models.AssignmentPolicy{},
+	&models.PolicyThreshold{},
+	&models.AuditEntry{},
+	&models.APIKey{},
+	&models.Subnet{},
+}
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	database, err := Open(filepath.Join(t.TempDir(), "computers.db"), "sqlite")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { Close(database) })
+	return database
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	database := openTestDB(t)
+	ctx := context.Background()
+
+	if err := CheckSchema(ctx, database); !errors.Is(err, ErrSchemaNotMigrated) {
+		t.Fatalf("Expected ErrSchemaNotMigrated before migrating, got: %v", err)
+	}
+
+	migrations, err := Migrations(database)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	applied, err := MigrateUp(ctx, database)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Fatalf("Expected %d migrations to be applied, got %d", len(migrations), len(applied))
+	}
+	if err := CheckSchema(ctx, database); err != nil {
+		t.Errorf("Expected a migrated schema, got: %v", err)
+	}
+	if applied, _ := MigrateUp(ctx, database); len(applied) != 0 {
+		t.Errorf("Expected no migrations to be applied twice, got %d", len(applied))
+	}
+
+	// Every column and index of the models exists
+	migrator := database.Migrator()
+	for _, model := range schemaModels {
+		stmt := &gorm.Statement{DB: database}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatalf("Failed to parse %T: %v", model, err)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !migrator.HasColumn(model, field.DBName) {
+				t.Errorf("Expected column %s.%s", stmt.Schema.Table, field.DBName)
+			}
+		}
+		for _, index := range stmt.Schema.ParseIndexes() {
+			if !migrator.HasIndex(model, index.Name) {
+				t.Errorf("Expected index %s on %s", index.Name, stmt.Schema.Table)
+			}
+		}
+	}
+
+	reverted, err := MigrateDown(ctx, database, len(migrations))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(reverted) != len(migrations) || reverted[0].Version != migrations[len(migrations)-1].Version {
+		t.Errorf("Expected all migrations to be reverted latest first, got %+v", reverted)
+	}
+	for _, model := range schemaModels {
+		if migrator.HasTable(model) {
+			t.Errorf("Expected the table of %T to be dropped", model)
+		}
+	}
+}
+
+func TestMigrateUpBaselinesAutoMigrateSchema(t *testing.T) {
+	database := openTestDB(t)
+	ctx := context.Background()
+
+	// A database created by AutoMigrate, with an address stored before
+	// addresses were canonicalised
+	if err := database.AutoMigrate(&legacyEmployee{}, &legacyComputer{}); err != nil {
+		t.Fatalf("Failed to create legacy schema: %v", err)
+	}
+	computer := &legacyComputer{MACAddress: "00-11-22-AA-BB-CC", ComputerName: "Legacy", IPAddress: "10.0.0.1"}
+	if err := database.Create(computer).Error; err != nil {
+		t.Fatalf("Failed to create computer: %v", err)
+	}
+	// An address stored before addresses were validated
+	invalid := &legacyComputer{MACAddress: "00:11:22:aa:bb:cd", ComputerName: "Unparsable", IPAddress: "not-an-ip"}
+	if err := database.Create(invalid).Error; err != nil {
+		t.Fatalf("Failed to create computer: %v", err)
+	}
+
+	if _, err := MigrateUp(ctx, database); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := CheckSchema(ctx, database); err != nil {
+		t.Errorf("Expected a migrated schema, got: %v", err)
+	}
+
+	states, err := MigrationStatus(ctx, database)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if states[0].AppliedAt == nil {
+		t.Error("Expected the first migration to be recorded as applied")
+	}
+	if !database.Migrator().HasTable(&models.APIKey{}) {
+		t.Error("Expected missing tables to be created")
+	}
+
+	var stored models.Computer
+	database.First(&stored, computer.ID)
+	if stored.MACAddress != "00:11:22:aa:bb:cc" {
+		t.Errorf("Expected the MAC address to be canonicalised, got %s", stored.MACAddress)
+	}
+	if stored.IPNumber != "40a000001" {
+		t.Errorf("Expected the IP number to be backfilled, got %q", stored.IPNumber)
+	}
+
+	// The unparsable address is kept without a number and is in no network
+	var unparsable models.Computer
+	database.First(&unparsable, invalid.ID)
+	if unparsable.IPAddress != "not-an-ip" || unparsable.IPNumber != "" {
+		t.Errorf("Expected the unparsable address to be kept, got %q with number %q", unparsable.IPAddress, unparsable.IPNumber)
+	}
+	page, err := models.NewComputerRepository(database).GetAll(ctx, models.ComputerQueryOptions{IPNetwork: netip.MustParsePrefix("10.0.0.0/8"), Limit: 10})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != computer.ID {
+		t.Errorf("Expected only the valid address in the network, got %+v", page.Items)
+	}
+}
+
+func TestMigrateUpKeepsCollidingLegacyMACAddresses(t *testing.T) {
+	database := openTestDB(t)
+	ctx := context.Background()
+
+	// Two spellings of one MAC address, and a computer stored after them
+	if err := database.AutoMigrate(&legacyEmployee{}, &legacyComputer{}); err != nil {
+		t.Fatalf("Failed to create legacy schema: %v", err)
+	}
+	computers := []*legacyComputer{
+		{MACAddress: "00:11:22:aa:bb:cc", ComputerName: "Canonical", IPAddress: "10.0.0.1"},
+		{MACAddress: "00-11-22-AA-BB-CC", ComputerName: "Colliding", IPAddress: "10.0.0.2"},
+		{MACAddress: "00-11-22-AA-BB-DD", ComputerName: "Later", IPAddress: "10.0.0.3"},
+	}
+	for _, computer := range computers {
+		if err := database.Create(computer).Error; err != nil {
+			t.Fatalf("Failed to create computer: %v", err)
+		}
+	}
+
+	if _, err := MigrateUp(ctx, database); err != nil {
+		t.Fatalf("Expected the collision not to fail the migration, got: %v", err)
+	}
+
+	var stored []models.Computer
+	database.Order("id").Find(&stored)
+	if len(stored) != 3 || stored[1].MACAddress != "00-11-22-AA-BB-CC" || stored[2].MACAddress != "00:11:22:aa:bb:dd" {
+		t.Errorf("Expected only the colliding MAC address to be kept, got %+v", stored)
+	}
+}
@@ -0,0 +1,90 @@
+-- Schema as created by AutoMigrate before versioned migrations
+CREATE TABLE employees (
+    abbreviation varchar(3) NOT NULL,
+    name varchar(100) NOT NULL,
+    email varchar(254),
+    department varchar(100),
+    active boolean NOT NULL DEFAULT true,
+    created_at timestamptz,
+    updated_at timestamptz,
+    PRIMARY KEY (abbreviation)
+);
+
+CREATE TABLE computers (
+    id bigserial PRIMARY KEY,
+    mac_address varchar(17) NOT NULL,
+    computer_name varchar(100) NOT NULL,
+    ip_address varchar(45) NOT NULL,
+    employee_abbreviation varchar(3),
+    description varchar(500),
+    version bigint NOT NULL DEFAULT 1,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_computers_employee FOREIGN KEY (employee_abbreviation) REFERENCES employees(abbreviation) ON DELETE RESTRICT ON UPDATE CASCADE
+);
+CREATE INDEX idx_computers_deleted_at ON computers (deleted_at);
+CREATE UNIQUE INDEX idx_computers_mac_address_active ON computers (mac_address) WHERE deleted_at IS NULL;
+
+CREATE TABLE outbox_messages (
+    id bigserial PRIMARY KEY,
+    kind varchar(50) NOT NULL,
+    payload text NOT NULL,
+    status varchar(20) NOT NULL,
+    attempts bigint NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL,
+    last_error varchar(1000),
+    request_id varchar(128),
+    trace_parent varchar(55),
+    delivered_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX idx_outbox_due ON outbox_messages (status, next_attempt_at);
+
+CREATE TABLE assignment_policies (
+    id bigserial PRIMARY KEY,
+    scope varchar(20) NOT NULL,
+    subject varchar(100) NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX idx_policy_scope ON assignment_policies (scope, subject);
+
+CREATE TABLE policy_thresholds (
+    id bigserial PRIMARY KEY,
+    policy_id bigint NOT NULL,
+    count bigint NOT NULL,
+    action varchar(10) NOT NULL,
+    level varchar(20),
+    CONSTRAINT fk_assignment_policies_thresholds FOREIGN KEY (policy_id) REFERENCES assignment_policies(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_policy_thresholds_policy_id ON policy_thresholds (policy_id);
+
+CREATE TABLE audit_entries (
+    id bigserial PRIMARY KEY,
+    entity_type varchar(50) NOT NULL,
+    entity_id bigint NOT NULL,
+    action varchar(20) NOT NULL,
+    actor varchar(255) NOT NULL,
+    request_id varchar(128),
+    changes text NOT NULL,
+    created_at timestamptz
+);
+CREATE INDEX idx_audit_entries_created_at ON audit_entries (created_at);
+CREATE INDEX idx_audit_entries_actor ON audit_entries (actor);
+CREATE INDEX idx_audit_entity ON audit_entries (entity_type, entity_id);
+
+CREATE TABLE api_keys (
+    id bigserial PRIMARY KEY,
+    name varchar(100) NOT NULL,
+    role varchar(20) NOT NULL,
+    prefix varchar(16) NOT NULL,
+    hash varchar(64) NOT NULL,
+    created_at timestamptz,
+    rotated_at timestamptz,
+    last_used_at timestamptz,
+    revoked_at timestamptz
+);
+CREATE UNIQUE INDEX idx_api_keys_hash ON api_keys (hash);
+CREATE UNIQUE INDEX idx_api_keys_name ON api_keys (name);
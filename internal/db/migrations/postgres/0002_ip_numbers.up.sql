@@ -0,0 +1,9 @@
+-- IP addresses as numbers, their family followed by the address in hex, to
+-- sort them numerically. Existing rows are filled in by the data migration of
+-- this version.
+ALTER TABLE computers ADD COLUMN ip_number varchar(33) NOT NULL DEFAULT '';
+CREATE INDEX idx_computers_ip_number ON computers (ip_number);
+
+-- Network queries compare addresses as inet. Every stored address must be
+-- valid for the index to be created.
+CREATE INDEX idx_computers_ip_inet ON computers USING gist ((ip_address::inet) inet_ops);
@@ -0,0 +1,15 @@
+-- Managed subnets. Reserved ranges are stored as JSON; the IP numbers of the
+-- first and last address find the subnet of an address.
+CREATE TABLE subnets (
+    id bigserial PRIMARY KEY,
+    cidr varchar(49) NOT NULL,
+    gateway varchar(45),
+    reserved_ranges text NOT NULL,
+    description varchar(500),
+    first_ip_number varchar(33) NOT NULL,
+    last_ip_number varchar(33) NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX idx_subnets_cidr ON subnets (cidr);
+CREATE INDEX idx_subnets_ip_numbers ON subnets (first_ip_number, last_ip_number);
@@ -0,0 +1,3 @@
+-- The lease of a message a dispatcher is delivering, so it is not replayed
+-- while the delivery is in progress
+ALTER TABLE outbox_messages ADD COLUMN leased_until timestamptz;
@@ -0,0 +1,5 @@
+-- The pg_trgm extension is kept, as other schemas may use it
+DROP INDEX idx_computers_mac_trgm;
+DROP INDEX idx_computers_description_trgm;
+DROP INDEX idx_computers_name_trgm;
+DROP INDEX idx_computers_search;
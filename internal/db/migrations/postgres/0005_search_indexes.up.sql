@@ -0,0 +1,8 @@
+-- Indexes for the search of computers: full-text search of names and
+-- descriptions, and trigrams for substring matches of names, descriptions
+-- and MAC addresses and for misspelt names
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX idx_computers_search ON computers USING gin (to_tsvector('simple', computer_name || ' ' || COALESCE(description, '')));
+CREATE INDEX idx_computers_name_trgm ON computers USING gin (LOWER(computer_name) gin_trgm_ops);
+CREATE INDEX idx_computers_description_trgm ON computers USING gin (LOWER(description) gin_trgm_ops);
+CREATE INDEX idx_computers_mac_trgm ON computers USING gin (REPLACE(mac_address, ':', '') gin_trgm_ops);
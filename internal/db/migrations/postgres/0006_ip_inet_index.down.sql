@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_computers_ip_inet_valid;
+CREATE INDEX idx_computers_ip_inet ON computers USING gist ((ip_address::inet) inet_ops);
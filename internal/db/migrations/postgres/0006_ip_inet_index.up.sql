@@ -0,0 +1,5 @@
+-- Network queries compare addresses as inet. Only addresses with an IP number
+-- are cast, as unparsable ones kept by the upgrade from AutoMigrate cannot be;
+-- the index of migration 2 replaced here cast every address.
+DROP INDEX IF EXISTS idx_computers_ip_inet;
+CREATE INDEX idx_computers_ip_inet_valid ON computers USING gist ((CASE WHEN ip_number <> '' THEN ip_address::inet END) inet_ops);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS `api_keys`;
+DROP TABLE IF EXISTS `audit_entries`;
+DROP TABLE IF EXISTS `policy_thresholds`;
+DROP TABLE IF EXISTS `assignment_policies`;
+DROP TABLE IF EXISTS `outbox_messages`;
+DROP TABLE IF EXISTS `computers`;
+DROP TABLE IF EXISTS `employees`;
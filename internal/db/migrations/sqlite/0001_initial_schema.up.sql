@@ -0,0 +1,90 @@
+-- Schema as created by AutoMigrate before versioned migrations
+CREATE TABLE `employees` (
+    `abbreviation` text,
+    `name` text NOT NULL,
+    `email` text,
+    `department` text,
+    `active` numeric NOT NULL DEFAULT true,
+    `created_at` datetime,
+    `updated_at` datetime,
+    PRIMARY KEY (`abbreviation`)
+);
+
+CREATE TABLE `computers` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `mac_address` text NOT NULL,
+    `computer_name` text NOT NULL,
+    `ip_address` text NOT NULL,
+    `employee_abbreviation` text,
+    `description` text,
+    `version` integer NOT NULL DEFAULT 1,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    CONSTRAINT `fk_computers_employee` FOREIGN KEY (`employee_abbreviation`) REFERENCES `employees`(`abbreviation`) ON DELETE RESTRICT ON UPDATE CASCADE
+);
+CREATE INDEX `idx_computers_deleted_at` ON `computers`(`deleted_at`);
+CREATE UNIQUE INDEX `idx_computers_mac_address_active` ON `computers`(`mac_address`) WHERE deleted_at IS NULL;
+
+CREATE TABLE `outbox_messages` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `kind` text NOT NULL,
+    `payload` text NOT NULL,
+    `status` text NOT NULL,
+    `attempts` integer NOT NULL DEFAULT 0,
+    `next_attempt_at` datetime NOT NULL,
+    `last_error` text,
+    `request_id` text,
+    `trace_parent` text,
+    `delivered_at` datetime,
+    `created_at` datetime,
+    `updated_at` datetime
+);
+CREATE INDEX `idx_outbox_due` ON `outbox_messages`(`status`, `next_attempt_at`);
+
+CREATE TABLE `assignment_policies` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `scope` text NOT NULL,
+    `subject` text NOT NULL,
+    `created_at` datetime,
+    `updated_at` datetime
+);
+CREATE UNIQUE INDEX `idx_policy_scope` ON `assignment_policies`(`scope`, `subject`);
+
+CREATE TABLE `policy_thresholds` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `policy_id` integer NOT NULL,
+    `count` integer NOT NULL,
+    `action` text NOT NULL,
+    `level` text,
+    CONSTRAINT `fk_assignment_policies_thresholds` FOREIGN KEY (`policy_id`) REFERENCES `assignment_policies`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX `idx_policy_thresholds_policy_id` ON `policy_thresholds`(`policy_id`);
+
+CREATE TABLE `audit_entries` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `entity_type` text NOT NULL,
+    `entity_id` integer NOT NULL,
+    `action` text NOT NULL,
+    `actor` text NOT NULL,
+    `request_id` text,
+    `changes` text NOT NULL,
+    `created_at` datetime
+);
+CREATE INDEX `idx_audit_entries_created_at` ON `audit_entries`(`created_at`);
+CREATE INDEX `idx_audit_entries_actor` ON `audit_entries`(`actor`);
+CREATE INDEX `idx_audit_entity` ON `audit_entries`(`entity_type`, `entity_id`);
+
+CREATE TABLE `api_keys` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `name` text NOT NULL,
+    `role` text NOT NULL,
+    `prefix` text NOT NULL,
+    `hash` text NOT NULL,
+    `created_at` datetime,
+    `rotated_at` datetime,
+    `last_used_at` datetime,
+    `revoked_at` datetime
+);
+CREATE UNIQUE INDEX `idx_api_keys_hash` ON `api_keys`(`hash`);
+CREATE UNIQUE INDEX `idx_api_keys_name` ON `api_keys`(`name`);
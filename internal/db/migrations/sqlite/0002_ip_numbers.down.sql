@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS `idx_computers_ip_number`;
+ALTER TABLE `computers` DROP COLUMN `ip_number`;
@@ -0,0 +1,5 @@
+-- IP addresses as numbers, their family followed by the address in hex, so
+-- networks are ranges of them. Existing rows are filled in by the data
+-- migration of this version.
+ALTER TABLE `computers` ADD COLUMN `ip_number` text NOT NULL DEFAULT '';
+CREATE INDEX `idx_computers_ip_number` ON `computers`(`ip_number`);
@@ -0,0 +1,15 @@
+-- Managed subnets. Reserved ranges are stored as JSON; the IP numbers of the
+-- first and last address find the subnet of an address.
+CREATE TABLE `subnets` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `cidr` text NOT NULL,
+    `gateway` text,
+    `reserved_ranges` text NOT NULL,
+    `description` text,
+    `first_ip_number` text NOT NULL,
+    `last_ip_number` text NOT NULL,
+    `created_at` datetime,
+    `updated_at` datetime
+);
+CREATE UNIQUE INDEX `idx_subnets_cidr` ON `subnets`(`cidr`);
+CREATE INDEX `idx_subnets_ip_numbers` ON `subnets`(`first_ip_number`, `last_ip_number`);
@@ -0,0 +1,3 @@
+-- SQLite cannot index substring matches, so the search of computers narrows
+-- them down with LIKE alone. This version only keeps the migrations of both
+-- dialects numbered alike.
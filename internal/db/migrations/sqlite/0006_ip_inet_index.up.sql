@@ -0,0 +1,2 @@
+-- SQLite has no inet type and compares IP numbers. This version only keeps
+-- the migrations of both dialects numbered alike.
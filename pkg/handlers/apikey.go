@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"greenbone-case-study/pkg/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// APIKeyHandler handles HTTP requests for API key management
+type APIKeyHandler struct {
+	service models.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(service models.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		service: service,
+	}
+}
+
+// apiKeySecretResponse is the body of create and rotate responses, the only
+// ones that contain the key itself
+type apiKeySecretResponse struct {
+	*models.APIKey
+	Key string `json:"key"`
+}
+
+// CreateKey handles POST /admin/api-keys
+func (h *APIKeyHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	var key models.APIKey
+	if err := json.NewDecoder(r.Body).Decode(&key); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	secret, err := h.service.CreateKey(r.Context(), &key)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusCreated, apiKeySecretResponse{APIKey: &key, Key: secret})
+}
+
+// GetAllKeys handles GET /admin/api-keys
+func (h *APIKeyHandler) GetAllKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.GetAllKeys(r.Context())
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+
+	writeJSONResponse(w, http.StatusOK, keys)
+}
+
+// RotateKey handles POST /admin/api-keys/{id}/rotate
+func (h *APIKeyHandler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	key, secret, err := h.service.RotateKey(r.Context(), uint(id))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, apiKeySecretResponse{APIKey: key, Key: secret})
+}
+
+// RevokeKey handles DELETE /admin/api-keys/{id}
+func (h *APIKeyHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	key, err := h.service.RevokeKey(r.Context(), uint(id))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, key)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// AuditHandler handles HTTP requests for the audit log
+type AuditHandler struct {
+	service models.AuditService
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(service models.AuditService) *AuditHandler {
+	return &AuditHandler{
+		service: service,
+	}
+}
+
+// auditListResponse is the body of audit log listings
+type auditListResponse struct {
+	Data       []models.AuditEntry `json:"data"`
+	Pagination paginationMeta      `json:"pagination"`
+}
+
+// GetAuditLog handles GET /audit?actor=&entity_type=&entity_id=&created_after=&created_before=
+func (h *AuditHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseAuditQuery(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.service.GetAuditLog(r.Context(), opts)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, newAuditListResponse(page))
+}
+
+// GetComputerHistory handles GET /computers/{id}/history
+func (h *AuditHandler) GetComputerHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil || id == 0 {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid computer ID")
+		return
+	}
+
+	opts, err := parseAuditQuery(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.service.GetComputerHistory(r.Context(), uint(id), opts.Limit, opts.Offset)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, newAuditListResponse(page))
+}
+
+// parseAuditQuery reads audit log filters from the query string
+func parseAuditQuery(values url.Values) (models.AuditQueryOptions, error) {
+	var opts models.AuditQueryOptions
+	var err error
+
+	if v := values.Get("limit"); v != "" {
+		if opts.Limit, err = strconv.Atoi(v); err != nil || opts.Limit < 1 {
+			return opts, fmt.Errorf("invalid limit %q", v)
+		}
+	}
+	if v := values.Get("offset"); v != "" {
+		if opts.Offset, err = strconv.Atoi(v); err != nil || opts.Offset < 0 {
+			return opts, fmt.Errorf("invalid offset %q", v)
+		}
+	}
+
+	opts.Actor = values.Get("actor")
+	opts.EntityType = values.Get("entity_type")
+	if v := values.Get("entity_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return opts, fmt.Errorf("invalid entity_id %q", v)
+		}
+		opts.EntityID = uint(id)
+	}
+
+	dates := []struct {
+		param string
+		dest  **time.Time
+	}{
+		{"created_after", &opts.CreatedAfter},
+		{"created_before", &opts.CreatedBefore},
+	}
+	for _, d := range dates {
+		if v := values.Get(d.param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return opts, fmt.Errorf("invalid %s, expected RFC 3339 timestamp", d.param)
+			}
+			*d.dest = &t
+		}
+	}
+
+	return opts, nil
+}
+
+// newAuditListResponse wraps a page of audit entries with pagination metadata
+func newAuditListResponse(page *models.AuditPage) auditListResponse {
+	items := page.Items
+	if items == nil {
+		items = []models.AuditEntry{}
+	}
+	return auditListResponse{
+		Data: items,
+		Pagination: paginationMeta{
+			Total:  page.Total,
+			Limit:  page.Limit,
+			Offset: page.Offset,
+		},
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"greenbone-case-study/pkg/models"
+	"greenbone-case-study/pkg/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// Mock audit service for testing
+type mockAuditService struct {
+	lastOpts models.AuditQueryOptions
+}
+
+func (m *mockAuditService) GetAuditLog(ctx context.Context, opts models.AuditQueryOptions) (*models.AuditPage, error) {
+	m.lastOpts = opts
+	return &models.AuditPage{Limit: opts.Limit, Offset: opts.Offset}, nil
+}
+
+func (m *mockAuditService) GetComputerHistory(ctx context.Context, id uint, limit, offset int) (*models.AuditPage, error) {
+	m.lastOpts = models.AuditQueryOptions{EntityType: models.AuditEntityComputer, EntityID: id, Limit: limit, Offset: offset}
+	entries := []models.AuditEntry{{EntityType: models.AuditEntityComputer, EntityID: id, Action: models.AuditCreate}}
+	return &models.AuditPage{Items: entries, Total: 1, Limit: limit, Offset: offset}, nil
+}
+
+func TestGetAuditLogFilters(t *testing.T) {
+	service := &mockAuditService{}
+	handler := NewAuditHandler(service)
+
+	req := httptest.NewRequest("GET", "/api/audit?actor=alice&entity_type=computer&entity_id=3&created_after=2024-01-01T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+	handler.GetAuditLog(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	opts := service.lastOpts
+	if opts.Actor != "alice" || opts.EntityType != "computer" || opts.EntityID != 3 || opts.CreatedAfter == nil {
+		t.Errorf("Unexpected options: %+v", opts)
+	}
+
+	req = httptest.NewRequest("GET", "/api/audit?created_before=yesterday", nil)
+	w = httptest.NewRecorder()
+	handler.GetAuditLog(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestGetComputerHistory(t *testing.T) {
+	handler := NewAuditHandler(&mockAuditService{})
+
+	req := httptest.NewRequest("GET", "/api/computers/7/history", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "7"})
+	w := httptest.NewRecorder()
+	handler.GetComputerHistory(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response auditListResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.Data) != 1 || response.Data[0].EntityID != 7 {
+		t.Errorf("Expected history of computer 7, got %+v", response.Data)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	handler := requestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = services.RequestIDFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/api/health", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if seen != "abc-123" || w.Header().Get("X-Request-ID") != "abc-123" {
+		t.Errorf("Expected propagated request ID, got context %q and header %q", seen, w.Header().Get("X-Request-ID"))
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/api/health", nil))
+
+	if seen == "" || seen == "abc-123" || w.Header().Get("X-Request-ID") != seen {
+		t.Errorf("Expected generated request ID, got context %q and header %q", seen, w.Header().Get("X-Request-ID"))
+	}
+}
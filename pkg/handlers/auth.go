@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"greenbone-case-study/pkg/models"
+	"greenbone-case-study/pkg/services"
+	"net/http"
+	"strings"
+)
+
+// authMiddleware authenticates requests that carry credentials and stores the
+// principal in the request context. API keys are sent as "Authorization:
+// Bearer <key>" or in the X-API-Key header; bearer tokens that look like JWTs
+// are verified by tokens instead, if it is set. Requests without credentials
+// continue anonymously and are turned away by requireRole; invalid
+// credentials are rejected right away.
+func authMiddleware(apiKeys, tokens models.TokenAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key := r.Header.Get("X-API-Key"); key != "" {
+				authenticate(w, r, next, apiKeys, key, "Invalid API key")
+				return
+			}
+
+			token := bearerToken(r)
+			switch {
+			case token == "":
+				next.ServeHTTP(w, r)
+			case tokens != nil && looksLikeJWT(token):
+				authenticate(w, r, next, tokens, token, "Invalid bearer token")
+			default:
+				authenticate(w, r, next, apiKeys, token, "Invalid API key")
+			}
+		})
+	}
+}
+
+// authenticate verifies credentials with authenticator and continues with the
+// principal in the request context, or writes a 401 problem with detail
+func authenticate(w http.ResponseWriter, r *http.Request, next http.Handler, authenticator models.TokenAuthenticator, credentials, detail string) {
+	principal, err := authenticator.Authenticate(r.Context(), credentials)
+	if err != nil {
+		writeUnauthorized(w, r, detail)
+		return
+	}
+	recordPrincipal(r, principal)
+	next.ServeHTTP(w, r.WithContext(services.WithPrincipal(r.Context(), principal)))
+}
+
+// requireRole only lets requests through whose principal has role or a more
+// privileged one
+func requireRole(role string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := services.PrincipalFromContext(r.Context())
+		if principal == nil {
+			writeUnauthorized(w, r, "Authentication required")
+			return
+		}
+		if !principal.HasRole(role) {
+			writeProblem(w, r, http.StatusForbidden, "This operation requires the "+role+" role")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// bearerToken returns the token of a Bearer Authorization header, if any
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// looksLikeJWT reports whether token has the three dot-separated parts of a
+// JWS compact serialisation. Generated API keys never contain dots.
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// writeUnauthorized writes a 401 problem with the authentication challenge
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="computer-management-api"`)
+	writeProblem(w, r, http.StatusUnauthorized, detail)
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"greenbone-case-study/pkg/models"
+	"greenbone-case-study/pkg/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Mock API key service for testing. Keys are looked up by their secret.
+type mockAPIKeyService struct {
+	principals map[string]*models.Principal
+}
+
+func (m *mockAPIKeyService) CreateKey(ctx context.Context, key *models.APIKey) (string, error) {
+	if !models.ValidRole(key.Role) {
+		return "", &services.ValidationError{Errors: []services.FieldError{{Field: "role", Message: "invalid"}}}
+	}
+	key.ID = 1
+	return "cmk_secret", nil
+}
+
+func (m *mockAPIKeyService) GetAllKeys(ctx context.Context) ([]models.APIKey, error) {
+	return nil, nil
+}
+
+func (m *mockAPIKeyService) RotateKey(ctx context.Context, id uint) (*models.APIKey, string, error) {
+	return nil, "", services.ErrAPIKeyNotFound
+}
+
+func (m *mockAPIKeyService) RevokeKey(ctx context.Context, id uint) (*models.APIKey, error) {
+	return nil, services.ErrAPIKeyNotFound
+}
+
+func (m *mockAPIKeyService) EnsureKey(ctx context.Context, name, role, secret string) error {
+	return nil
+}
+
+func (m *mockAPIKeyService) Authenticate(ctx context.Context, secret string) (*models.Principal, error) {
+	principal, exists := m.principals[secret]
+	if !exists {
+		return nil, services.ErrInvalidCredentials
+	}
+	return principal, nil
+}
+
+func TestRequireRole(t *testing.T) {
+	keys := &mockAPIKeyService{principals: map[string]*models.Principal{
+		"viewer-key":   {Subject: "dashboard", Role: models.RoleViewer},
+		"operator-key": {Subject: "ops", Role: models.RoleOperator},
+		"admin-key":    {Subject: "root", Role: models.RoleAdmin},
+	}}
+
+	var actor string
+	handler := authMiddleware(keys, nil)(requireRole(models.RoleOperator, func(w http.ResponseWriter, r *http.Request) {
+		actor = services.ActorFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"anonymous", "", "", http.StatusUnauthorized},
+		{"unknown key", "X-API-Key", "nope", http.StatusUnauthorized},
+		{"viewer", "Authorization", "Bearer viewer-key", http.StatusForbidden},
+		{"operator", "Authorization", "Bearer operator-key", http.StatusNoContent},
+		{"admin", "X-API-Key", "admin-key", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("DELETE", "/api/computers/1", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, w.Code)
+			}
+			if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected WWW-Authenticate challenge")
+			}
+		})
+	}
+
+	if actor != "root" {
+		t.Errorf("Expected the principal as actor, got %q", actor)
+	}
+}
+
+func TestCreateAPIKeyReturnsSecret(t *testing.T) {
+	handler := NewAPIKeyHandler(&mockAPIKeyService{})
+
+	body := []byte(`{"name":"dashboard","role":"viewer"}`)
+	req := httptest.NewRequest("POST", "/api/admin/api-keys", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+	handler.CreateKey(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response["key"] != "cmk_secret" || response["name"] != "dashboard" {
+		t.Errorf("Unexpected response %v", response)
+	}
+	if _, exists := response["hash"]; exists {
+		t.Error("Expected the hash to be hidden")
+	}
+}
+
+// tokenAuthenticatorFunc adapts a function to models.TokenAuthenticator
+type tokenAuthenticatorFunc func(token string) (*models.Principal, error)
+
+func (f tokenAuthenticatorFunc) Authenticate(ctx context.Context, token string) (*models.Principal, error) {
+	return f(token)
+}
+
+func TestAuthMiddlewareRoutesJWTs(t *testing.T) {
+	keys := &mockAPIKeyService{principals: map[string]*models.Principal{
+		"cmk_key": {Subject: "dashboard", Role: models.RoleViewer, Method: models.AuthMethodAPIKey},
+	}}
+	tokens := tokenAuthenticatorFunc(func(token string) (*models.Principal, error) {
+		if token != "header.payload.signature" {
+			return nil, services.ErrInvalidCredentials
+		}
+		return &models.Principal{Subject: "alice", Role: models.RoleViewer, Method: models.AuthMethodJWT}, nil
+	})
+
+	var method string
+	handler := authMiddleware(keys, tokens)(requireRole(models.RoleViewer, func(w http.ResponseWriter, r *http.Request) {
+		method = services.PrincipalFromContext(r.Context()).Method
+	}))
+
+	tests := []struct {
+		token      string
+		want       int
+		wantMethod string
+	}{
+		{"header.payload.signature", http.StatusOK, models.AuthMethodJWT},
+		{"cmk_key", http.StatusOK, models.AuthMethodAPIKey},
+		{"header.payload.forged", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		method = ""
+		req := httptest.NewRequest("GET", "/api/computers", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != tt.want || method != tt.wantMethod {
+			t.Errorf("%s: expected %d via %q, got %d via %q", tt.token, tt.want, tt.wantMethod, w.Code, method)
+		}
+	}
+}
@@ -2,9 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"greenbone-case-study/pkg/models"
+	"io"
+	"mime"
 	"net/http"
+	"net/netip"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -26,27 +32,131 @@ func (h *ComputerHandler) CreateComputer(w http.ResponseWriter, r *http.Request)
 	var computer models.Computer
 
 	if err := json.NewDecoder(r.Body).Decode(&computer); err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		writeProblem(w, r, http.StatusBadRequest, "Invalid JSON format")
 		return
 	}
 
-	if err := h.service.CreateComputer(&computer); err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+	if err := h.service.CreateComputer(r.Context(), &computer); err != nil {
+		writeServiceError(w, r, err)
 		return
 	}
 
-	h.writeJSONResponse(w, http.StatusCreated, computer)
+	w.Header().Set("ETag", computerETag(&computer))
+	writeJSONResponse(w, http.StatusCreated, computer)
 }
 
-// GetAllComputers handles GET /computers
+// GetAllComputers handles GET /computers. Exports in other formats than JSON
+// contain every matching computer, without pagination.
 func (h *ComputerHandler) GetAllComputers(w http.ResponseWriter, r *http.Request) {
-	computers, err := h.service.GetAllComputers()
+	opts, err := parseComputerQuery(r.URL.Query())
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve computers")
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	h.writeJSONResponse(w, http.StatusOK, computers)
+	h.listComputers(w, r, opts, "computers")
+}
+
+// GetComputersInNetwork handles GET /networks/{cidr}/computers, listing the
+// computers with an address in the network like GET /computers
+func (h *ComputerHandler) GetComputersInNetwork(w http.ResponseWriter, r *http.Request) {
+	network, err := models.ParseIPNetwork(mux.Vars(r)["cidr"])
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts, err := parseComputerQuery(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if opts.IPNetwork.IsValid() {
+		writeProblem(w, r, http.StatusBadRequest, "ip_in cannot be combined with a network path")
+		return
+	}
+	opts.IPNetwork = network
+
+	filename := "computers-" + strings.NewReplacer("/", "_", ":", "-").Replace(network.String())
+	h.listComputers(w, r, opts, filename)
+}
+
+// listComputers writes a page of the computers matching opts, or exports them
+// all as filename if another format than JSON is requested
+func (h *ComputerHandler) listComputers(w http.ResponseWriter, r *http.Request, opts models.ComputerQueryOptions, filename string) {
+	format, columns, ok := negotiateExport(w, r, opts.IncludeDeleted)
+	if !ok {
+		return
+	}
+	if format != nil {
+		h.writeExport(w, r, format, columns, filename, opts)
+		return
+	}
+
+	page, err := h.service.GetAllComputers(r.Context(), opts)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, newComputerListResponse(r, page))
+}
+
+// computerSearchResponse is the body of GET /computers/search
+type computerSearchResponse struct {
+	Query string                     `json:"query"`
+	Data  []models.ComputerSearchHit `json:"data"`
+}
+
+// SearchComputers handles GET /computers/search?q=
+func (h *ComputerHandler) SearchComputers(w http.ResponseWriter, r *http.Request) {
+	opts := models.ComputerSearchOptions{Query: r.URL.Query().Get("q")}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if opts.Limit, err = strconv.Atoi(v); err != nil || opts.Limit < 1 {
+			writeProblem(w, r, http.StatusBadRequest, fmt.Sprintf("invalid limit %q", v))
+			return
+		}
+	}
+	includeDeleted, err := parseIncludeDeleted(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts.IncludeDeleted = includeDeleted
+
+	hits, err := h.service.SearchComputers(r.Context(), opts)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, computerSearchResponse{Query: opts.Query, Data: hits})
+}
+
+// duplicateIPAddressResponse is the body of GET /computers/duplicate-ips
+type duplicateIPAddressResponse struct {
+	Data []models.DuplicateIPAddress `json:"data"`
+}
+
+// GetDuplicateIPAddresses handles GET /computers/duplicate-ips, reporting
+// the IP addresses held by several computers, optionally within ip_in
+func (h *ComputerHandler) GetDuplicateIPAddresses(w http.ResponseWriter, r *http.Request) {
+	var network netip.Prefix
+	if v := r.URL.Query().Get("ip_in"); v != "" {
+		var err error
+		if network, err = models.ParseIPNetwork(v); err != nil {
+			writeProblem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	duplicates, err := h.service.FindDuplicateIPAddresses(r.Context(), network)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, duplicateIPAddressResponse{Data: duplicates})
 }
 
 // GetComputerByID handles GET /computers/{id}
@@ -56,17 +166,24 @@ func (h *ComputerHandler) GetComputerByID(w http.ResponseWriter, r *http.Request
 
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid computer ID")
+		writeProblem(w, r, http.StatusBadRequest, "Invalid computer ID")
 		return
 	}
 
-	computer, err := h.service.GetComputerByID(uint(id))
+	computer, err := h.service.GetComputerByID(r.Context(), uint(id))
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusNotFound, "Computer not found")
+		writeServiceError(w, r, err)
 		return
 	}
 
-	h.writeJSONResponse(w, http.StatusOK, computer)
+	etag := computerETag(computer)
+	w.Header().Set("ETag", etag)
+	if match := r.Header.Get("If-None-Match"); match != "" && etagListContains(match, etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, computer)
 }
 
 // GetComputersByEmployee handles GET /employees/{abbr}/computers
@@ -74,13 +191,29 @@ func (h *ComputerHandler) GetComputersByEmployee(w http.ResponseWriter, r *http.
 	vars := mux.Vars(r)
 	abbr := vars["abbr"]
 
-	computers, err := h.service.GetComputersByEmployee(abbr)
+	includeDeleted, err := parseIncludeDeleted(r.URL.Query())
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format, columns, ok := negotiateExport(w, r, includeDeleted)
+	if !ok {
+		return
+	}
+	if format != nil {
+		opts := models.ComputerQueryOptions{EmployeeAbbreviation: abbr, IncludeDeleted: includeDeleted}
+		h.writeExport(w, r, format, columns, "computers-"+abbr, opts)
+		return
+	}
+
+	computers, err := h.service.GetComputersByEmployee(r.Context(), abbr, includeDeleted)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		writeServiceError(w, r, err)
 		return
 	}
 
-	h.writeJSONResponse(w, http.StatusOK, computers)
+	writeJSONResponse(w, http.StatusOK, computers)
 }
 
 // UpdateComputer handles PUT /computers/{id}
@@ -90,28 +223,73 @@ func (h *ComputerHandler) UpdateComputer(w http.ResponseWriter, r *http.Request)
 
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid computer ID")
+		writeProblem(w, r, http.StatusBadRequest, "Invalid computer ID")
 		return
 	}
 
 	var computer models.Computer
 	if err := json.NewDecoder(r.Body).Decode(&computer); err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid JSON format")
+		writeProblem(w, r, http.StatusBadRequest, "Invalid JSON format")
 		return
 	}
 
 	computer.ID = uint(id)
 
-	if err := h.service.UpdateComputer(&computer); err != nil {
-		if err.Error() == "computer not found" {
-			h.writeErrorResponse(w, http.StatusNotFound, err.Error())
-		} else {
-			h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
-		}
+	version, ok := h.checkIfMatch(w, r, computer.ID)
+	if !ok {
+		return
+	}
+	if version != 0 {
+		computer.Version = version
+	}
+
+	if err := h.service.UpdateComputer(r.Context(), &computer); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", computerETag(&computer))
+	writeJSONResponse(w, http.StatusOK, computer)
+}
+
+// PatchComputer handles PATCH /computers/{id}
+func (h *ComputerHandler) PatchComputer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid computer ID")
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	patchType := models.PatchType(mediaType)
+	if patchType != models.MergePatch && patchType != models.JSONPatch {
+		w.Header().Set("Accept-Patch", string(models.MergePatch)+", "+string(models.JSONPatch))
+		writeProblem(w, r, http.StatusUnsupportedMediaType, "Unsupported patch format")
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+
+	version, ok := h.checkIfMatch(w, r, uint(id))
+	if !ok {
+		return
+	}
+
+	computer, err := h.service.PatchComputer(r.Context(), uint(id), version, patchType, patch)
+	if err != nil {
+		writeServiceError(w, r, err)
 		return
 	}
 
-	h.writeJSONResponse(w, http.StatusOK, computer)
+	w.Header().Set("ETag", computerETag(computer))
+	writeJSONResponse(w, http.StatusOK, computer)
 }
 
 // DeleteComputer handles DELETE /computers/{id}
@@ -121,36 +299,70 @@ func (h *ComputerHandler) DeleteComputer(w http.ResponseWriter, r *http.Request)
 
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid computer ID")
+		writeProblem(w, r, http.StatusBadRequest, "Invalid computer ID")
 		return
 	}
 
-	if err := h.service.DeleteComputer(uint(id)); err != nil {
-		if err.Error() == "computer not found" {
-			h.writeErrorResponse(w, http.StatusNotFound, err.Error())
-		} else {
-			h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete computer")
-		}
+	version, ok := h.checkIfMatch(w, r, uint(id))
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteComputer(r.Context(), uint(id), version); err != nil {
+		writeServiceError(w, r, err)
 		return
 	}
 
-	h.writeJSONResponse(w, http.StatusOK, map[string]string{
+	writeJSONResponse(w, http.StatusOK, map[string]string{
 		"message": "Computer deleted successfully",
 	})
 }
 
-// writeJSONResponse writes a JSON response
-func (h *ComputerHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(data)
+// RestoreComputer handles POST /computers/{id}/restore
+func (h *ComputerHandler) RestoreComputer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid computer ID")
+		return
+	}
+
+	computer, err := h.service.RestoreComputer(r.Context(), uint(id))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", computerETag(computer))
+	writeJSONResponse(w, http.StatusOK, computer)
+}
+
+// PurgeComputers handles POST /admin/computers/purge?older_than=720h
+func (h *ComputerHandler) PurgeComputers(w http.ResponseWriter, r *http.Request) {
+	// Without older_than the service applies its default retention
+	var olderThan *time.Duration
+	if v := r.URL.Query().Get("older_than"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid older_than, expected a duration such as 720h")
+			return
+		}
+		olderThan = &d
+	}
+
+	purged, err := h.service.PurgeComputers(r.Context(), olderThan)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]int{
+		"purged": purged,
+	})
 }
 
-// writeErrorResponse writes an error response
-func (h *ComputerHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+// writeJSONResponse writes a JSON response
+func writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]string{
-		"error": message,
-	})
+	json.NewEncoder(w).Encode(data)
 }
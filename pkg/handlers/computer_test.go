@@ -2,12 +2,17 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"greenbone-case-study/pkg/models"
+	"greenbone-case-study/pkg/services"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -16,6 +21,14 @@ import (
 type mockComputerService struct {
 	computers map[uint]*models.Computer
 	nextID    uint
+
+	lastPatchType models.PatchType
+	lastPurge     *time.Duration
+	lastImport    []models.Computer
+	lastImportOpt models.ImportOptions
+	lastQuery     models.ComputerQueryOptions
+	lastExport    models.ComputerQueryOptions
+	lastSearch    models.ComputerSearchOptions
 }
 
 func newMockService() *mockComputerService {
@@ -25,30 +38,84 @@ func newMockService() *mockComputerService {
 	}
 }
 
-func (m *mockComputerService) CreateComputer(computer *models.Computer) error {
+func (m *mockComputerService) CreateComputer(ctx context.Context, computer *models.Computer) error {
+	verr := &services.ValidationError{}
+	if computer.MACAddress == "" {
+		verr.Add("mac_address", "is required")
+	}
+	if computer.ComputerName == "" {
+		verr.Add("computer_name", "is required")
+	}
+	if len(verr.Errors) > 0 {
+		return verr
+	}
+	for _, existing := range m.computers {
+		if existing.MACAddress == computer.MACAddress {
+			return services.ErrMACAddressInUse
+		}
+	}
+	if computer.EmployeeAbbreviation != nil && *computer.EmployeeAbbreviation == "max" {
+		return services.ErrAssignmentBlocked
+	}
 	computer.ID = m.nextID
+	computer.Version = 1
 	m.nextID++
 	m.computers[computer.ID] = computer
 	return nil
 }
 
-func (m *mockComputerService) GetAllComputers() ([]models.Computer, error) {
+func (m *mockComputerService) GetAllComputers(ctx context.Context, opts models.ComputerQueryOptions) (*models.ComputerPage, error) {
+	m.lastQuery = opts
 	var result []models.Computer
 	for _, computer := range m.computers {
 		result = append(result, *computer)
 	}
-	return result, nil
+	return &models.ComputerPage{Items: result, Total: int64(len(result)), Limit: opts.Limit, Offset: opts.Offset}, nil
 }
 
-func (m *mockComputerService) GetComputerByID(id uint) (*models.Computer, error) {
+func (m *mockComputerService) ExportComputers(ctx context.Context, opts models.ComputerQueryOptions, fn func(computer *models.Computer) error) error {
+	if opts.EmployeeAbbreviation != "" && opts.EmployeeAbbreviation != "abc" {
+		return services.ErrEmployeeNotFound
+	}
+	m.lastExport = opts
+	for id := uint(1); id < m.nextID; id++ {
+		computer, exists := m.computers[id]
+		if !exists || (opts.EmployeeAbbreviation != "" && (computer.EmployeeAbbreviation == nil || *computer.EmployeeAbbreviation != opts.EmployeeAbbreviation)) {
+			continue
+		}
+		if err := fn(computer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockComputerService) SearchComputers(ctx context.Context, opts models.ComputerSearchOptions) ([]models.ComputerSearchHit, error) {
+	if opts.Query == "" {
+		return nil, &services.ValidationError{Errors: []services.FieldError{{Field: "q", Message: "is required"}}}
+	}
+	m.lastSearch = opts
+	hits := []models.ComputerSearchHit{}
+	for id := uint(1); id < m.nextID; id++ {
+		if computer, exists := m.computers[id]; exists && strings.Contains(computer.ComputerName, opts.Query) {
+			hits = append(hits, models.ComputerSearchHit{Computer: *computer, Score: 3, Matches: []string{"computer_name"}})
+		}
+	}
+	return hits, nil
+}
+
+func (m *mockComputerService) GetComputerByID(ctx context.Context, id uint) (*models.Computer, error) {
 	computer, exists := m.computers[id]
 	if !exists {
-		return nil, errors.New("computer not found")
+		return nil, services.ErrComputerNotFound
 	}
 	return computer, nil
 }
 
-func (m *mockComputerService) GetComputersByEmployee(abbr string) ([]models.Computer, error) {
+func (m *mockComputerService) GetComputersByEmployee(ctx context.Context, abbr string, includeDeleted bool) ([]models.Computer, error) {
+	if abbr != "abc" {
+		return nil, services.ErrEmployeeNotFound
+	}
 	var result []models.Computer
 	for _, computer := range m.computers {
 		if computer.EmployeeAbbreviation != nil && *computer.EmployeeAbbreviation == abbr {
@@ -58,22 +125,100 @@ func (m *mockComputerService) GetComputersByEmployee(abbr string) ([]models.Comp
 	return result, nil
 }
 
-func (m *mockComputerService) UpdateComputer(computer *models.Computer) error {
-	if _, exists := m.computers[computer.ID]; !exists {
-		return errors.New("computer not found")
+func (m *mockComputerService) FindDuplicateIPAddresses(ctx context.Context, network netip.Prefix) ([]models.DuplicateIPAddress, error) {
+	result := []models.DuplicateIPAddress{}
+	seen := make(map[string]int)
+	for id := uint(1); id < m.nextID; id++ {
+		computer, exists := m.computers[id]
+		if !exists || (network.IsValid() && !network.Contains(netip.MustParseAddr(computer.IPAddress))) {
+			continue
+		}
+		i, found := seen[computer.IPAddress]
+		if !found {
+			i = len(result)
+			seen[computer.IPAddress] = i
+			result = append(result, models.DuplicateIPAddress{IPAddress: computer.IPAddress})
+		}
+		result[i].Computers = append(result[i].Computers, *computer)
+	}
+
+	duplicates := result[:0]
+	for _, duplicate := range result {
+		if len(duplicate.Computers) > 1 {
+			duplicates = append(duplicates, duplicate)
+		}
 	}
+	return duplicates, nil
+}
+
+func (m *mockComputerService) UpdateComputer(ctx context.Context, computer *models.Computer) error {
+	existing, exists := m.computers[computer.ID]
+	if !exists {
+		return services.ErrComputerNotFound
+	}
+	if computer.Version != 0 && computer.Version != existing.Version {
+		return models.ErrVersionConflict
+	}
+	computer.Version = existing.Version + 1
 	m.computers[computer.ID] = computer
 	return nil
 }
 
-func (m *mockComputerService) DeleteComputer(id uint) error {
-	if _, exists := m.computers[id]; !exists {
-		return errors.New("computer not found")
+func (m *mockComputerService) PatchComputer(ctx context.Context, id uint, version uint, patchType models.PatchType, patch []byte) (*models.Computer, error) {
+	computer, exists := m.computers[id]
+	if !exists {
+		return nil, services.ErrComputerNotFound
+	}
+	m.lastPatchType = patchType
+	return computer, nil
+}
+
+func (m *mockComputerService) DeleteComputer(ctx context.Context, id uint, version uint) error {
+	existing, exists := m.computers[id]
+	if !exists {
+		return services.ErrComputerNotFound
+	}
+	if version != 0 && version != existing.Version {
+		return models.ErrVersionConflict
 	}
 	delete(m.computers, id)
 	return nil
 }
 
+func (m *mockComputerService) RestoreComputer(ctx context.Context, id uint) (*models.Computer, error) {
+	if _, exists := m.computers[id]; exists {
+		return nil, services.ErrComputerNotDeleted
+	}
+	return nil, services.ErrComputerNotFound
+}
+
+func (m *mockComputerService) PurgeComputers(ctx context.Context, olderThan *time.Duration) (int, error) {
+	m.lastPurge = olderThan
+	return 0, nil
+}
+
+func (m *mockComputerService) ImportComputers(ctx context.Context, computers []models.Computer, opts models.ImportOptions) (*models.ImportResult, error) {
+	m.lastImport = computers
+	m.lastImportOpt = opts
+	if opts.Mode == "" {
+		opts.Mode = models.ImportAtomic
+	}
+	result := &models.ImportResult{Mode: opts.Mode, DryRun: opts.DryRun, Applied: !opts.DryRun}
+	for i, computer := range computers {
+		row := models.ImportRowResult{Row: i + 1, MACAddress: computer.MACAddress, Action: models.ImportCreated}
+		if computer.ComputerName == "" {
+			row.Action = models.ImportFailed
+			row.Errors = []models.ImportRowError{{Field: "computer_name", Message: "is required"}}
+			result.Failed++
+			result.Applied = result.Applied && opts.Mode == models.ImportBestEffort
+		} else {
+			result.Created++
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
 func TestCreateComputer(t *testing.T) {
 	service := newMockService()
 	handler := NewComputerHandler(service)
@@ -113,7 +258,7 @@ func TestGetAllComputers(t *testing.T) {
 		ComputerName: "Test Computer",
 		IPAddress:    "192.168.1.100",
 	}
-	service.CreateComputer(computer)
+	service.CreateComputer(context.Background(), computer)
 
 	req := httptest.NewRequest("GET", "/api/computers", nil)
 	w := httptest.NewRecorder()
@@ -124,11 +269,184 @@ func TestGetAllComputers(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
 	}
 
-	var computers []models.Computer
-	json.Unmarshal(w.Body.Bytes(), &computers)
+	var response computerListResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if len(response.Data) != 1 {
+		t.Errorf("Expected 1 computer, got %d", len(response.Data))
+	}
+	if response.Pagination.Total != 1 {
+		t.Errorf("Expected total 1, got %d", response.Pagination.Total)
+	}
+}
+
+func TestGetAllComputersInvalidQuery(t *testing.T) {
+	service := newMockService()
+	handler := NewComputerHandler(service)
+
+	tests := []string{
+		"/api/computers?limit=abc",
+		"/api/computers?offset=-1",
+		"/api/computers?assigned=maybe",
+		"/api/computers?created_after=yesterday",
+		"/api/computers?sort=password",
+		"/api/computers?cursor=abc&offset=10",
+		"/api/computers?mac_address=00:11:22",
+		"/api/computers?ip_address=10.0.0.256",
+		"/api/computers?include_deleted=sometimes",
+	}
+
+	for _, target := range tests {
+		req := httptest.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+
+		handler.GetAllComputers(w, req)
 
-	if len(computers) != 1 {
-		t.Errorf("Expected 1 computer, got %d", len(computers))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", target, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestGetAllComputersPaginationLinks(t *testing.T) {
+	page := &models.ComputerPage{Total: 25, Limit: 10, Offset: 10}
+	req := httptest.NewRequest("GET", "/api/computers?limit=10&offset=10&sort=-created_at", nil)
+
+	response := newComputerListResponse(req, page)
+
+	if response.Links.Next != "/api/computers?limit=10&offset=20&sort=-created_at" {
+		t.Errorf("Unexpected next link: %s", response.Links.Next)
+	}
+	if response.Links.Prev != "/api/computers?limit=10&sort=-created_at" {
+		t.Errorf("Unexpected prev link: %s", response.Links.Prev)
+	}
+	if response.Data == nil {
+		t.Error("Expected empty data array, got nil")
+	}
+}
+
+func TestSearchComputers(t *testing.T) {
+	service := newMockService()
+	handler := NewComputerHandler(service)
+
+	service.CreateComputer(context.Background(), &models.Computer{
+		MACAddress:   "00:11:22:33:44:55",
+		ComputerName: "Latitude",
+		IPAddress:    "192.168.1.100",
+	})
+
+	req := httptest.NewRequest("GET", "/api/computers/search?q=Latitude&limit=5&include_deleted=true", nil)
+	w := httptest.NewRecorder()
+	handler.SearchComputers(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if service.lastSearch.Limit != 5 || !service.lastSearch.IncludeDeleted {
+		t.Errorf("Expected the query options to be passed on, got %+v", service.lastSearch)
+	}
+
+	var response computerSearchResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Query != "Latitude" || len(response.Data) != 1 || response.Data[0].Score != 3 {
+		t.Errorf("Unexpected response %s", w.Body.String())
+	}
+
+	tests := []struct {
+		target string
+		status int
+	}{
+		{"/api/computers/search", http.StatusUnprocessableEntity},
+		{"/api/computers/search?q=Latitude&limit=0", http.StatusBadRequest},
+		{"/api/computers/search?q=Latitude&limit=abc", http.StatusBadRequest},
+		{"/api/computers/search?q=Latitude&include_deleted=sometimes", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.target, nil)
+		w := httptest.NewRecorder()
+		handler.SearchComputers(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.target, tt.status, w.Code)
+		}
+	}
+}
+
+func TestGetComputersInNetwork(t *testing.T) {
+	service := newMockService()
+	handler := NewComputerHandler(service)
+
+	req := httptest.NewRequest("GET", "/api/computers?ip_in=10.20.1.0/16", nil)
+	w := httptest.NewRecorder()
+	handler.GetAllComputers(w, req)
+	if w.Code != http.StatusOK || service.lastQuery.IPNetwork.String() != "10.20.0.0/16" {
+		t.Errorf("Expected the masked network to be passed on, got %d and %s", w.Code, service.lastQuery.IPNetwork)
+	}
+
+	req = httptest.NewRequest("GET", "/api/networks/2001:db8::%2F32/computers?assigned=true", nil)
+	req = mux.SetURLVars(req, map[string]string{"cidr": "2001:db8::/32"})
+	w = httptest.NewRecorder()
+	handler.GetComputersInNetwork(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if service.lastQuery.IPNetwork.String() != "2001:db8::/32" || service.lastQuery.Assigned == nil {
+		t.Errorf("Expected the network and filters to be passed on, got %+v", service.lastQuery)
+	}
+
+	tests := []struct {
+		target string
+		cidr   string
+	}{
+		{"/api/computers?ip_in=10.20.0.0", ""},
+		{"/api/networks/10.20.0.0/computers", "10.20.0.0"},
+		{"/api/networks/10.20.0.0/16/computers?ip_in=10.20.1.0/24", "10.20.0.0/16"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.target, nil)
+		w := httptest.NewRecorder()
+		if tt.cidr == "" {
+			handler.GetAllComputers(w, req)
+		} else {
+			handler.GetComputersInNetwork(w, mux.SetURLVars(req, map[string]string{"cidr": tt.cidr}))
+		}
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", tt.target, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestGetDuplicateIPAddresses(t *testing.T) {
+	service := newMockService()
+	handler := NewComputerHandler(service)
+
+	for i, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.1", "192.168.1.1", "192.168.1.1"} {
+		service.CreateComputer(context.Background(), &models.Computer{
+			MACAddress:   fmt.Sprintf("00:11:22:33:44:%02d", i),
+			ComputerName: "Test Computer",
+			IPAddress:    ip,
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/api/computers/duplicate-ips?ip_in=10.0.0.0/8", nil)
+	w := httptest.NewRecorder()
+	handler.GetDuplicateIPAddresses(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response duplicateIPAddressResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if len(response.Data) != 1 || response.Data[0].IPAddress != "10.0.0.1" || len(response.Data[0].Computers) != 2 {
+		t.Errorf("Unexpected response %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/api/computers/duplicate-ips?ip_in=everything", nil)
+	w = httptest.NewRecorder()
+	handler.GetDuplicateIPAddresses(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid network, got %d", http.StatusBadRequest, w.Code)
 	}
 }
 
@@ -142,7 +460,7 @@ func TestGetComputerByID(t *testing.T) {
 		ComputerName: "Test Computer",
 		IPAddress:    "192.168.1.100",
 	}
-	service.CreateComputer(computer)
+	service.CreateComputer(context.Background(), computer)
 
 	req := httptest.NewRequest("GET", "/api/computers/1", nil)
 	req = mux.SetURLVars(req, map[string]string{"id": "1"})
@@ -169,3 +487,248 @@ func TestGetComputerByIDNotFound(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
 	}
 }
+
+func TestPatchComputer(t *testing.T) {
+	service := newMockService()
+	handler := NewComputerHandler(service)
+
+	service.CreateComputer(context.Background(), &models.Computer{
+		MACAddress:   "00:11:22:33:44:55",
+		ComputerName: "Test Computer",
+		IPAddress:    "192.168.1.100",
+	})
+
+	tests := []struct {
+		name        string
+		id          string
+		contentType string
+		wantStatus  int
+	}{
+		{"merge patch", "1", "application/merge-patch+json", http.StatusOK},
+		{"json patch with charset", "1", "application/json-patch+json; charset=utf-8", http.StatusOK},
+		{"plain json", "1", "application/json", http.StatusUnsupportedMediaType},
+		{"not found", "999", "application/merge-patch+json", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PATCH", "/api/computers/"+tt.id, bytes.NewBufferString(`{}`))
+			req.Header.Set("Content-Type", tt.contentType)
+			req = mux.SetURLVars(req, map[string]string{"id": tt.id})
+			w := httptest.NewRecorder()
+
+			handler.PatchComputer(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantStatus == http.StatusUnsupportedMediaType && w.Header().Get("Accept-Patch") == "" {
+				t.Error("Expected Accept-Patch header")
+			}
+		})
+	}
+
+	if service.lastPatchType != models.JSONPatch {
+		t.Errorf("Expected patch type %s, got %s", models.JSONPatch, service.lastPatchType)
+	}
+}
+
+func TestGetComputerByIDNotModified(t *testing.T) {
+	service := newMockService()
+	handler := NewComputerHandler(service)
+
+	service.CreateComputer(context.Background(), &models.Computer{
+		MACAddress:   "00:11:22:33:44:55",
+		ComputerName: "Test Computer",
+		IPAddress:    "192.168.1.100",
+	})
+
+	req := httptest.NewRequest("GET", "/api/computers/1", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	w := httptest.NewRecorder()
+	handler.GetComputerByID(w, req)
+
+	etag := w.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("Expected ETag \"1\", got %s", etag)
+	}
+
+	req = httptest.NewRequest("GET", "/api/computers/1", nil)
+	req.Header.Set("If-None-Match", etag)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	w = httptest.NewRecorder()
+	handler.GetComputerByID(w, req)
+
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected status %d, got %d", http.StatusNotModified, w.Code)
+	}
+}
+
+func TestConditionalWrites(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		ifMatch    string
+		wantStatus int
+	}{
+		{"update with current etag", "PUT", `"1"`, http.StatusOK},
+		{"update with stale etag", "PUT", `"7"`, http.StatusPreconditionFailed},
+		{"update with wildcard", "PUT", "*", http.StatusOK},
+		{"patch with stale etag", "PATCH", `"7"`, http.StatusPreconditionFailed},
+		{"delete with current etag", "DELETE", `"1"`, http.StatusOK},
+		{"delete with stale etag", "DELETE", `"2", "3"`, http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newMockService()
+			handler := NewComputerHandler(service)
+			service.CreateComputer(context.Background(), &models.Computer{
+				MACAddress:   "00:11:22:33:44:55",
+				ComputerName: "Test Computer",
+				IPAddress:    "192.168.1.100",
+			})
+
+			body, _ := json.Marshal(models.Computer{
+				MACAddress:   "00:11:22:33:44:55",
+				ComputerName: "Renamed",
+				IPAddress:    "192.168.1.100",
+			})
+			req := httptest.NewRequest(tt.method, "/api/computers/1", bytes.NewBuffer(body))
+			req.Header.Set("If-Match", tt.ifMatch)
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			w := httptest.NewRecorder()
+
+			switch tt.method {
+			case "PUT":
+				handler.UpdateComputer(w, req)
+			case "PATCH":
+				handler.PatchComputer(w, req)
+			case "DELETE":
+				handler.DeleteComputer(w, req)
+			}
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.method == "PUT" && w.Code == http.StatusOK && w.Header().Get("ETag") != `"2"` {
+				t.Errorf("Expected new ETag \"2\", got %s", w.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestGetComputersByUnknownEmployee(t *testing.T) {
+	handler := NewComputerHandler(newMockService())
+
+	for abbr, want := range map[string]int{"abc": http.StatusOK, "zzz": http.StatusNotFound} {
+		req := httptest.NewRequest("GET", "/api/employees/"+abbr+"/computers", nil)
+		req = mux.SetURLVars(req, map[string]string{"abbr": abbr})
+		w := httptest.NewRecorder()
+
+		handler.GetComputersByEmployee(w, req)
+
+		if w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", abbr, want, w.Code)
+		}
+	}
+}
+
+func TestCreateComputerBlockedByPolicy(t *testing.T) {
+	handler := NewComputerHandler(newMockService())
+
+	body := []byte(`{"mac_address":"00:11:22:33:44:55","computer_name":"Test","ip_address":"10.0.0.1","employee_abbreviation":"max"}`)
+	req := httptest.NewRequest("POST", "/api/computers", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	handler.CreateComputer(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+func TestCreateComputerProblemDetails(t *testing.T) {
+	handler := NewComputerHandler(newMockService())
+
+	post := func(body string) (*httptest.ResponseRecorder, problem) {
+		req := httptest.NewRequest("POST", "/api/computers", bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		handler.CreateComputer(w, req)
+
+		if ct := w.Header().Get("Content-Type"); w.Code >= 400 && ct != problemContentType {
+			t.Errorf("Expected Content-Type %s, got %s", problemContentType, ct)
+		}
+		var p problem
+		json.Unmarshal(w.Body.Bytes(), &p)
+		return w, p
+	}
+
+	// Every invalid field is reported at once
+	w, p := post(`{"ip_address":"10.0.0.1"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	if p.Status != w.Code || p.Instance != "/api/computers" || p.Type != validationProblem.uri {
+		t.Errorf("Unexpected problem %+v", p)
+	}
+	if len(p.Errors) != 2 || p.Errors[0].Field != "mac_address" || p.Errors[1].Field != "computer_name" {
+		t.Errorf("Expected errors for mac_address and computer_name, got %+v", p.Errors)
+	}
+
+	body := `{"mac_address":"00:11:22:33:44:55","computer_name":"Test","ip_address":"10.0.0.1"}`
+	if w, _ := post(body); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	w, p = post(body)
+	if w.Code != http.StatusConflict || p.Type != "/problems/duplicate-mac-address" {
+		t.Errorf("Expected duplicate MAC conflict, got %d %+v", w.Code, p)
+	}
+
+	w, p = post(`{`)
+	if w.Code != http.StatusBadRequest || p.Type != "about:blank" || p.Title != "Bad Request" {
+		t.Errorf("Expected generic bad request problem, got %d %+v", w.Code, p)
+	}
+}
+
+func TestRestoreAndPurgeComputers(t *testing.T) {
+	service := newMockService()
+	handler := NewComputerHandler(service)
+	service.CreateComputer(context.Background(), &models.Computer{MACAddress: "00:11:22:33:44:55", ComputerName: "Test", IPAddress: "10.0.0.1"})
+
+	for id, want := range map[string]int{"1": http.StatusConflict, "2": http.StatusNotFound} {
+		req := httptest.NewRequest("POST", "/api/computers/"+id+"/restore", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		w := httptest.NewRecorder()
+
+		handler.RestoreComputer(w, req)
+
+		if w.Code != want {
+			t.Errorf("Restore %s: expected status %d, got %d", id, want, w.Code)
+		}
+	}
+
+	for query, want := range map[string]int{"?older_than=48h": http.StatusOK, "?older_than=soon": http.StatusBadRequest} {
+		req := httptest.NewRequest("POST", "/api/admin/computers/purge"+query, nil)
+		w := httptest.NewRecorder()
+
+		handler.PurgeComputers(w, req)
+
+		if w.Code != want {
+			t.Errorf("Purge %s: expected status %d, got %d", query, want, w.Code)
+		}
+	}
+	if service.lastPurge == nil || *service.lastPurge != 48*time.Hour {
+		t.Errorf("Expected retention of 48h, got %v", service.lastPurge)
+	}
+
+	// An explicit zero is passed on, not confused with the default
+	for query, want := range map[string]*time.Duration{"?older_than=0s": new(time.Duration), "": nil} {
+		req := httptest.NewRequest("POST", "/api/admin/computers/purge"+query, nil)
+		handler.PurgeComputers(httptest.NewRecorder(), req)
+		if (service.lastPurge == nil) != (want == nil) || (want != nil && *service.lastPurge != *want) {
+			t.Errorf("Purge %q: expected retention %v, got %v", query, want, service.lastPurge)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"greenbone-case-study/pkg/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// EmployeeHandler handles HTTP requests for employees
+type EmployeeHandler struct {
+	service models.EmployeeService
+}
+
+// NewEmployeeHandler creates a new employee handler
+func NewEmployeeHandler(service models.EmployeeService) *EmployeeHandler {
+	return &EmployeeHandler{
+		service: service,
+	}
+}
+
+// CreateEmployee handles POST /employees
+func (h *EmployeeHandler) CreateEmployee(w http.ResponseWriter, r *http.Request) {
+	var employee models.Employee
+
+	if err := json.NewDecoder(r.Body).Decode(&employee); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	if err := h.service.CreateEmployee(r.Context(), &employee); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusCreated, employee)
+}
+
+// GetAllEmployees handles GET /employees
+func (h *EmployeeHandler) GetAllEmployees(w http.ResponseWriter, r *http.Request) {
+	opts := models.EmployeeQueryOptions{
+		Department: r.URL.Query().Get("department"),
+	}
+	if v := r.URL.Query().Get("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid active value, expected true or false")
+			return
+		}
+		opts.Active = &active
+	}
+
+	employees, err := h.service.GetAllEmployees(r.Context(), opts)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if employees == nil {
+		employees = []models.Employee{}
+	}
+
+	writeJSONResponse(w, http.StatusOK, employees)
+}
+
+// GetEmployee handles GET /employees/{abbr}
+func (h *EmployeeHandler) GetEmployee(w http.ResponseWriter, r *http.Request) {
+	abbr := mux.Vars(r)["abbr"]
+
+	employee, err := h.service.GetEmployee(r.Context(), abbr)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, employee)
+}
+
+// UpdateEmployee handles PUT /employees/{abbr}
+func (h *EmployeeHandler) UpdateEmployee(w http.ResponseWriter, r *http.Request) {
+	var employee models.Employee
+	if err := json.NewDecoder(r.Body).Decode(&employee); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	employee.Abbreviation = mux.Vars(r)["abbr"]
+
+	if err := h.service.UpdateEmployee(r.Context(), &employee); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, employee)
+}
+
+// DeleteEmployee handles DELETE /employees/{abbr}
+func (h *EmployeeHandler) DeleteEmployee(w http.ResponseWriter, r *http.Request) {
+	abbr := mux.Vars(r)["abbr"]
+
+	if err := h.service.DeleteEmployee(r.Context(), abbr); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{
+		"message": "Employee deleted successfully",
+	})
+}
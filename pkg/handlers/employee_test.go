@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"greenbone-case-study/pkg/models"
+	"greenbone-case-study/pkg/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// Mock employee service for testing
+type mockEmployeeService struct {
+	employees map[string]*models.Employee
+}
+
+func newMockEmployeeService() *mockEmployeeService {
+	return &mockEmployeeService{employees: make(map[string]*models.Employee)}
+}
+
+func (m *mockEmployeeService) CreateEmployee(ctx context.Context, employee *models.Employee) error {
+	if _, exists := m.employees[employee.Abbreviation]; exists {
+		return services.ErrEmployeeExists
+	}
+	employee.Active = true
+	m.employees[employee.Abbreviation] = employee
+	return nil
+}
+
+func (m *mockEmployeeService) GetAllEmployees(ctx context.Context, opts models.EmployeeQueryOptions) ([]models.Employee, error) {
+	var result []models.Employee
+	for _, employee := range m.employees {
+		result = append(result, *employee)
+	}
+	return result, nil
+}
+
+func (m *mockEmployeeService) GetEmployee(ctx context.Context, abbr string) (*models.Employee, error) {
+	employee, exists := m.employees[abbr]
+	if !exists {
+		return nil, services.ErrEmployeeNotFound
+	}
+	return employee, nil
+}
+
+func (m *mockEmployeeService) UpdateEmployee(ctx context.Context, employee *models.Employee) error {
+	if _, exists := m.employees[employee.Abbreviation]; !exists {
+		return services.ErrEmployeeNotFound
+	}
+	m.employees[employee.Abbreviation] = employee
+	return nil
+}
+
+func (m *mockEmployeeService) DeleteEmployee(ctx context.Context, abbr string) error {
+	if _, exists := m.employees[abbr]; !exists {
+		return services.ErrEmployeeNotFound
+	}
+	if abbr == "abc" {
+		return services.ErrEmployeeHasComputers
+	}
+	delete(m.employees, abbr)
+	return nil
+}
+
+func TestCreateEmployee(t *testing.T) {
+	service := newMockEmployeeService()
+	handler := NewEmployeeHandler(service)
+
+	body, _ := json.Marshal(models.Employee{Abbreviation: "mmu", Name: "Max Mustermann"})
+
+	for _, want := range []int{http.StatusCreated, http.StatusConflict} {
+		req := httptest.NewRequest("POST", "/api/employees", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+
+		handler.CreateEmployee(w, req)
+
+		if w.Code != want {
+			t.Errorf("Expected status %d, got %d", want, w.Code)
+		}
+	}
+}
+
+func TestGetAllEmployeesEmpty(t *testing.T) {
+	handler := NewEmployeeHandler(newMockEmployeeService())
+
+	req := httptest.NewRequest("GET", "/api/employees", nil)
+	w := httptest.NewRecorder()
+
+	handler.GetAllEmployees(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if w.Body.String() != "[]\n" {
+		t.Errorf("Expected empty array, got %s", w.Body.String())
+	}
+}
+
+func TestUpdateEmployeeUsesPathAbbreviation(t *testing.T) {
+	service := newMockEmployeeService()
+	handler := NewEmployeeHandler(service)
+	service.CreateEmployee(context.Background(), &models.Employee{Abbreviation: "mmu", Name: "Max"})
+
+	body, _ := json.Marshal(models.Employee{Abbreviation: "xyz", Name: "Max Mustermann", Active: false})
+	req := httptest.NewRequest("PUT", "/api/employees/mmu", bytes.NewBuffer(body))
+	req = mux.SetURLVars(req, map[string]string{"abbr": "mmu"})
+	w := httptest.NewRecorder()
+
+	handler.UpdateEmployee(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if service.employees["mmu"].Name != "Max Mustermann" || service.employees["mmu"].Active {
+		t.Errorf("Expected employee mmu to be renamed and deactivated, got %+v", service.employees["mmu"])
+	}
+}
+
+func TestDeleteEmployee(t *testing.T) {
+	service := newMockEmployeeService()
+	handler := NewEmployeeHandler(service)
+	service.CreateEmployee(context.Background(), &models.Employee{Abbreviation: "abc", Name: "Has Computers"})
+	service.CreateEmployee(context.Background(), &models.Employee{Abbreviation: "mmu", Name: "No Computers"})
+
+	tests := map[string]int{
+		"abc": http.StatusConflict,
+		"mmu": http.StatusOK,
+		"zzz": http.StatusNotFound,
+	}
+
+	for abbr, want := range tests {
+		req := httptest.NewRequest("DELETE", "/api/employees/"+abbr, nil)
+		req = mux.SetURLVars(req, map[string]string{"abbr": abbr})
+		w := httptest.NewRecorder()
+
+		handler.DeleteEmployee(w, req)
+
+		if w.Code != want {
+			t.Errorf("%s: expected status %d, got %d", abbr, want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"net/http"
+	"strings"
+)
+
+// computerETag returns the strong entity tag for a computer
+func computerETag(computer *models.Computer) string {
+	return fmt.Sprintf(`"%d"`, computer.Version)
+}
+
+// etagListContains reports whether an If-Match / If-None-Match header value
+// matches the given entity tag. Weak tags only match when weak is true.
+func etagListContains(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch evaluates the If-Match precondition for the computer with the given ID.
+// It returns the version the write must be conditional on (0 when the header is absent)
+// and false if a response has already been written.
+func (h *ComputerHandler) checkIfMatch(w http.ResponseWriter, r *http.Request, id uint) (uint, bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, true
+	}
+
+	computer, err := h.service.GetComputerByID(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return 0, false
+	}
+
+	if !etagListContains(header, computerETag(computer), false) {
+		w.Header().Set("ETag", computerETag(computer))
+		writeProblem(w, r, http.StatusPreconditionFailed, "Computer has been modified")
+		return 0, false
+	}
+	return computer.Version, true
+}
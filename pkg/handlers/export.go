@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// exportWriteTimeout bounds the time to write each row of an export
+const exportWriteTimeout = time.Minute
+
+// errNotAcceptable is returned when no format of the Accept header can be served
+var errNotAcceptable = errors.New("none of the accepted media types can be served")
+
+// exportColumn is a column of an export, named like the JSON field of the
+// computer. Its value is nil for an empty field.
+type exportColumn struct {
+	name  string
+	value func(computer *models.Computer) any
+}
+
+// exportColumns are the columns of an export in their default order
+var exportColumns = []exportColumn{
+	{"id", func(c *models.Computer) any { return c.ID }},
+	{"mac_address", func(c *models.Computer) any { return c.MACAddress }},
+	{"computer_name", func(c *models.Computer) any { return c.ComputerName }},
+	{"ip_address", func(c *models.Computer) any { return c.IPAddress }},
+	{"employee_abbreviation", func(c *models.Computer) any {
+		if c.EmployeeAbbreviation == nil {
+			return nil
+		}
+		return *c.EmployeeAbbreviation
+	}},
+	{"description", func(c *models.Computer) any { return c.Description }},
+	{"version", func(c *models.Computer) any { return c.Version }},
+	{"created_at", func(c *models.Computer) any { return exportTime(c.CreatedAt) }},
+	{"updated_at", func(c *models.Computer) any { return exportTime(c.UpdatedAt) }},
+	{"deleted_at", func(c *models.Computer) any {
+		if !c.DeletedAt.Valid {
+			return nil
+		}
+		return exportTime(c.DeletedAt.Time)
+	}},
+}
+
+// exportTime formats timestamps of every export format alike, in UTC with
+// second precision
+func exportTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// exportWriter writes the rows of an export
+type exportWriter interface {
+	Write(computer *models.Computer) error
+	// Close writes what is buffered and ends the document
+	Close() error
+}
+
+// exportFormat is a format computer listings can be exported in, selected by
+// the format query parameter or by one of the accept media types
+type exportFormat struct {
+	name      string
+	mediaType string
+	extension string
+	accept    []string
+	newWriter func(w io.Writer, columns []exportColumn) (exportWriter, error)
+}
+
+// exportFormats lists the export formats in order of preference for Accept
+// headers with media ranges such as text/*
+var exportFormats = []*exportFormat{
+	{
+		name:      "csv",
+		mediaType: "text/csv; charset=utf-8",
+		extension: "csv",
+		accept:    []string{"text/csv", "text/*"},
+		newWriter: func(w io.Writer, columns []exportColumn) (exportWriter, error) {
+			return newCSVExportWriter(w, columns, false)
+		},
+	},
+	{
+		// CSV that spreadsheet applications such as Excel open without an
+		// import dialog and without evaluating cells as formulas
+		name:      "excel",
+		mediaType: "text/csv; charset=utf-8",
+		extension: "csv",
+		accept:    []string{"application/vnd.ms-excel"},
+		newWriter: func(w io.Writer, columns []exportColumn) (exportWriter, error) {
+			return newCSVExportWriter(w, columns, true)
+		},
+	},
+	{
+		name:      "ndjson",
+		mediaType: "application/x-ndjson",
+		extension: "ndjson",
+		accept:    []string{"application/x-ndjson", "application/ndjson"},
+		newWriter: func(w io.Writer, columns []exportColumn) (exportWriter, error) {
+			return &ndjsonExportWriter{w: w, columns: columns}, nil
+		},
+	},
+	{
+		name:      "yaml",
+		mediaType: "application/yaml",
+		extension: "yaml",
+		accept:    []string{"application/yaml", "application/x-yaml", "text/yaml"},
+		newWriter: func(w io.Writer, columns []exportColumn) (exportWriter, error) {
+			return &yamlExportWriter{w: w, columns: columns}, nil
+		},
+	},
+}
+
+// negotiateExportFormat picks the format of a computer listing from the format
+// query parameter or else the Accept header. It returns nil for the paginated
+// JSON response, which is also served when the client accepts anything.
+func negotiateExportFormat(r *http.Request) (*exportFormat, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		if name == "json" {
+			return nil, nil
+		}
+		for _, format := range exportFormats {
+			if format.name == name {
+				return format, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid format %q, expected json, csv, excel, ndjson or yaml", name)
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return nil, nil
+	}
+
+	type mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, mediaRange{mediaType, quality})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].quality > ranges[j].quality })
+
+	for _, mediaRange := range ranges {
+		switch mediaRange.mediaType {
+		case "application/json", "application/*", "*/*":
+			return nil, nil
+		}
+		for _, format := range exportFormats {
+			for _, accepted := range format.accept {
+				if accepted == mediaRange.mediaType {
+					return format, nil
+				}
+			}
+		}
+	}
+	return nil, errNotAcceptable
+}
+
+// negotiateExport picks the format and columns of a computer listing. The
+// response varies by Accept header; if the request cannot be served, a
+// problem is written and ok is false.
+func negotiateExport(w http.ResponseWriter, r *http.Request, includeDeleted bool) (format *exportFormat, columns []exportColumn, ok bool) {
+	w.Header().Add("Vary", "Accept")
+
+	format, err := negotiateExportFormat(r)
+	if errors.Is(err, errNotAcceptable) {
+		writeProblem(w, r, http.StatusNotAcceptable, "Computers can be served as application/json, text/csv, application/vnd.ms-excel, application/x-ndjson or application/yaml")
+		return nil, nil, false
+	}
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return nil, nil, false
+	}
+	if format == nil {
+		return nil, nil, true
+	}
+
+	if columns, err = parseExportColumns(r.URL.Query(), includeDeleted); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return nil, nil, false
+	}
+	return format, columns, true
+}
+
+// parseExportColumns reads the columns query parameter. By default every
+// column is exported, deleted_at only if deleted computers are included.
+func parseExportColumns(values url.Values, includeDeleted bool) ([]exportColumn, error) {
+	spec := values.Get("columns")
+	if strings.TrimSpace(spec) == "" {
+		columns := exportColumns
+		if !includeDeleted {
+			columns = columns[:len(columns)-1]
+		}
+		return columns, nil
+	}
+
+	var columns []exportColumn
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		seen[name] = true
+
+		found := false
+		for _, column := range exportColumns {
+			if column.name == name {
+				columns = append(columns, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unsupported column %q", name)
+		}
+	}
+	return columns, nil
+}
+
+// writeExport streams the computers matching opts in format as an attachment
+// named filename. The status is sent with the first row, so errors before it
+// are reported as problems; an error after it aborts the response, so that
+// clients do not mistake a truncated export for a complete one. Exports are
+// not bound by the request timeout, and each row extends the write deadline
+// by exportWriteTimeout, so large exports are not cut short by the server's
+// write timeout.
+func (h *ComputerHandler) writeExport(w http.ResponseWriter, r *http.Request, format *exportFormat, columns []exportColumn, filename string, opts models.ComputerQueryOptions) {
+	ctx, cancel := withoutRequestTimeout(r)
+	defer cancel()
+	controller := http.NewResponseController(w)
+
+	var writer exportWriter
+	started := false
+	start := func() error {
+		started = true
+		w.Header().Set("Content-Type", format.mediaType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+			"filename": filename + "." + format.extension,
+		}))
+		w.WriteHeader(http.StatusOK)
+
+		var err error
+		writer, err = format.newWriter(w, columns)
+		return err
+	}
+
+	err := h.service.ExportComputers(ctx, opts, func(computer *models.Computer) error {
+		err := controller.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return writer.Write(computer)
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		return
+	}
+
+	if !started {
+		writeServiceError(w, r, err)
+		return
+	}
+	slog.ErrorContext(r.Context(), "Export failed after the response started", "format", format.name, "error", err)
+	panic(http.ErrAbortHandler)
+}
+
+// csvExportWriter writes a header row and one row per computer. For
+// spreadsheets it starts with a byte order mark, so UTF-8 is detected, ends
+// lines with CRLF and prefixes text that would be evaluated as a formula
+// with an apostrophe.
+type csvExportWriter struct {
+	w           *csv.Writer
+	columns     []exportColumn
+	spreadsheet bool
+	record      []string
+}
+
+func newCSVExportWriter(w io.Writer, columns []exportColumn, spreadsheet bool) (*csvExportWriter, error) {
+	if spreadsheet {
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return nil, err
+		}
+	}
+
+	writer := &csvExportWriter{w: csv.NewWriter(w), columns: columns, spreadsheet: spreadsheet, record: make([]string, len(columns))}
+	writer.w.UseCRLF = spreadsheet
+	for i, column := range columns {
+		writer.record[i] = column.name
+	}
+	return writer, writer.w.Write(writer.record)
+}
+
+func (e *csvExportWriter) Write(computer *models.Computer) error {
+	for i, column := range e.columns {
+		switch value := column.value(computer).(type) {
+		case nil:
+			e.record[i] = ""
+		case string:
+			if e.spreadsheet && value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+				value = "'" + value
+			}
+			e.record[i] = value
+		default:
+			e.record[i] = fmt.Sprint(value)
+		}
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvExportWriter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// ndjsonExportWriter writes one JSON object per computer and line, with the
+// keys in column order
+type ndjsonExportWriter struct {
+	w       io.Writer
+	columns []exportColumn
+	line    bytes.Buffer
+}
+
+func (e *ndjsonExportWriter) Write(computer *models.Computer) error {
+	e.line.Reset()
+	e.line.WriteByte('{')
+	for i, column := range e.columns {
+		if i > 0 {
+			e.line.WriteByte(',')
+		}
+		key, _ := json.Marshal(column.name)
+		value, err := json.Marshal(column.value(computer))
+		if err != nil {
+			return err
+		}
+		e.line.Write(key)
+		e.line.WriteByte(':')
+		e.line.Write(value)
+	}
+	e.line.WriteString("}\n")
+	_, err := e.w.Write(e.line.Bytes())
+	return err
+}
+
+func (e *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+// yamlExportWriter writes a YAML sequence with one mapping per computer.
+// Strings are quoted, so that no YAML version reads them as another type.
+type yamlExportWriter struct {
+	w       io.Writer
+	columns []exportColumn
+	written bool
+}
+
+func (e *yamlExportWriter) Write(computer *models.Computer) error {
+	mapping := &yaml.Node{Kind: yaml.MappingNode}
+	for _, column := range e.columns {
+		value := &yaml.Node{}
+		if err := value.Encode(column.value(computer)); err != nil {
+			return err
+		}
+		if value.Tag == "!!str" {
+			value.Style = yaml.DoubleQuotedStyle
+		}
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: column.name}, value)
+	}
+
+	// Each row is encoded as a sequence of one item; their concatenation is
+	// the sequence of all rows
+	item, err := yaml.Marshal([]*yaml.Node{mapping})
+	if err != nil {
+		return err
+	}
+	e.written = true
+	_, err = e.w.Write(item)
+	return err
+}
+
+func (e *yamlExportWriter) Close() error {
+	if !e.written {
+		_, err := io.WriteString(e.w, "[]\n")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"greenbone-case-study/pkg/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gopkg.in/yaml.v3"
+)
+
+func newExportService() *mockComputerService {
+	service := newMockService()
+	abbr := "abc"
+	created := time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("CET", 3600))
+	service.CreateComputer(context.Background(), &models.Computer{
+		MACAddress:           "00:11:22:33:44:55",
+		ComputerName:         "=HYPERLINK(\"http://example.com\")",
+		IPAddress:            "10.0.0.1",
+		EmployeeAbbreviation: &abbr,
+		Description:          "Desk, 2nd floor",
+		CreatedAt:            created,
+		UpdatedAt:            created,
+	})
+	service.CreateComputer(context.Background(), &models.Computer{
+		MACAddress:   "00:11:22:33:44:66",
+		ComputerName: "Spare",
+		IPAddress:    "10.0.0.2",
+		CreatedAt:    created,
+		UpdatedAt:    created,
+	})
+	return service
+}
+
+func TestExportComputersCSV(t *testing.T) {
+	handler := NewComputerHandler(newExportService())
+
+	req := httptest.NewRequest("GET", "/api/computers?limit=1", nil)
+	req.Header.Set("Accept", "application/json;q=0.5, text/csv")
+	w := httptest.NewRecorder()
+	handler.GetAllComputers(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "text/csv; charset=utf-8" {
+		t.Errorf("Expected CSV, got %s", contentType)
+	}
+	if disposition := w.Header().Get("Content-Disposition"); disposition != "attachment; filename=computers.csv" {
+		t.Errorf("Unexpected Content-Disposition %s", disposition)
+	}
+	if w.Header().Get("Vary") != "Accept" {
+		t.Error("Expected the response to vary by Accept")
+	}
+
+	// Exports are not paginated and timestamps are in UTC
+	expected := "id,mac_address,computer_name,ip_address,employee_abbreviation,description,version,created_at,updated_at\n" +
+		"1,00:11:22:33:44:55,\"=HYPERLINK(\"\"http://example.com\"\")\",10.0.0.1,abc,\"Desk, 2nd floor\",1,2024-03-01T11:30:00Z,2024-03-01T11:30:00Z\n" +
+		"2,00:11:22:33:44:66,Spare,10.0.0.2,,,1,2024-03-01T11:30:00Z,2024-03-01T11:30:00Z\n"
+	if w.Body.String() != expected {
+		t.Errorf("Unexpected CSV:\n%s", w.Body.String())
+	}
+}
+
+func TestExportComputersSpreadsheetCSV(t *testing.T) {
+	handler := NewComputerHandler(newExportService())
+
+	req := httptest.NewRequest("GET", "/api/computers?format=excel&columns=computer_name,id", nil)
+	w := httptest.NewRecorder()
+	handler.GetAllComputers(w, req)
+
+	expected := "\ufeffcomputer_name,id\r\n\"'=HYPERLINK(\"\"http://example.com\"\")\",1\r\nSpare,2\r\n"
+	if w.Body.String() != expected {
+		t.Errorf("Unexpected CSV:\n%q", w.Body.String())
+	}
+}
+
+func TestExportComputersNDJSON(t *testing.T) {
+	handler := NewComputerHandler(newExportService())
+
+	req := httptest.NewRequest("GET", "/api/computers?columns=mac_address,employee_abbreviation,created_at", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	w := httptest.NewRecorder()
+	handler.GetAllComputers(w, req)
+
+	expected := `{"mac_address":"00:11:22:33:44:55","employee_abbreviation":"abc","created_at":"2024-03-01T11:30:00Z"}` + "\n" +
+		`{"mac_address":"00:11:22:33:44:66","employee_abbreviation":null,"created_at":"2024-03-01T11:30:00Z"}` + "\n"
+	if w.Body.String() != expected {
+		t.Errorf("Unexpected NDJSON:\n%s", w.Body.String())
+	}
+}
+
+func TestExportComputersYAML(t *testing.T) {
+	handler := NewComputerHandler(newExportService())
+
+	req := httptest.NewRequest("GET", "/api/computers?format=yaml", nil)
+	w := httptest.NewRecorder()
+	handler.GetAllComputers(w, req)
+
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/yaml" {
+		t.Errorf("Expected YAML, got %s", contentType)
+	}
+	var rows []map[string]any
+	if err := yaml.Unmarshal(w.Body.Bytes(), &rows); err != nil {
+		t.Fatalf("Failed to parse YAML: %v\n%s", err, w.Body.String())
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(rows))
+	}
+	if rows[0]["id"] != 1 || rows[0]["mac_address"] != "00:11:22:33:44:55" || rows[1]["employee_abbreviation"] != nil {
+		t.Errorf("Unexpected rows %v", rows)
+	}
+	if rows[0]["created_at"] != "2024-03-01T11:30:00Z" {
+		t.Errorf("Expected timestamps as UTC strings, got %v", rows[0]["created_at"])
+	}
+
+	// An empty export is still a sequence
+	w = httptest.NewRecorder()
+	NewComputerHandler(newMockService()).GetAllComputers(w, req)
+	if w.Body.String() != "[]\n" {
+		t.Errorf("Expected an empty sequence, got %q", w.Body.String())
+	}
+}
+
+func TestExportComputersByEmployee(t *testing.T) {
+	handler := NewComputerHandler(newExportService())
+
+	req := httptest.NewRequest("GET", "/api/employees/abc/computers?format=csv&columns=mac_address", nil)
+	req = mux.SetURLVars(req, map[string]string{"abbr": "abc"})
+	w := httptest.NewRecorder()
+	handler.GetComputersByEmployee(w, req)
+
+	if w.Body.String() != "mac_address\n00:11:22:33:44:55\n" {
+		t.Errorf("Unexpected CSV:\n%s", w.Body.String())
+	}
+	if disposition := w.Header().Get("Content-Disposition"); disposition != "attachment; filename=computers-abc.csv" {
+		t.Errorf("Unexpected Content-Disposition %s", disposition)
+	}
+
+	req = httptest.NewRequest("GET", "/api/employees/zzz/computers?format=csv", nil)
+	req = mux.SetURLVars(req, map[string]string{"abbr": "zzz"})
+	w = httptest.NewRecorder()
+	handler.GetComputersByEmployee(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown employee, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestExportComputersNegotiation(t *testing.T) {
+	tests := []struct {
+		name   string
+		url    string
+		accept string
+		status int
+		json   bool
+	}{
+		{"no Accept header", "/api/computers", "", http.StatusOK, true},
+		{"browser", "/api/computers", "text/html,application/xhtml+xml,*/*;q=0.8", http.StatusOK, true},
+		{"format overrides Accept", "/api/computers?format=json", "text/csv", http.StatusOK, true},
+		{"unsupported media type", "/api/computers", "application/xml", http.StatusNotAcceptable, false},
+		{"refused media type", "/api/computers", "text/csv;q=0", http.StatusNotAcceptable, false},
+		{"unknown format", "/api/computers?format=xlsx", "", http.StatusBadRequest, false},
+		{"unknown column", "/api/computers?format=csv&columns=id,owner", "", http.StatusBadRequest, false},
+		{"duplicate column", "/api/computers?format=csv&columns=id,id", "", http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewComputerHandler(newExportService())
+
+			req := httptest.NewRequest("GET", tt.url, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			handler.GetAllComputers(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.json {
+				var response computerListResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || len(response.Data) != 2 {
+					t.Errorf("Expected the paginated JSON response, got %s", w.Body.String())
+				}
+			}
+			if tt.status != http.StatusOK && !strings.HasPrefix(w.Header().Get("Content-Type"), problemContentType) {
+				t.Errorf("Expected a problem, got %s", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+// slowExportService exports computers with a delay before each one, failing
+// once its context is done
+type slowExportService struct {
+	*mockComputerService
+	delay time.Duration
+}
+
+func (s slowExportService) ExportComputers(ctx context.Context, opts models.ComputerQueryOptions, fn func(computer *models.Computer) error) error {
+	return s.mockComputerService.ExportComputers(ctx, opts, func(computer *models.Computer) error {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return fn(computer)
+	})
+}
+
+func TestExportComputersOutlastsTimeouts(t *testing.T) {
+	handler := NewComputerHandler(slowExportService{newExportService(), 100 * time.Millisecond})
+	server := httptest.NewUnstartedServer(metricsMiddleware(timeoutMiddleware(50 * time.Millisecond)(http.HandlerFunc(handler.GetAllComputers))))
+	server.Config.WriteTimeout = 150 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"/api/computers", nil)
+	req.Header.Set("Accept", "text/csv")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Expected the export, got: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Expected the complete export, got: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || strings.Count(string(body), "\n") != 3 {
+		t.Errorf("Expected a header and two rows, got %d: %s", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"greenbone-case-study/pkg/models"
+	"log/slog"
+	"net/http"
+)
+
+// HealthHandler handles liveness and readiness probes
+type HealthHandler struct {
+	service models.HealthService
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(service models.HealthService) *HealthHandler {
+	return &HealthHandler{
+		service: service,
+	}
+}
+
+// Live handles GET /health/live. It only reports that the process serves
+// requests and checks no dependencies, so a failing database does not get the
+// process restarted.
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	writeJSONResponse(w, http.StatusOK, models.HealthReport{Status: models.HealthUp, Components: []models.ComponentHealth{}})
+}
+
+// Ready handles GET /health/ready and GET /health, reporting each dependency
+// with its latency. It responds with 503 if a required dependency is down.
+// Failures are logged rather than reported, as the probe is unauthenticated.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	report := h.service.CheckReadiness(r.Context())
+	for _, component := range report.Components {
+		if component.Status != models.HealthUp {
+			slog.WarnContext(r.Context(), "Health check failed",
+				"component", component.Name, "required", component.Required, "error", component.Error)
+		}
+	}
+
+	status := http.StatusOK
+	if report.Status == models.HealthDown {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSONResponse(w, status, report)
+}
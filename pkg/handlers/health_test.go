@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"greenbone-case-study/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// healthServiceFunc adapts a function to models.HealthService
+type healthServiceFunc func(ctx context.Context) *models.HealthReport
+
+func (f healthServiceFunc) CheckReadiness(ctx context.Context) *models.HealthReport {
+	return f(ctx)
+}
+
+func TestReadiness(t *testing.T) {
+	tests := []struct {
+		status string
+		want   int
+	}{
+		{models.HealthUp, http.StatusOK},
+		{models.HealthDegraded, http.StatusOK},
+		{models.HealthDown, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		handler := NewHealthHandler(healthServiceFunc(func(ctx context.Context) *models.HealthReport {
+			return &models.HealthReport{Status: tt.status, Components: []models.ComponentHealth{
+				{Name: "database", Status: tt.status, Required: true, LatencyMS: 1.5, Error: "dial tcp 10.0.0.5:5432: connection refused"},
+			}}
+		}))
+
+		w := httptest.NewRecorder()
+		handler.Ready(w, httptest.NewRequest("GET", "/api/health/ready", nil))
+
+		if w.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.status, tt.want, w.Code)
+		}
+		if strings.Contains(w.Body.String(), "10.0.0.5") {
+			t.Errorf("%s: expected no error details, got %s", tt.status, w.Body.String())
+		}
+		var report models.HealthReport
+		json.Unmarshal(w.Body.Bytes(), &report)
+		if report.Status != tt.status || len(report.Components) != 1 || report.Components[0].LatencyMS != 1.5 {
+			t.Errorf("%s: unexpected report %+v", tt.status, report)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxImportBodySize bounds the request body of an import
+const maxImportBodySize = 10 << 20
+
+// Media types accepted by ImportComputers
+const (
+	csvContentType    = "text/csv"
+	ndjsonContentType = "application/x-ndjson"
+)
+
+// errUnsupportedImportFormat is returned for a body that is neither CSV, a
+// JSON array nor NDJSON
+var errUnsupportedImportFormat = errors.New("unsupported import format")
+
+// importCSVColumns maps the CSV header columns of an import to setters of the
+// computer fields, named like their JSON fields
+var importCSVColumns = map[string]func(computer *models.Computer, value string){
+	"mac_address":   func(c *models.Computer, v string) { c.MACAddress = v },
+	"computer_name": func(c *models.Computer, v string) { c.ComputerName = v },
+	"ip_address":    func(c *models.Computer, v string) { c.IPAddress = v },
+	"employee_abbreviation": func(c *models.Computer, v string) {
+		if v != "" {
+			c.EmployeeAbbreviation = &v
+		}
+	},
+	"description": func(c *models.Computer, v string) { c.Description = v },
+	// An invalid subnet ID is read as 0, which validation rejects
+	"subnet_id": func(c *models.Computer, v string) {
+		if v != "" {
+			id, _ := strconv.ParseUint(v, 10, 32)
+			subnetID := uint(id)
+			c.SubnetID = &subnetID
+		}
+	},
+}
+
+// ImportComputers handles POST /computers/import?mode=atomic&dry_run=true. The
+// body is CSV with a header row, a JSON array or NDJSON, as named by its
+// Content-Type. The report is returned with 200, or with 422 if rows of an
+// atomic import failed, so that nothing was or, for a dry run, would be applied.
+func (h *ComputerHandler) ImportComputers(w http.ResponseWriter, r *http.Request) {
+	opts := models.ImportOptions{Mode: r.URL.Query().Get("mode")}
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if opts.DryRun, err = strconv.ParseBool(v); err != nil {
+			writeProblem(w, r, http.StatusBadRequest, fmt.Sprintf("invalid dry_run value %q, expected true or false", v))
+			return
+		}
+	}
+
+	computers, err := decodeImport(r.Header.Get("Content-Type"), http.MaxBytesReader(w, r.Body, maxImportBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writeProblem(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Import must not exceed %d bytes", tooLarge.Limit))
+		case errors.Is(err, errUnsupportedImportFormat):
+			w.Header().Set("Accept-Post", strings.Join([]string{csvContentType, "application/json", ndjsonContentType}, ", "))
+			writeProblem(w, r, http.StatusUnsupportedMediaType, "Import must be text/csv, application/json or application/x-ndjson")
+		default:
+			writeProblem(w, r, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	result, err := h.service.ImportComputers(r.Context(), computers, opts)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if result.Failed > 0 && result.Mode == models.ImportAtomic {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSONResponse(w, status, result)
+}
+
+// decodeImport reads the computers of an import body in the format of
+// contentType. At most models.MaxImportRows+1 rows are read, so the service
+// can reject larger imports without the rest being parsed.
+func decodeImport(contentType string, body io.Reader) ([]models.Computer, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case csvContentType:
+		return decodeImportCSV(body)
+	case "application/json":
+		return decodeImportJSON(body)
+	case ndjsonContentType, "application/ndjson":
+		return decodeImportNDJSON(body)
+	default:
+		return nil, errUnsupportedImportFormat
+	}
+}
+
+// decodeImportCSV reads CSV whose header row names the columns
+func decodeImportCSV(body io.Reader) ([]models.Computer, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return readError("invalid CSV", err)
+	}
+	setters := make([]func(*models.Computer, string), len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if setters[i] = importCSVColumns[column]; setters[i] == nil {
+			return nil, fmt.Errorf("unknown CSV column %q", column)
+		}
+	}
+
+	var computers []models.Computer
+	for len(computers) <= models.MaxImportRows {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return readError("invalid CSV", err)
+		}
+		var computer models.Computer
+		for i, value := range record {
+			setters[i](&computer, strings.TrimSpace(value))
+		}
+		computers = append(computers, computer)
+	}
+	return computers, nil
+}
+
+// decodeImportJSON reads a JSON array of computers
+func decodeImportJSON(body io.Reader) ([]models.Computer, error) {
+	decoder := json.NewDecoder(body)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		if err != nil {
+			return readError("invalid JSON", err)
+		}
+		return nil, errors.New("invalid JSON, expected an array of computers")
+	}
+
+	var computers []models.Computer
+	for decoder.More() && len(computers) <= models.MaxImportRows {
+		var computer models.Computer
+		if err := decoder.Decode(&computer); err != nil {
+			return readError(fmt.Sprintf("invalid JSON in row %d", len(computers)+1), err)
+		}
+		computers = append(computers, computer)
+	}
+	return computers, nil
+}
+
+// decodeImportNDJSON reads one JSON computer per line
+func decodeImportNDJSON(body io.Reader) ([]models.Computer, error) {
+	decoder := json.NewDecoder(body)
+
+	var computers []models.Computer
+	for len(computers) <= models.MaxImportRows {
+		var computer models.Computer
+		err := decoder.Decode(&computer)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return readError(fmt.Sprintf("invalid JSON in row %d", len(computers)+1), err)
+		}
+		computers = append(computers, computer)
+	}
+	return computers, nil
+}
+
+// readError describes a malformed body, unless reading it failed because it
+// exceeded its size limit
+func readError(detail string, err error) ([]models.Computer, error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%s: %v", detail, err)
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"greenbone-case-study/pkg/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestImportComputersFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "CSV",
+			contentType: "text/csv; charset=utf-8",
+			body: "\ufeffMAC_Address,computer_name,ip_address,employee_abbreviation\n" +
+				"00:11:22:33:44:55,First,10.0.0.1,abc\n" +
+				"00:11:22:33:44:66,\"Second, spare\",10.0.0.2,\n",
+		},
+		{
+			name:        "JSON array",
+			contentType: "application/json",
+			body: `[{"mac_address":"00:11:22:33:44:55","computer_name":"First","ip_address":"10.0.0.1","employee_abbreviation":"abc"},
+				{"mac_address":"00:11:22:33:44:66","computer_name":"Second, spare","ip_address":"10.0.0.2"}]`,
+		},
+		{
+			name:        "NDJSON",
+			contentType: "application/x-ndjson",
+			body: `{"mac_address":"00:11:22:33:44:55","computer_name":"First","ip_address":"10.0.0.1","employee_abbreviation":"abc"}` + "\n\n" +
+				`{"mac_address":"00:11:22:33:44:66","computer_name":"Second, spare","ip_address":"10.0.0.2"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newMockService()
+			handler := NewComputerHandler(service)
+
+			req := httptest.NewRequest("POST", "/api/computers/import?mode=best_effort&dry_run=true", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			handler.ImportComputers(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			if service.lastImportOpt.Mode != models.ImportBestEffort || !service.lastImportOpt.DryRun {
+				t.Errorf("Expected the query options to be passed on, got %+v", service.lastImportOpt)
+			}
+			computers := service.lastImport
+			if len(computers) != 2 {
+				t.Fatalf("Expected 2 computers, got %d", len(computers))
+			}
+			if computers[0].EmployeeAbbreviation == nil || *computers[0].EmployeeAbbreviation != "abc" {
+				t.Errorf("Expected the first computer to be assigned to abc, got %+v", computers[0])
+			}
+			if computers[1].ComputerName != "Second, spare" || computers[1].EmployeeAbbreviation != nil {
+				t.Errorf("Unexpected second computer %+v", computers[1])
+			}
+
+			var result models.ImportResult
+			json.Unmarshal(w.Body.Bytes(), &result)
+			if result.Created != 2 || len(result.Rows) != 2 {
+				t.Errorf("Expected the import report, got %+v", result)
+			}
+		})
+	}
+}
+
+func TestImportComputersFailedAtomicImport(t *testing.T) {
+	service := newMockService()
+	handler := NewComputerHandler(service)
+
+	body := "mac_address,computer_name,ip_address\n00:11:22:33:44:55,,10.0.0.1\n"
+	req := httptest.NewRequest("POST", "/api/computers/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	handler.ImportComputers(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	var result models.ImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if result.Failed != 1 || result.Rows[0].Errors[0].Field != "computer_name" {
+		t.Errorf("Expected the row errors to be reported, got %+v", result)
+	}
+}
+
+func TestImportComputersRejectsMalformedBodies(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		url         string
+		status      int
+	}{
+		{"unsupported format", "application/xml", "<computers/>", "", http.StatusUnsupportedMediaType},
+		{"missing content type", "", "[]", "", http.StatusUnsupportedMediaType},
+		{"unknown CSV column", "text/csv", "mac_address,owner\n00:11:22:33:44:55,abc\n", "", http.StatusBadRequest},
+		{"ragged CSV", "text/csv", "mac_address,computer_name\n00:11:22:33:44:55\n", "", http.StatusBadRequest},
+		{"JSON object", "application/json", `{"mac_address":"00:11:22:33:44:55"}`, "", http.StatusBadRequest},
+		{"invalid NDJSON line", "application/x-ndjson", "{\"computer_name\":\"First\"}\n{oops}\n", "", http.StatusBadRequest},
+		{"invalid dry_run", "application/json", "[]", "?dry_run=maybe", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewComputerHandler(newMockService())
+
+			req := httptest.NewRequest("POST", "/api/computers/import"+tt.url, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			handler.ImportComputers(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
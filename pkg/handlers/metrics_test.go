@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"greenbone-case-study/pkg/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddlewareUsesRouteTemplate(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(metricsMiddleware)
+	router.HandleFunc("/api/computers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusNotFound, "Computer not found")
+	}).Methods("GET")
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	requests := metrics.HTTPRequests.WithLabelValues("GET", "/api/computers/{id}", "404")
+	before := testutil.ToFloat64(requests)
+
+	for _, path := range []string{"/api/computers/1", "/api/computers/2"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	if got := testutil.ToFloat64(requests) - before; got != 2 {
+		t.Errorf("Expected 2 requests counted under the route template, got %v", got)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`computer_management_http_requests_total{method="GET",route="/api/computers/{id}",status="404"}`,
+		`computer_management_http_request_duration_seconds_bucket{method="GET",route="/api/computers/{id}",status="404"`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %s", want)
+		}
+	}
+}
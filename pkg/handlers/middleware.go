@@ -1,48 +1,213 @@
 package handlers
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"greenbone-case-study/pkg/metrics"
+	"greenbone-case-study/pkg/models"
+	"greenbone-case-study/pkg/services"
+	"greenbone-case-study/pkg/tracing"
+	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// loggingMiddleware logs HTTP requests
+// maxRequestIDLength bounds client-supplied request IDs
+const maxRequestIDLength = 128
+
+// requestIDMiddleware takes the request ID from the X-Request-ID header or
+// generates one, echoes it in the response and stores it in the request context
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = newRequestID()
+		}
+
+		w.Header().Set("X-Request-ID", requestID)
+		next.ServeHTTP(w, r.WithContext(services.WithRequestID(r.Context(), requestID)))
+	})
+}
+
+// newRequestID returns a random 128-bit hex request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// accessLogKey stores the accessLogEntry of a request in its context
+type accessLogKey struct{}
+
+// accessLogEntry collects what inner handlers learn about a request for its
+// access log line, such as the principal authenticated by authMiddleware
+type accessLogEntry struct {
+	principal *models.Principal
+}
+
+// loggingMiddleware logs HTTP requests with the request ID and trace of their
+// context and the authenticated principal
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
 		// Create a response writer wrapper to capture status code
 		wrapper := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		entry := &accessLogEntry{}
 
-		next.ServeHTTP(wrapper, r)
+		next.ServeHTTP(wrapper, r.WithContext(context.WithValue(r.Context(), accessLogKey{}, entry)))
 
-		log.Printf(
-			"%s %s %d %v %s",
-			r.Method,
-			r.RequestURI,
-			wrapper.statusCode,
-			time.Since(start),
-			r.RemoteAddr,
-		)
+		attrs := []any{
+			"method", r.Method,
+			"path", r.RequestURI,
+			"route", routeTemplate(r),
+			"status", wrapper.statusCode,
+			"duration", time.Since(start),
+			"remote_addr", r.RemoteAddr,
+		}
+		if entry.principal != nil {
+			attrs = append(attrs, "principal", entry.principal.Subject, "role", entry.principal.Role)
+		}
+		slog.InfoContext(r.Context(), "Request", attrs...)
 	})
 }
 
-// corsMiddleware handles CORS headers
-func corsMiddleware(next http.Handler) http.Handler {
+// recordPrincipal adds the principal to the access log line of the request
+func recordPrincipal(r *http.Request, principal *models.Principal) {
+	if entry, ok := r.Context().Value(accessLogKey{}).(*accessLogEntry); ok {
+		entry.principal = principal
+	}
+}
+
+// tracingMiddleware starts a server span for each request, continuing the
+// trace of an incoming traceparent header
+func tracingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		route := routeTemplate(r)
+		ctx, span := tracing.Tracer().Start(tracing.Extract(r.Context(), r.Header), r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(r.URL.Path),
+				attribute.String("request.id", services.RequestIDFromContext(r.Context())),
+			))
+		defer span.End()
+
+		wrapper := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(wrapper, r.WithContext(ctx))
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
+		span.SetAttributes(semconv.HTTPResponseStatusCode(wrapper.statusCode))
+		if wrapper.statusCode >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(wrapper.statusCode))
 		}
+	})
+}
 
-		next.ServeHTTP(w, r)
+// metricsMiddleware counts requests and observes their latency by method,
+// route template and status code
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		wrapper := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+		next.ServeHTTP(wrapper, r)
+
+		route := routeTemplate(r)
+		status := strconv.Itoa(wrapper.statusCode)
+		metrics.HTTPRequests.WithLabelValues(r.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
 	})
 }
 
+// untimedContextKey stores the context of a request before timeoutMiddleware
+// bounded it
+type untimedContextKey struct{}
+
+// timeoutMiddleware bounds the context of each request by timeout, so that
+// database queries of slow requests are cancelled. A timeout of zero or less
+// leaves requests unbounded.
+func timeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			ctx = context.WithValue(ctx, untimedContextKey{}, r.Context())
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// withoutRequestTimeout returns the context of a request without the deadline
+// of timeoutMiddleware, for responses that are streamed for as long as they
+// take. It keeps the values of the request context and is still cancelled
+// when the client goes away or the server shuts down.
+func withoutRequestTimeout(r *http.Request) (context.Context, context.CancelFunc) {
+	untimed, ok := r.Context().Value(untimedContextKey{}).(context.Context)
+	if !ok {
+		return context.WithCancel(r.Context())
+	}
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	stop := context.AfterFunc(untimed, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// routeTemplate returns the path template of the matched route, such as
+// /api/computers/{id}, so requests can be grouped without their IDs
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unmatched"
+}
+
+// corsMiddleware allows cross-origin requests from allowedOrigins, which hold
+// origins such as "https://inventory.example.com", or "*" for any origin. The
+// CORS headers are only sent to allowed origins; without any, browsers keep
+// every cross-origin request from reading responses.
+func corsMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
+	anyOrigin := slices.Contains(allowedOrigins, "*")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(allowedOrigins) > 0 {
+				// Responses depend on the origin, so caches must not share them
+				w.Header().Add("Vary", "Origin")
+			}
+			origin := r.Header.Get("Origin")
+			if origin != "" && (anyOrigin || slices.Contains(allowedOrigins, origin)) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, If-None-Match, X-API-Key, X-Request-ID, traceparent, tracestate")
+				w.Header().Set("Access-Control-Expose-Headers", "Content-Disposition, ETag, X-Request-ID")
+			}
+
+			if r.Method == "OPTIONS" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // responseWriter wrapper to capture status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -53,3 +218,9 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap returns the wrapped writer, so http.ResponseController reaches the
+// connection
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
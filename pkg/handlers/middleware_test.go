@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"greenbone-case-study/pkg/logging"
+	"greenbone-case-study/pkg/models"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingMiddlewareContinuesIncomingTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	var handlerSpan trace.SpanContext
+	router := mux.NewRouter()
+	router.Use(tracingMiddleware)
+	router.HandleFunc("/api/computers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusInternalServerError)
+	}).Methods("GET")
+
+	req := httptest.NewRequest("GET", "/api/computers/7", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /api/computers/{id}" || span.SpanKind != trace.SpanKindServer {
+		t.Errorf("Unexpected span %s of kind %v", span.Name, span.SpanKind)
+	}
+	if span.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || span.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("Expected the incoming trace to be continued, got trace %s parent %s", span.SpanContext.TraceID(), span.Parent.SpanID())
+	}
+	if handlerSpan.SpanID() != span.SpanContext.SpanID() {
+		t.Error("Expected the handler to run in the request span")
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("Expected a server error to mark the span, got %v", span.Status)
+	}
+}
+
+func TestLoggingMiddlewareLogsRequestAndPrincipal(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := logging.New(logging.Config{Writer: &out})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	keys := &mockAPIKeyService{principals: map[string]*models.Principal{
+		"viewer-key": {Subject: "dashboard", Role: models.RoleViewer},
+	}}
+	router := mux.NewRouter()
+	router.Use(requestIDMiddleware)
+	router.Use(loggingMiddleware)
+	router.Use(authMiddleware(keys, nil))
+	router.HandleFunc("/api/computers/{id}", requireRole(models.RoleViewer, func(w http.ResponseWriter, r *http.Request) {
+		slog.InfoContext(r.Context(), "Handling")
+	})).Methods("GET")
+
+	req := httptest.NewRequest("GET", "/api/computers/7", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	req.Header.Set("X-API-Key", "viewer-key")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %q", out.String())
+	}
+	for _, line := range lines {
+		var record map[string]interface{}
+		json.Unmarshal([]byte(line), &record)
+		if record["request_id"] != "req-1" || record["principal"] != "dashboard" {
+			t.Errorf("Expected request ID and principal in %s", line)
+		}
+	}
+
+	var access map[string]interface{}
+	json.Unmarshal([]byte(lines[1]), &access)
+	if access["msg"] != "Request" || access["route"] != "/api/computers/{id}" || access["status"] != float64(http.StatusOK) {
+		t.Errorf("Unexpected access log line %s", lines[1])
+	}
+}
+
+func TestTimeoutMiddlewareReportsTimedOutRequests(t *testing.T) {
+	handler := timeoutMiddleware(time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		// Drivers report cancelled queries with errors of their own
+		writeServiceError(w, r, errors.New("interrupted"))
+	}))
+
+	req := httptest.NewRequest("GET", "/api/computers", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	var p problem
+	json.Unmarshal(w.Body.Bytes(), &p)
+	if p.Type != "/problems/request-timeout" {
+		t.Errorf("Expected the request-timeout problem, got %q", p.Type)
+	}
+}
+
+func TestCORSMiddlewareAllowsConfiguredOrigins(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    string
+	}{
+		{"no origins allowed by default", nil, "https://evil.example.com", ""},
+		{"allowed origin", []string{"https://inventory.example.com"}, "https://inventory.example.com", "https://inventory.example.com"},
+		{"other origin", []string{"https://inventory.example.com"}, "https://evil.example.com", ""},
+		{"any origin", []string{"*"}, "https://evil.example.com", "https://evil.example.com"},
+		{"same-origin request", []string{"*"}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, method := range []string{"GET", "OPTIONS"} {
+				req := httptest.NewRequest(method, "/api/computers", nil)
+				if tt.origin != "" {
+					req.Header.Set("Origin", tt.origin)
+				}
+				w := httptest.NewRecorder()
+				corsMiddleware(tt.allowed)(next).ServeHTTP(w, req)
+
+				if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.want {
+					t.Errorf("%s: expected Access-Control-Allow-Origin %q, got %q", method, tt.want, got)
+				}
+				if allowsMethods := w.Header().Get("Access-Control-Allow-Methods") != ""; allowsMethods != (tt.want != "") {
+					t.Errorf("%s: expected CORS headers only for allowed origins, got %v", method, w.Header())
+				}
+				if vary := w.Header().Get("Vary"); (vary == "Origin") != (len(tt.allowed) > 0) {
+					t.Errorf("%s: unexpected Vary header %q", method, vary)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"greenbone-case-study/pkg/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// OutboxHandler handles admin HTTP requests for the notification outbox
+type OutboxHandler struct {
+	service models.OutboxService
+}
+
+// NewOutboxHandler creates a new outbox handler
+func NewOutboxHandler(service models.OutboxService) *OutboxHandler {
+	return &OutboxHandler{
+		service: service,
+	}
+}
+
+// GetMessages handles GET /admin/outbox?status=pending|delivered|failed|stuck
+func (h *OutboxHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+	}
+
+	var messages []models.OutboxMessage
+	var err error
+	if status == "stuck" {
+		messages, err = h.service.GetStuckMessages(r.Context(), limit)
+	} else {
+		messages, err = h.service.GetMessages(r.Context(), models.OutboxQueryOptions{Status: status, Limit: limit})
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if messages == nil {
+		messages = []models.OutboxMessage{}
+	}
+
+	writeJSONResponse(w, http.StatusOK, messages)
+}
+
+// ReplayMessage handles POST /admin/outbox/{id}/replay
+func (h *OutboxHandler) ReplayMessage(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid message ID")
+		return
+	}
+
+	message, err := h.service.ReplayMessage(r.Context(), uint(id))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, message)
+}
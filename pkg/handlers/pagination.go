@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// computerListResponse is the body of GET /computers
+type computerListResponse struct {
+	Data       []models.Computer `json:"data"`
+	Pagination paginationMeta    `json:"pagination"`
+	Links      paginationLinks   `json:"links"`
+}
+
+// paginationMeta describes the returned page
+type paginationMeta struct {
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// paginationLinks holds navigation links relative to the API root
+type paginationLinks struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// parseComputerQuery reads list options from the query string
+func parseComputerQuery(values url.Values) (models.ComputerQueryOptions, error) {
+	var opts models.ComputerQueryOptions
+	var err error
+
+	if v := values.Get("limit"); v != "" {
+		if opts.Limit, err = strconv.Atoi(v); err != nil || opts.Limit < 1 {
+			return opts, fmt.Errorf("invalid limit %q", v)
+		}
+	}
+	if v := values.Get("offset"); v != "" {
+		if opts.Offset, err = strconv.Atoi(v); err != nil || opts.Offset < 0 {
+			return opts, fmt.Errorf("invalid offset %q", v)
+		}
+	}
+	opts.Cursor = values.Get("cursor")
+	if opts.Cursor != "" && opts.Offset > 0 {
+		return opts, fmt.Errorf("cursor and offset cannot be combined")
+	}
+
+	opts.ComputerName = values.Get("computer_name")
+	opts.EmployeeAbbreviation = values.Get("employee_abbreviation")
+
+	// Address filters match the canonical form addresses are stored in
+	if v := values.Get("ip_address"); v != "" {
+		if opts.IPAddress, err = models.ParseIPAddress(v); err != nil {
+			return opts, err
+		}
+	}
+	if v := values.Get("ip_in"); v != "" {
+		if opts.IPNetwork, err = models.ParseIPNetwork(v); err != nil {
+			return opts, err
+		}
+	}
+	if v := values.Get("mac_address"); v != "" {
+		if opts.MACAddress, err = models.ParseMACAddress(v); err != nil {
+			return opts, err
+		}
+	}
+
+	if opts.IncludeDeleted, err = parseIncludeDeleted(values); err != nil {
+		return opts, err
+	}
+
+	if v := values.Get("assigned"); v != "" {
+		assigned, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid assigned value %q, expected true or false", v)
+		}
+		opts.Assigned = &assigned
+	}
+
+	dates := []struct {
+		param string
+		dest  **time.Time
+	}{
+		{"created_after", &opts.CreatedAfter},
+		{"created_before", &opts.CreatedBefore},
+		{"updated_after", &opts.UpdatedAfter},
+		{"updated_before", &opts.UpdatedBefore},
+	}
+	for _, d := range dates {
+		if v := values.Get(d.param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return opts, fmt.Errorf("invalid %s, expected RFC 3339 timestamp", d.param)
+			}
+			*d.dest = &t
+		}
+	}
+
+	if opts.Sort, err = models.ParseSort(values.Get("sort")); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+// parseIncludeDeleted reads the include_deleted query parameter
+func parseIncludeDeleted(values url.Values) (bool, error) {
+	v := values.Get("include_deleted")
+	if v == "" {
+		return false, nil
+	}
+	includeDeleted, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid include_deleted value %q, expected true or false", v)
+	}
+	return includeDeleted, nil
+}
+
+// newComputerListResponse wraps a page with pagination metadata and links
+func newComputerListResponse(r *http.Request, page *models.ComputerPage) computerListResponse {
+	items := page.Items
+	if items == nil {
+		items = []models.Computer{}
+	}
+
+	response := computerListResponse{
+		Data: items,
+		Pagination: paginationMeta{
+			Total:      page.Total,
+			Limit:      page.Limit,
+			Offset:     page.Offset,
+			NextCursor: page.NextCursor,
+			PrevCursor: page.PrevCursor,
+		},
+		Links: paginationLinks{Self: r.URL.RequestURI()},
+	}
+
+	if r.URL.Query().Get("cursor") != "" {
+		if page.NextCursor != "" {
+			response.Links.Next = pageLink(r, page.Limit, -1, page.NextCursor)
+		}
+		if page.PrevCursor != "" {
+			response.Links.Prev = pageLink(r, page.Limit, -1, page.PrevCursor)
+		}
+		return response
+	}
+
+	if int64(page.Offset+page.Limit) < page.Total {
+		response.Links.Next = pageLink(r, page.Limit, page.Offset+page.Limit, "")
+	}
+	if page.Offset > 0 {
+		prev := page.Offset - page.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		response.Links.Prev = pageLink(r, page.Limit, prev, "")
+	}
+	return response
+}
+
+// pageLink returns the request URL with its pagination parameters replaced
+func pageLink(r *http.Request, limit, offset int, cursor string) string {
+	query := r.URL.Query()
+	query.Del("offset")
+	query.Del("cursor")
+	query.Set("limit", strconv.Itoa(limit))
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	} else if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+	return r.URL.Path + "?" + query.Encode()
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"greenbone-case-study/pkg/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// PolicyHandler handles HTTP requests for assignment policies
+type PolicyHandler struct {
+	service models.PolicyService
+}
+
+// NewPolicyHandler creates a new policy handler
+func NewPolicyHandler(service models.PolicyService) *PolicyHandler {
+	return &PolicyHandler{
+		service: service,
+	}
+}
+
+// CreatePolicy handles POST /policies
+func (h *PolicyHandler) CreatePolicy(w http.ResponseWriter, r *http.Request) {
+	var policy models.AssignmentPolicy
+
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	if err := h.service.CreatePolicy(r.Context(), &policy); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusCreated, policy)
+}
+
+// GetAllPolicies handles GET /policies
+func (h *PolicyHandler) GetAllPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.service.GetAllPolicies(r.Context())
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if policies == nil {
+		policies = []models.AssignmentPolicy{}
+	}
+
+	writeJSONResponse(w, http.StatusOK, policies)
+}
+
+// GetPolicy handles GET /policies/{id}
+func (h *PolicyHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid policy ID")
+		return
+	}
+
+	policy, err := h.service.GetPolicy(r.Context(), uint(id))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, policy)
+}
+
+// UpdatePolicy handles PUT /policies/{id}
+func (h *PolicyHandler) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid policy ID")
+		return
+	}
+
+	var policy models.AssignmentPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	policy.ID = uint(id)
+
+	if err := h.service.UpdatePolicy(r.Context(), &policy); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, policy)
+}
+
+// DeletePolicy handles DELETE /policies/{id}
+func (h *PolicyHandler) DeletePolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid policy ID")
+		return
+	}
+
+	if err := h.service.DeletePolicy(r.Context(), uint(id)); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{
+		"message": "Policy deleted successfully",
+	})
+}
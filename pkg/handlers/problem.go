@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"greenbone-case-study/pkg/services"
+	"log/slog"
+	"net/http"
+)
+
+// problemContentType is the media type of RFC 7807 error responses
+const problemContentType = "application/problem+json"
+
+// problem is an RFC 7807 problem details object. Errors lists the invalid
+// fields of validation problems.
+type problem struct {
+	Type     string                `json:"type"`
+	Title    string                `json:"title"`
+	Status   int                   `json:"status"`
+	Detail   string                `json:"detail,omitempty"`
+	Instance string                `json:"instance,omitempty"`
+	Errors   []services.FieldError `json:"errors,omitempty"`
+}
+
+// problemType describes the problem reported for a domain error
+type problemType struct {
+	err    error
+	status int
+	uri    string
+	title  string
+}
+
+// validationProblem is reported for services.ValidationError
+var validationProblem = problemType{
+	status: http.StatusUnprocessableEntity,
+	uri:    "/problems/validation-error",
+	title:  "Request validation failed",
+}
+
+// problemTypes maps domain errors to problem types, checked in order
+var problemTypes = []problemType{
+	{services.ErrComputerNotFound, http.StatusNotFound, "/problems/computer-not-found", "Computer not found"},
+	{services.ErrEmployeeNotFound, http.StatusNotFound, "/problems/employee-not-found", "Employee not found"},
+	{services.ErrPolicyNotFound, http.StatusNotFound, "/problems/policy-not-found", "Policy not found"},
+	{services.ErrOutboxMessageNotFound, http.StatusNotFound, "/problems/outbox-message-not-found", "Outbox message not found"},
+	{services.ErrAPIKeyNotFound, http.StatusNotFound, "/problems/api-key-not-found", "API key not found"},
+	{services.ErrSubnetNotFound, http.StatusNotFound, "/problems/subnet-not-found", "Subnet not found"},
+	{models.ErrVersionConflict, http.StatusPreconditionFailed, "/problems/version-conflict", "Resource has been modified"},
+	{services.ErrMACAddressInUse, http.StatusConflict, "/problems/duplicate-mac-address", "MAC address already in use"},
+	{services.ErrAssignmentBlocked, http.StatusConflict, "/problems/assignment-blocked", "Assignment blocked by policy"},
+	{services.ErrComputerNotDeleted, http.StatusConflict, "/problems/computer-not-deleted", "Computer is not deleted"},
+	{services.ErrEmployeeExists, http.StatusConflict, "/problems/employee-exists", "Employee already exists"},
+	{services.ErrEmployeeHasComputers, http.StatusConflict, "/problems/employee-has-computers", "Employee still has computers"},
+	{services.ErrPolicyExists, http.StatusConflict, "/problems/policy-exists", "Policy already exists"},
+	{services.ErrOutboxMessageDelivered, http.StatusConflict, "/problems/outbox-message-delivered", "Outbox message already delivered"},
+	{services.ErrOutboxMessageLeased, http.StatusConflict, "/problems/outbox-message-leased", "Outbox message is being delivered"},
+	{services.ErrOutboxMessageChanged, http.StatusConflict, "/problems/outbox-message-changed", "Outbox message changed concurrently"},
+	{services.ErrAPIKeyExists, http.StatusConflict, "/problems/api-key-exists", "API key already exists"},
+	{services.ErrAPIKeyRevoked, http.StatusConflict, "/problems/api-key-revoked", "API key is revoked"},
+	{services.ErrSubnetOverlaps, http.StatusConflict, "/problems/subnet-overlaps", "Subnet overlaps another subnet"},
+	{services.ErrSubnetExhausted, http.StatusConflict, "/problems/subnet-exhausted", "Subnet has no free address"},
+	{services.ErrIPAddressInUse, http.StatusConflict, "/problems/ip-address-in-use", "IP address already in use"},
+	{services.ErrPatchTestFailed, http.StatusConflict, "/problems/patch-test-failed", "Patch test operation failed"},
+	{services.ErrInvalidPatch, http.StatusBadRequest, "/problems/invalid-patch", "Invalid patch document"},
+	{services.ErrUnsupportedPatchType, http.StatusUnsupportedMediaType, "/problems/unsupported-patch-type", "Unsupported patch format"},
+	{models.ErrInvalidCursor, http.StatusBadRequest, "/problems/invalid-cursor", "Invalid cursor"},
+	{context.DeadlineExceeded, http.StatusServiceUnavailable, "/problems/request-timeout", "Request timed out"},
+	{context.Canceled, http.StatusServiceUnavailable, "/problems/request-cancelled", "Request cancelled"},
+}
+
+// writeProblem writes a generic problem for errors detected by the handler
+// itself, such as malformed JSON or query parameters
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	writeProblemDetails(w, problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+	})
+}
+
+// writeServiceError writes the problem for an error returned by a service.
+// Errors of requests whose context has ended are reported as timed out or
+// cancelled, since drivers do not always wrap the context error. Unknown
+// errors are logged and reported as 500 without their details.
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	if ctxErr := r.Context().Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		err = fmt.Errorf("%w: %v", ctxErr, err)
+	}
+
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		writeProblemDetails(w, problem{
+			Type:     validationProblem.uri,
+			Title:    validationProblem.title,
+			Status:   validationProblem.status,
+			Detail:   "One or more fields are invalid",
+			Instance: r.URL.Path,
+			Errors:   validationErr.Errors,
+		})
+		return
+	}
+
+	for _, pt := range problemTypes {
+		if errors.Is(err, pt.err) {
+			writeProblemDetails(w, problem{
+				Type:     pt.uri,
+				Title:    pt.title,
+				Status:   pt.status,
+				Detail:   err.Error(),
+				Instance: r.URL.Path,
+			})
+			return
+		}
+	}
+
+	slog.ErrorContext(r.Context(), "Unexpected error", "method", r.Method, "path", r.URL.Path, "error", err)
+	writeProblem(w, r, http.StatusInternalServerError, "An unexpected error occurred")
+}
+
+// writeProblemDetails writes p as an application/problem+json response
+func writeProblemDetails(w http.ResponseWriter, p problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
@@ -1,45 +1,109 @@
 package handlers
 
 import (
+	"greenbone-case-study/pkg/metrics"
 	"greenbone-case-study/pkg/models"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
-// SetupRoutes sets up all HTTP routes
-func SetupRoutes(service models.ComputerService) *mux.Router {
+// SetupRoutes sets up all HTTP routes. Every route except the health checks
+// and the Prometheus metrics requires an API key or, if tokenAuthenticator is set, a JWT with at least
+// the role given here: viewers can read, operators can also change computers
+// and employees, and admins can manage policies, subnets, the audit log, the
+// outbox and API keys. The context of each request is cancelled after
+// requestTimeout, unless it is zero. Browsers may only call the API from
+// allowedOrigins.
+func SetupRoutes(service models.ComputerService, employeeService models.EmployeeService, outboxService models.OutboxService, policyService models.PolicyService, subnetService models.SubnetService, auditService models.AuditService, apiKeyService models.APIKeyService, healthService models.HealthService, tokenAuthenticator models.TokenAuthenticator, requestTimeout time.Duration, allowedOrigins []string) *mux.Router {
 	router := mux.NewRouter()
 
 	// Add middleware
+	router.Use(requestIDMiddleware)
+	router.Use(tracingMiddleware)
 	router.Use(loggingMiddleware)
-	router.Use(corsMiddleware)
+	router.Use(metricsMiddleware)
+	router.Use(timeoutMiddleware(requestTimeout))
+	router.Use(corsMiddleware(allowedOrigins))
+	router.Use(authMiddleware(apiKeyService, tokenAuthenticator))
 
-	// Create handler
+	// Create handlers
 	computerHandler := NewComputerHandler(service)
+	employeeHandler := NewEmployeeHandler(employeeService)
+	outboxHandler := NewOutboxHandler(outboxService)
+	policyHandler := NewPolicyHandler(policyService)
+	subnetHandler := NewSubnetHandler(subnetService)
+	auditHandler := NewAuditHandler(auditService)
+	apiKeyHandler := NewAPIKeyHandler(apiKeyService)
+	healthHandler := NewHealthHandler(healthService)
 
 	// API routes
 	api := router.PathPrefix("/api").Subrouter()
 
+	viewer := func(handler http.HandlerFunc) http.HandlerFunc { return requireRole(models.RoleViewer, handler) }
+	operator := func(handler http.HandlerFunc) http.HandlerFunc { return requireRole(models.RoleOperator, handler) }
+	admin := func(handler http.HandlerFunc) http.HandlerFunc { return requireRole(models.RoleAdmin, handler) }
+
 	// Computer routes
-	api.HandleFunc("/computers", computerHandler.CreateComputer).Methods("POST")
-	api.HandleFunc("/computers", computerHandler.GetAllComputers).Methods("GET")
-	api.HandleFunc("/computers/{id}", computerHandler.GetComputerByID).Methods("GET")
-	api.HandleFunc("/computers/{id}", computerHandler.UpdateComputer).Methods("PUT")
-	api.HandleFunc("/computers/{id}", computerHandler.DeleteComputer).Methods("DELETE")
+	api.HandleFunc("/computers", operator(computerHandler.CreateComputer)).Methods("POST")
+	api.HandleFunc("/computers", viewer(computerHandler.GetAllComputers)).Methods("GET")
+	api.HandleFunc("/computers/import", operator(computerHandler.ImportComputers)).Methods("POST")
+	api.HandleFunc("/computers/search", viewer(computerHandler.SearchComputers)).Methods("GET")
+	api.HandleFunc("/computers/duplicate-ips", viewer(computerHandler.GetDuplicateIPAddresses)).Methods("GET")
+	api.HandleFunc("/computers/{id}", viewer(computerHandler.GetComputerByID)).Methods("GET")
+	api.HandleFunc("/computers/{id}", operator(computerHandler.UpdateComputer)).Methods("PUT")
+	api.HandleFunc("/computers/{id}", operator(computerHandler.PatchComputer)).Methods("PATCH")
+	api.HandleFunc("/computers/{id}", operator(computerHandler.DeleteComputer)).Methods("DELETE")
+	api.HandleFunc("/computers/{id}/history", viewer(auditHandler.GetComputerHistory)).Methods("GET")
+	api.HandleFunc("/computers/{id}/restore", operator(computerHandler.RestoreComputer)).Methods("POST")
 
 	// Employee routes
-	api.HandleFunc("/employees/{abbr}/computers", computerHandler.GetComputersByEmployee).Methods("GET")
+	api.HandleFunc("/employees", operator(employeeHandler.CreateEmployee)).Methods("POST")
+	api.HandleFunc("/employees", viewer(employeeHandler.GetAllEmployees)).Methods("GET")
+	api.HandleFunc("/employees/{abbr}", viewer(employeeHandler.GetEmployee)).Methods("GET")
+	api.HandleFunc("/employees/{abbr}", operator(employeeHandler.UpdateEmployee)).Methods("PUT")
+	api.HandleFunc("/employees/{abbr}", operator(employeeHandler.DeleteEmployee)).Methods("DELETE")
+	api.HandleFunc("/employees/{abbr}/computers", viewer(computerHandler.GetComputersByEmployee)).Methods("GET")
 
-	// Health check endpoint
-	api.HandleFunc("/health", healthCheckHandler).Methods("GET")
+	// Network routes. The network is in CIDR notation, its slash may be
+	// escaped or not.
+	api.HandleFunc("/networks/{cidr:.+}/computers", viewer(computerHandler.GetComputersInNetwork)).Methods("GET")
 
-	return router
-}
+	// Policy routes
+	api.HandleFunc("/policies", admin(policyHandler.CreatePolicy)).Methods("POST")
+	api.HandleFunc("/policies", viewer(policyHandler.GetAllPolicies)).Methods("GET")
+	api.HandleFunc("/policies/{id}", viewer(policyHandler.GetPolicy)).Methods("GET")
+	api.HandleFunc("/policies/{id}", admin(policyHandler.UpdatePolicy)).Methods("PUT")
+	api.HandleFunc("/policies/{id}", admin(policyHandler.DeletePolicy)).Methods("DELETE")
+
+	// Subnet routes
+	api.HandleFunc("/subnets", admin(subnetHandler.CreateSubnet)).Methods("POST")
+	api.HandleFunc("/subnets", viewer(subnetHandler.GetAllSubnets)).Methods("GET")
+	api.HandleFunc("/subnets/{id}", viewer(subnetHandler.GetSubnet)).Methods("GET")
+	api.HandleFunc("/subnets/{id}", admin(subnetHandler.UpdateSubnet)).Methods("PUT")
+	api.HandleFunc("/subnets/{id}", admin(subnetHandler.DeleteSubnet)).Methods("DELETE")
+	api.HandleFunc("/subnets/{id}/utilization", viewer(subnetHandler.GetSubnetUtilization)).Methods("GET")
+
+	// Audit routes
+	api.HandleFunc("/audit", admin(auditHandler.GetAuditLog)).Methods("GET")
 
-// healthCheckHandler handles health check requests
-func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"status": "healthy", "service": "computer-management-api"}`))
+	// Admin routes
+	api.HandleFunc("/admin/computers/purge", admin(computerHandler.PurgeComputers)).Methods("POST")
+	api.HandleFunc("/admin/outbox", admin(outboxHandler.GetMessages)).Methods("GET")
+	api.HandleFunc("/admin/outbox/{id}/replay", admin(outboxHandler.ReplayMessage)).Methods("POST")
+	api.HandleFunc("/admin/api-keys", admin(apiKeyHandler.CreateKey)).Methods("POST")
+	api.HandleFunc("/admin/api-keys", admin(apiKeyHandler.GetAllKeys)).Methods("GET")
+	api.HandleFunc("/admin/api-keys/{id}/rotate", admin(apiKeyHandler.RotateKey)).Methods("POST")
+	api.HandleFunc("/admin/api-keys/{id}", admin(apiKeyHandler.RevokeKey)).Methods("DELETE")
+
+	// Health check endpoints; /health is kept as an alias of readiness
+	api.HandleFunc("/health", healthHandler.Ready).Methods("GET")
+	api.HandleFunc("/health/live", healthHandler.Live).Methods("GET")
+	api.HandleFunc("/health/ready", healthHandler.Ready).Methods("GET")
+
+	// Prometheus metrics
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	return router
 }
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"greenbone-case-study/pkg/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// SubnetHandler handles HTTP requests for managed subnets
+type SubnetHandler struct {
+	service models.SubnetService
+}
+
+// NewSubnetHandler creates a new subnet handler
+func NewSubnetHandler(service models.SubnetService) *SubnetHandler {
+	return &SubnetHandler{
+		service: service,
+	}
+}
+
+// CreateSubnet handles POST /subnets
+func (h *SubnetHandler) CreateSubnet(w http.ResponseWriter, r *http.Request) {
+	var subnet models.Subnet
+
+	if err := json.NewDecoder(r.Body).Decode(&subnet); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	if err := h.service.CreateSubnet(r.Context(), &subnet); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusCreated, subnet)
+}
+
+// GetAllSubnets handles GET /subnets
+func (h *SubnetHandler) GetAllSubnets(w http.ResponseWriter, r *http.Request) {
+	subnets, err := h.service.GetAllSubnets(r.Context())
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	if subnets == nil {
+		subnets = []models.Subnet{}
+	}
+
+	writeJSONResponse(w, http.StatusOK, subnets)
+}
+
+// GetSubnet handles GET /subnets/{id}
+func (h *SubnetHandler) GetSubnet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid subnet ID")
+		return
+	}
+
+	subnet, err := h.service.GetSubnet(r.Context(), uint(id))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, subnet)
+}
+
+// UpdateSubnet handles PUT /subnets/{id}
+func (h *SubnetHandler) UpdateSubnet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid subnet ID")
+		return
+	}
+
+	var subnet models.Subnet
+	if err := json.NewDecoder(r.Body).Decode(&subnet); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	subnet.ID = uint(id)
+
+	if err := h.service.UpdateSubnet(r.Context(), &subnet); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, subnet)
+}
+
+// DeleteSubnet handles DELETE /subnets/{id}
+func (h *SubnetHandler) DeleteSubnet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid subnet ID")
+		return
+	}
+
+	if err := h.service.DeleteSubnet(r.Context(), uint(id)); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, map[string]string{
+		"message": "Subnet deleted successfully",
+	})
+}
+
+// GetSubnetUtilization handles GET /subnets/{id}/utilization
+func (h *SubnetHandler) GetSubnetUtilization(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid subnet ID")
+		return
+	}
+
+	utilization, err := h.service.GetSubnetUtilization(r.Context(), uint(id))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, utilization)
+}
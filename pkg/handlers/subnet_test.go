@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"greenbone-case-study/pkg/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// mockSubnetService keeps subnets in memory and reports their utilization as
+// if no computer used them
+type mockSubnetService struct {
+	subnets map[uint]*models.Subnet
+	nextID  uint
+}
+
+func newMockSubnetService() *mockSubnetService {
+	return &mockSubnetService{subnets: make(map[uint]*models.Subnet), nextID: 1}
+}
+
+func (m *mockSubnetService) CreateSubnet(ctx context.Context, subnet *models.Subnet) error {
+	if subnet.CIDR == "" {
+		return &services.ValidationError{Errors: []services.FieldError{{Field: "cidr", Message: "is required"}}}
+	}
+	for _, other := range m.subnets {
+		if other.CIDR == subnet.CIDR {
+			return fmt.Errorf("%w: %s overlaps %s", services.ErrSubnetOverlaps, subnet.CIDR, other.CIDR)
+		}
+	}
+	subnet.ID = m.nextID
+	m.nextID++
+	m.subnets[subnet.ID] = subnet
+	return nil
+}
+
+func (m *mockSubnetService) GetAllSubnets(ctx context.Context) ([]models.Subnet, error) {
+	var subnets []models.Subnet
+	for id := uint(1); id < m.nextID; id++ {
+		if subnet, exists := m.subnets[id]; exists {
+			subnets = append(subnets, *subnet)
+		}
+	}
+	return subnets, nil
+}
+
+func (m *mockSubnetService) GetSubnet(ctx context.Context, id uint) (*models.Subnet, error) {
+	if subnet, exists := m.subnets[id]; exists {
+		return subnet, nil
+	}
+	return nil, services.ErrSubnetNotFound
+}
+
+func (m *mockSubnetService) UpdateSubnet(ctx context.Context, subnet *models.Subnet) error {
+	if _, err := m.GetSubnet(ctx, subnet.ID); err != nil {
+		return err
+	}
+	m.subnets[subnet.ID] = subnet
+	return nil
+}
+
+func (m *mockSubnetService) DeleteSubnet(ctx context.Context, id uint) error {
+	if _, err := m.GetSubnet(ctx, id); err != nil {
+		return err
+	}
+	delete(m.subnets, id)
+	return nil
+}
+
+func (m *mockSubnetService) GetSubnetUtilization(ctx context.Context, id uint) (*models.SubnetUtilization, error) {
+	subnet, err := m.GetSubnet(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return subnet.Utilization(nil, 0), nil
+}
+
+func TestSubnetHandler(t *testing.T) {
+	handler := NewSubnetHandler(newMockSubnetService())
+
+	create := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/subnets", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.CreateSubnet(w, req)
+		return w
+	}
+
+	w := create(`{"cidr": "2001:db8::/64", "gateway": "2001:db8::1", "reserved_ranges": [{"start": "2001:db8::2", "end": "2001:db8::ff"}]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var subnet models.Subnet
+	json.Unmarshal(w.Body.Bytes(), &subnet)
+	if subnet.ID != 1 || len(subnet.ReservedRanges) != 1 || subnet.ReservedRanges[0].End != "2001:db8::ff" {
+		t.Errorf("Unexpected subnet %+v", subnet)
+	}
+
+	if w := create(`{"cidr": "2001:db8::/64"}`); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "/problems/subnet-overlaps") {
+		t.Errorf("Expected an overlap problem, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := create(`{}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+
+	// Address counts are exact JSON numbers, even beyond 2^64
+	req := httptest.NewRequest("GET", "/api/subnets/1/utilization", nil)
+	req = mux.SetURLVars(req, map[string]string{"id": "1"})
+	w = httptest.NewRecorder()
+	handler.GetSubnetUtilization(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"addresses":18446744073709551616`) {
+		t.Errorf("Unexpected utilization %d: %s", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		id     string
+		status int
+	}{
+		{"2", http.StatusNotFound},
+		{"abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/subnets/"+tt.id+"/utilization", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": tt.id})
+		w := httptest.NewRecorder()
+		handler.GetSubnetUtilization(w, req)
+		if w.Code != tt.status {
+			t.Errorf("Expected status %d for subnet %s, got %d", tt.status, tt.id, w.Code)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Package logging configures the log/slog logger of the service. Records
+// logged with a context carry the request ID, principal and trace of the
+// request they belong to.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"greenbone-case-study/pkg/services"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Formats
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Config selects the level and format of log records
+type Config struct {
+	// Level is debug, info, warn or error
+	Level string
+	// Format is FormatJSON or FormatText
+	Format string
+	// Writer receives log records, os.Stderr if nil
+	Writer io.Writer
+}
+
+// New creates a logger as configured
+func New(config Config) (*slog.Logger, error) {
+	var level slog.Level
+	if config.Level != "" {
+		if err := level.UnmarshalText([]byte(config.Level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q, expected debug, info, warn or error", config.Level)
+		}
+	}
+
+	writer := config.Writer
+	if writer == nil {
+		writer = os.Stderr
+	}
+	options := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch strings.ToLower(config.Format) {
+	case "", FormatJSON:
+		handler = slog.NewJSONHandler(writer, options)
+	case FormatText:
+		handler = slog.NewTextHandler(writer, options)
+	default:
+		return nil, fmt.Errorf("invalid log format %q, expected %s or %s", config.Format, FormatJSON, FormatText)
+	}
+	return slog.New(&contextHandler{Handler: handler}), nil
+}
+
+// Setup creates a logger as configured and makes it the default of log/slog
+// and of the log package
+func Setup(config Config) (*slog.Logger, error) {
+	logger, err := New(config)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+// contextHandler adds the request ID, principal and trace stored in the
+// context of a record to its attributes
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle adds the context attributes and passes the record on
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := services.RequestIDFromContext(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	if principal := services.PrincipalFromContext(ctx); principal != nil {
+		record.AddAttrs(slog.String("principal", principal.Subject), slog.String("role", principal.Role))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs keeps the context attributes on loggers with extra attributes
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup keeps the context attributes on loggers with a group
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
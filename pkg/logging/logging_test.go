@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"greenbone-case-study/pkg/models"
+	"greenbone-case-study/pkg/services"
+	"strings"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestLoggerAddsContextAttributes(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := New(Config{Level: "debug", Format: FormatJSON, Writer: &out})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	ctx := services.WithRequestID(context.Background(), "req-1")
+	ctx = services.WithPrincipal(ctx, &models.Principal{Subject: "alice", Role: models.RoleOperator})
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(ctx, "request")
+	defer span.End()
+
+	logger.With("component", "test").DebugContext(ctx, "Something happened", "computer_id", 7)
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON record, got %q", out.String())
+	}
+	want := map[string]interface{}{
+		"level":       "DEBUG",
+		"msg":         "Something happened",
+		"component":   "test",
+		"computer_id": float64(7),
+		"request_id":  "req-1",
+		"principal":   "alice",
+		"role":        models.RoleOperator,
+		"trace_id":    span.SpanContext().TraceID().String(),
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, record[key])
+		}
+	}
+
+	// Records without request context have no such attributes
+	out.Reset()
+	logger.Info("Plain")
+	if strings.Contains(out.String(), "request_id") || strings.Contains(out.String(), "trace_id") {
+		t.Errorf("Unexpected context attributes in %s", out.String())
+	}
+}
+
+func TestLoggerConfig(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := New(Config{Level: "warn", Format: FormatText, Writer: &out})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	logger.Info("Hidden")
+	logger.Warn("Shown")
+	if strings.Contains(out.String(), "Hidden") || !strings.Contains(out.String(), "level=WARN msg=Shown") {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+
+	for _, config := range []Config{{Level: "verbose"}, {Format: "xml"}} {
+		if _, err := New(config); err == nil {
+			t.Errorf("Expected error for %+v", config)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"greenbone-case-study/internal/db/callbacks"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// startKey stores the start time of a statement in its gorm instance
+const startKey = "metrics:start"
+
+// gormPlugin observes the duration of every statement in DBQueryDuration
+type gormPlugin struct{}
+
+// NewGormPlugin returns a gorm plugin that records statement durations.
+// Register it with db.Use.
+func NewGormPlugin() gorm.Plugin {
+	return gormPlugin{}
+}
+
+// Name identifies the plugin to gorm
+func (gormPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize registers callbacks around each of gorm's operations
+func (gormPlugin) Initialize(db *gorm.DB) error {
+	return callbacks.RegisterAround(db, "metrics", func(string) func(*gorm.DB) { return startTimer }, observeDuration)
+}
+
+func startTimer(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func observeDuration(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "other"
+		}
+		DBQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+	}
+}
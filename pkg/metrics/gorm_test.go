@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func TestGormPluginObservesStatements(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.Use(NewGormPlugin()); err != nil {
+		t.Fatalf("Failed to register plugin: %v", err)
+	}
+	if err := db.AutoMigrate(&widget{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+
+	if err := db.Create(&widget{Name: "a"}).Error; err != nil {
+		t.Fatalf("Failed to create widget: %v", err)
+	}
+	var widgets []widget
+	if err := db.Find(&widgets).Error; err != nil {
+		t.Fatalf("Failed to query widgets: %v", err)
+	}
+
+	for _, operation := range []string{"create", "query"} {
+		var metric dto.Metric
+		DBQueryDuration.WithLabelValues(operation, "widgets").(prometheus.Histogram).Write(&metric)
+		if metric.GetHistogram().GetSampleCount() == 0 {
+			t.Errorf("Expected %s statements on widgets to be observed", operation)
+		}
+	}
+}
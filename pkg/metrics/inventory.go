@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"context"
+	"greenbone-case-study/pkg/models"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	computersDesc = prometheus.NewDesc(namespace+"_computers",
+		"Computers that are not deleted.", nil, nil)
+	deletedComputersDesc = prometheus.NewDesc(namespace+"_computers_deleted",
+		"Soft-deleted computers that have not been purged.", nil, nil)
+	employeesDesc = prometheus.NewDesc(namespace+"_employees",
+		"Employees, active or not.", nil, nil)
+	employeesOverThresholdDesc = prometheus.NewDesc(namespace+"_employees_over_threshold",
+		"Employees whose computer count reaches a threshold of their assignment policy.", nil, nil)
+)
+
+// inventoryTimeout bounds the queries of a scrape, which carries no context
+const inventoryTimeout = 5 * time.Second
+
+// inventoryCollector reports inventory gauges, read from the service on every scrape
+type inventoryCollector struct {
+	service models.InventoryService
+}
+
+// NewInventoryCollector returns a collector of inventory gauges. Register it
+// with Registry.
+func NewInventoryCollector(service models.InventoryService) prometheus.Collector {
+	return &inventoryCollector{service: service}
+}
+
+// Describe sends the descriptors of the inventory gauges
+func (c *inventoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- computersDesc
+	ch <- deletedComputersDesc
+	ch <- employeesDesc
+	ch <- employeesOverThresholdDesc
+}
+
+// Collect reads the inventory statistics and sends them as gauges, or an
+// invalid metric if they cannot be read
+func (c *inventoryCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), inventoryTimeout)
+	defer cancel()
+
+	stats, err := c.service.GetInventoryStats(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(computersDesc, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(computersDesc, prometheus.GaugeValue, float64(stats.Computers))
+	ch <- prometheus.MustNewConstMetric(deletedComputersDesc, prometheus.GaugeValue, float64(stats.DeletedComputers))
+	ch <- prometheus.MustNewConstMetric(employeesDesc, prometheus.GaugeValue, float64(stats.Employees))
+	ch <- prometheus.MustNewConstMetric(employeesOverThresholdDesc, prometheus.GaugeValue, float64(stats.EmployeesOverThreshold))
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// ParseMACAddress parses a 48-bit MAC address in colon (00:11:22:aa:bb:cc),
+// dash (00-11-22-AA-BB-CC), dotted Cisco (0011.22aa.bbcc) or bare hex
+// (001122aabbcc) form and returns it in canonical lowercase colon form
+func ParseMACAddress(s string) (string, error) {
+	s = strings.TrimSpace(s)
+
+	var digits string
+	switch {
+	case len(s) == 17 && (s[2] == ':' || s[2] == '-'):
+		sep := s[2]
+		for i := 2; i < len(s); i += 3 {
+			if s[i] != sep {
+				return "", fmt.Errorf("invalid MAC address %q", s)
+			}
+		}
+		digits = strings.ReplaceAll(s, string(sep), "")
+	case len(s) == 14 && s[4] == '.' && s[9] == '.':
+		digits = strings.ReplaceAll(s, ".", "")
+	case len(s) == 12:
+		digits = s
+	default:
+		return "", fmt.Errorf("invalid MAC address %q", s)
+	}
+
+	b, err := hex.DecodeString(digits)
+	if err != nil || len(b) != 6 {
+		return "", fmt.Errorf("invalid MAC address %q", s)
+	}
+
+	parts := make([]string, len(b))
+	for i := range b {
+		parts[i] = hex.EncodeToString(b[i : i+1])
+	}
+	return strings.Join(parts, ":"), nil
+}
+
+// ParseIPAddress parses an IPv4 or IPv6 address and returns its canonical
+// text form. IPv4-mapped IPv6 addresses are stored as IPv4, zones are rejected.
+func ParseIPAddress(s string) (string, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(s))
+	if err != nil {
+		return "", fmt.Errorf("invalid IP address %q", s)
+	}
+	if addr.Zone() != "" {
+		return "", fmt.Errorf("invalid IP address %q: zones are not supported", s)
+	}
+	return addr.Unmap().String(), nil
+}
@@ -0,0 +1,56 @@
+package models
+
+import "testing"
+
+func TestParseMACAddress(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"00:11:22:aa:bb:cc", "00:11:22:aa:bb:cc", false},
+		{"00:11:22:AA:BB:CC", "00:11:22:aa:bb:cc", false},
+		{"00-11-22-aa-bb-cc", "00:11:22:aa:bb:cc", false},
+		{"0011.22aa.bbcc", "00:11:22:aa:bb:cc", false},
+		{"001122AABBCC", "00:11:22:aa:bb:cc", false},
+		{" 00:11:22:aa:bb:cc ", "00:11:22:aa:bb:cc", false},
+		{"00:11:22-aa:bb:cc", "", true},
+		{"00:11:22:aa:bb", "", true},
+		{"0011.22aa.bbc", "", true},
+		{"00112233445g", "", true},
+		{"00:11:22:aa:bb:cc:dd:ee", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMACAddress(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMACAddress(%q) = %q, %v; want %q, error %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseIPAddress(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"192.168.1.1", "192.168.1.1", false},
+		{"2001:0DB8:0000:0000:0000:0000:0000:0001", "2001:db8::1", false},
+		{"::ffff:192.168.1.1", "192.168.1.1", false},
+		{"::1", "::1", false},
+		{"192.168.1.256", "", true},
+		{"192.168.001.1", "", true},
+		{"fe80::1%eth0", "", true},
+		{"localhost", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseIPAddress(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseIPAddress(%q) = %q, %v; want %q, error %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
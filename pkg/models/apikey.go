@@ -0,0 +1,98 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// Roles, from least to most privileged. Each role includes the privileges of
+// the roles before it.
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// Authentication methods of a principal
+const (
+	AuthMethodAPIKey = "api_key"
+	AuthMethodJWT    = "jwt"
+)
+
+// roleRanks orders roles by privilege
+var roleRanks = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// ValidRole reports whether role is one of the known roles
+func ValidRole(role string) bool {
+	return roleRanks[role] > 0
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject string `json:"subject"`
+	Role    string `json:"role"`
+	Method  string `json:"method"`
+}
+
+// HasRole reports whether the principal has role or a more privileged one
+func (p *Principal) HasRole(role string) bool {
+	return p != nil && ValidRole(role) && roleRanks[p.Role] >= roleRanks[role]
+}
+
+// HighestRole returns the most privileged known role in roles, or "" if there
+// is none
+func HighestRole(roles []string) string {
+	highest := ""
+	for _, role := range roles {
+		if roleRanks[role] > roleRanks[highest] {
+			highest = role
+		}
+	}
+	return highest
+}
+
+// TokenAuthenticator verifies a bearer token and returns its principal
+type TokenAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+// APIKey grants its role to requests that present the key. Only a SHA-256
+// hash of the key is stored; the key itself is shown once when it is created
+// or rotated. Prefix holds its first characters so keys can be told apart.
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name       string     `json:"name" gorm:"not null;size:100;uniqueIndex"`
+	Role       string     `json:"role" gorm:"not null;size:20"`
+	Prefix     string     `json:"prefix" gorm:"not null;size:16"`
+	Hash       string     `json:"-" gorm:"not null;size:64;uniqueIndex"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyRepository interface for API key data operations
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *APIKey) error
+	GetAll(ctx context.Context) ([]APIKey, error)
+	GetByID(ctx context.Context, id uint) (*APIKey, error)
+	GetByName(ctx context.Context, name string) (*APIKey, error)
+	GetByHash(ctx context.Context, hash string) (*APIKey, error)
+	Update(ctx context.Context, key *APIKey) error
+	TouchLastUsed(ctx context.Context, id uint, at time.Time) error
+}
+
+// APIKeyService interface for API key management and authentication. Create
+// and rotate return the plaintext key, which cannot be retrieved later.
+type APIKeyService interface {
+	CreateKey(ctx context.Context, key *APIKey) (string, error)
+	GetAllKeys(ctx context.Context) ([]APIKey, error)
+	RotateKey(ctx context.Context, id uint) (*APIKey, string, error)
+	RevokeKey(ctx context.Context, id uint) (*APIKey, error)
+	EnsureKey(ctx context.Context, name, role, secret string) error
+	Authenticate(ctx context.Context, secret string) (*Principal, error)
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+// Create adds a new API key
+func (r *apiKeyRepository) Create(ctx context.Context, key *APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+// GetAll retrieves all API keys, including revoked ones
+func (r *apiKeyRepository) GetAll(ctx context.Context) ([]APIKey, error) {
+	var keys []APIKey
+	err := r.db.WithContext(ctx).Order("id").Find(&keys).Error
+	return keys, err
+}
+
+// GetByID retrieves an API key by ID
+func (r *apiKeyRepository) GetByID(ctx context.Context, id uint) (*APIKey, error) {
+	var key APIKey
+	if err := r.db.WithContext(ctx).First(&key, id).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetByName retrieves an API key by name
+func (r *apiKeyRepository) GetByName(ctx context.Context, name string) (*APIKey, error) {
+	var key APIKey
+	if err := r.db.WithContext(ctx).Where("name = ?", name).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetByHash retrieves an API key by the hash of its secret
+func (r *apiKeyRepository) GetByHash(ctx context.Context, hash string) (*APIKey, error) {
+	var key APIKey
+	if err := r.db.WithContext(ctx).Where("hash = ?", hash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Update saves the role, secret and rotation and revocation times of an API
+// key. The last use is only written by TouchLastUsed.
+func (r *apiKeyRepository) Update(ctx context.Context, key *APIKey) error {
+	return r.db.WithContext(ctx).Model(key).Select("role", "prefix", "hash", "rotated_at", "revoked_at").Updates(key).Error
+}
+
+// TouchLastUsed records when an API key was last used
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
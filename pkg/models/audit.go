@@ -0,0 +1,100 @@
+package models
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Audit actions
+const (
+	AuditCreate   = "create"
+	AuditUpdate   = "update"
+	AuditReassign = "reassign"
+	AuditDelete   = "delete"
+	AuditRestore  = "restore"
+	AuditPurge    = "purge"
+)
+
+// AuditEntityComputer is the entity type of computer audit entries
+const AuditEntityComputer = "computer"
+
+// FieldChange is the value of a field before and after a change
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// AuditChanges maps field names to their changes and is stored as JSON text
+type AuditChanges map[string]FieldChange
+
+// Value implements driver.Valuer
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (c *AuditChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = AuditChanges{}
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case []byte:
+		return json.Unmarshal(v, c)
+	}
+	return fmt.Errorf("cannot scan %T into AuditChanges", value)
+}
+
+// AuditEntry records one change of an entity. Entries are append-only.
+type AuditEntry struct {
+	ID         uint         `json:"id" gorm:"primaryKey;autoIncrement"`
+	EntityType string       `json:"entity_type" gorm:"not null;size:50;index:idx_audit_entity,priority:1"`
+	EntityID   uint         `json:"entity_id" gorm:"not null;index:idx_audit_entity,priority:2"`
+	Action     string       `json:"action" gorm:"not null;size:20"`
+	Actor      string       `json:"actor" gorm:"not null;size:255;index"`
+	RequestID  string       `json:"request_id,omitempty" gorm:"size:128"`
+	Changes    AuditChanges `json:"changes" gorm:"not null;type:text"`
+	CreatedAt  time.Time    `json:"created_at" gorm:"index"`
+}
+
+// AuditQueryOptions holds filters for listing audit entries
+type AuditQueryOptions struct {
+	EntityType    string
+	EntityID      uint
+	Actor         string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Limit         int
+	Offset        int
+}
+
+// AuditPage is one page of audit entries
+type AuditPage struct {
+	Items  []AuditEntry
+	Total  int64
+	Limit  int
+	Offset int
+}
+
+// AuditRepository interface for reading the audit log. Entries are written
+// by the repository of the audited entity, in the transaction of the change.
+type AuditRepository interface {
+	GetAll(ctx context.Context, opts AuditQueryOptions) ([]AuditEntry, int64, error)
+}
+
+// AuditService interface for audit log business logic
+type AuditService interface {
+	GetAuditLog(ctx context.Context, opts AuditQueryOptions) (*AuditPage, error)
+	GetComputerHistory(ctx context.Context, id uint, limit, offset int) (*AuditPage, error)
+}
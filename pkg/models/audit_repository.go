@@ -0,0 +1,53 @@
+package models
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// GetAll retrieves a page of audit entries matching the given filters, newest
+// first, and the total number of matching entries
+func (r *auditRepository) GetAll(ctx context.Context, opts AuditQueryOptions) ([]AuditEntry, int64, error) {
+	query := r.db.WithContext(ctx).Model(&AuditEntry{})
+	if opts.EntityType != "" {
+		query = query.Where("entity_type = ?", opts.EntityType)
+	}
+	if opts.EntityID != 0 {
+		query = query.Where("entity_id = ?", opts.EntityID)
+	}
+	if opts.Actor != "" {
+		query = query.Where("actor = ?", opts.Actor)
+	}
+	if opts.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *opts.CreatedAfter)
+	}
+	if opts.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *opts.CreatedBefore)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []AuditEntry
+	err := query.Order("id DESC").Limit(opts.Limit).Offset(opts.Offset).Find(&entries).Error
+	return entries, total, err
+}
+
+// createAuditEntries stores audit entries within a transaction
+func createAuditEntries(tx *gorm.DB, entries []*AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return tx.Create(entries).Error
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// Employee represents a person computers can be assigned to
+type Employee struct {
+	Abbreviation string    `json:"abbreviation" gorm:"primaryKey;size:3"`
+	Name         string    `json:"name" gorm:"not null;size:100"`
+	Email        string    `json:"email" gorm:"size:254"`
+	Department   string    `json:"department" gorm:"size:100"`
+	Active       bool      `json:"active" gorm:"not null;default:true"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// EmployeeQueryOptions holds filters for listing employees
+type EmployeeQueryOptions struct {
+	Active     *bool
+	Department string
+	// Abbreviations limits the result to these employees if not nil
+	Abbreviations []string
+}
+
+// EmployeeRepository interface for employee database operations
+type EmployeeRepository interface {
+	Create(ctx context.Context, employee *Employee) error
+	GetAll(ctx context.Context, opts EmployeeQueryOptions) ([]Employee, error)
+	GetByAbbreviation(ctx context.Context, abbr string) (*Employee, error)
+	Update(ctx context.Context, employee *Employee) error
+	Delete(ctx context.Context, abbr string) error
+	Count(ctx context.Context) (int64, error)
+}
+
+// EmployeeService interface for employee business logic
+type EmployeeService interface {
+	CreateEmployee(ctx context.Context, employee *Employee) error
+	GetAllEmployees(ctx context.Context, opts EmployeeQueryOptions) ([]Employee, error)
+	GetEmployee(ctx context.Context, abbr string) (*Employee, error)
+	UpdateEmployee(ctx context.Context, employee *Employee) error
+	DeleteEmployee(ctx context.Context, abbr string) error
+}
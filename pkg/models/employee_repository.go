@@ -0,0 +1,74 @@
+package models
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+type employeeRepository struct {
+	db *gorm.DB
+}
+
+// NewEmployeeRepository creates a new employee repository
+func NewEmployeeRepository(db *gorm.DB) EmployeeRepository {
+	return &employeeRepository{db: db}
+}
+
+// Create adds a new employee to the database
+func (r *employeeRepository) Create(ctx context.Context, employee *Employee) error {
+	return r.db.WithContext(ctx).Create(employee).Error
+}
+
+// GetAll retrieves employees matching the given filters
+func (r *employeeRepository) GetAll(ctx context.Context, opts EmployeeQueryOptions) ([]Employee, error) {
+	query := r.db.WithContext(ctx).Order("abbreviation")
+	if opts.Active != nil {
+		query = query.Where("active = ?", *opts.Active)
+	}
+	if opts.Department != "" {
+		query = query.Where("department = ?", opts.Department)
+	}
+	if opts.Abbreviations != nil {
+		query = query.Where("abbreviation IN ?", opts.Abbreviations)
+	}
+
+	var employees []Employee
+	err := query.Find(&employees).Error
+	return employees, err
+}
+
+// Count counts all employees
+func (r *employeeRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&Employee{}).Count(&count).Error
+	return count, err
+}
+
+// GetByAbbreviation retrieves an employee by abbreviation
+func (r *employeeRepository) GetByAbbreviation(ctx context.Context, abbr string) (*Employee, error) {
+	var employee Employee
+	err := r.db.WithContext(ctx).Where("abbreviation = ?", abbr).First(&employee).Error
+	if err != nil {
+		return nil, err
+	}
+	return &employee, nil
+}
+
+// Update updates all fields of an employee
+func (r *employeeRepository) Update(ctx context.Context, employee *Employee) error {
+	return r.db.WithContext(ctx).Model(employee).Select("*").Omit("created_at").Updates(employee).Error
+}
+
+// Delete removes an employee by abbreviation
+func (r *employeeRepository) Delete(ctx context.Context, abbr string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Deleted computers keep no reference to the employee, their audit history does
+		err := tx.Unscoped().Model(&Computer{}).
+			Where("employee_abbreviation = ? AND deleted_at IS NOT NULL", abbr).
+			Update("employee_abbreviation", nil).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("abbreviation = ?", abbr).Delete(&Employee{}).Error
+	})
+}
@@ -0,0 +1,5 @@
+package models
+
+// ScoreName is the search score of an exact name match, for the repository
+// tests in package models_test
+const ScoreName = scoreName
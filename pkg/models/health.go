@@ -0,0 +1,41 @@
+package models
+
+import "context"
+
+// Health statuses of components and of the service as a whole
+const (
+	HealthUp       = "up"
+	HealthDown     = "down"
+	HealthDegraded = "degraded"
+)
+
+// HealthCheck checks one dependency of the service
+type HealthCheck struct {
+	Name string
+	// Required dependencies make the service unready when they are down;
+	// others only degrade it
+	Required bool
+	Check    func(ctx context.Context) error
+}
+
+// ComponentHealth is the result of one HealthCheck. Error is logged, not
+// reported, as readiness probes are public.
+type ComponentHealth struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Required  bool    `json:"required"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"-"`
+}
+
+// HealthReport is the readiness of the service and its components. Status is
+// down if a required component is down and degraded if another one is.
+type HealthReport struct {
+	Status     string            `json:"status"`
+	Components []ComponentHealth `json:"components"`
+}
+
+// HealthService interface for readiness checks
+type HealthService interface {
+	CheckReadiness(ctx context.Context) *HealthReport
+}
@@ -0,0 +1,66 @@
+package models
+
+// Import modes
+const (
+	// ImportAtomic applies every row of an import or, if any row fails, none
+	ImportAtomic = "atomic"
+	// ImportBestEffort applies every valid row and reports the others
+	ImportBestEffort = "best_effort"
+)
+
+// Outcomes of an import row
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+	ImportFailed    = "failed"
+)
+
+// MaxImportRows bounds the number of computers in one import
+const MaxImportRows = 10000
+
+// ImportOptions control how computers are imported. A dry run validates and
+// checks every row like a real import but does not change anything.
+type ImportOptions struct {
+	Mode   string
+	DryRun bool
+}
+
+// ImportRowError describes why a row failed, for a single field or, if Field
+// is empty, for the row as a whole
+type ImportRowError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportRowResult is the outcome of one row of an import. Rows are numbered
+// from 1 in the order they were submitted.
+type ImportRowResult struct {
+	Row        int              `json:"row"`
+	MACAddress string           `json:"mac_address,omitempty"`
+	Action     string           `json:"action"`
+	ComputerID uint             `json:"computer_id,omitempty"`
+	Errors     []ImportRowError `json:"errors,omitempty"`
+}
+
+// ImportNotification is a computer limit notification queued, or for a dry
+// run one that would be queued, for an employee after an import
+type ImportNotification struct {
+	EmployeeAbbreviation string `json:"employee_abbreviation"`
+	Computers            int    `json:"computers"`
+	Level                string `json:"level"`
+}
+
+// ImportResult reports an import row by row. Applied is false for dry runs
+// and for atomic imports that were rolled back because a row failed.
+type ImportResult struct {
+	Mode          string               `json:"mode"`
+	DryRun        bool                 `json:"dry_run"`
+	Applied       bool                 `json:"applied"`
+	Created       int                  `json:"created"`
+	Updated       int                  `json:"updated"`
+	Unchanged     int                  `json:"unchanged"`
+	Failed        int                  `json:"failed"`
+	Rows          []ImportRowResult    `json:"rows"`
+	Notifications []ImportNotification `json:"notifications"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "context"
+
+// InventoryStats summarises the current inventory
+type InventoryStats struct {
+	Computers              int64
+	DeletedComputers       int64
+	Employees              int64
+	EmployeesOverThreshold int64
+}
+
+// InventoryService interface for inventory statistics
+type InventoryService interface {
+	GetInventoryStats(ctx context.Context) (*InventoryStats, error)
+}
@@ -1,38 +1,130 @@
 package models
 
 import (
+	"context"
+	"errors"
+	"net/netip"
 	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrVersionConflict is returned when a conditional write finds a different version
+	ErrVersionConflict = errors.New("computer was modified concurrently")
+	// ErrDuplicateMACAddress is returned when a write would give two computers that
+	// are not deleted the same MAC address
+	ErrDuplicateMACAddress = errors.New("duplicate MAC address")
 )
 
-// Computer represents a company-issued computer
+// Computer represents a company-issued computer. Deleted computers are kept
+// with DeletedAt set until they are purged; MAC addresses are only unique
+// among computers that are not deleted.
 type Computer struct {
-	ID                   uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	MACAddress           string    `json:"mac_address" gorm:"not null;unique;size:17" validate:"required"`
-	ComputerName         string    `json:"computer_name" gorm:"not null;size:100" validate:"required"`
-	IPAddress            string    `json:"ip_address" gorm:"not null;size:15" validate:"required"`
-	EmployeeAbbreviation *string   `json:"employee_abbreviation,omitempty" gorm:"size:3"`
-	Description          string    `json:"description" gorm:"size:500"`
-	CreatedAt            time.Time `json:"created_at"`
-	UpdatedAt            time.Time `json:"updated_at"`
+	ID           uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	MACAddress   string `json:"mac_address" gorm:"not null;size:17;uniqueIndex:idx_computers_mac_address_active,where:deleted_at IS NULL" validate:"required"`
+	ComputerName string `json:"computer_name" gorm:"not null;size:100" validate:"required"`
+	IPAddress    string `json:"ip_address" gorm:"not null;size:45" validate:"required"`
+	// IPNumber is IPAddress as a number, see IPNumber. It is set on every
+	// write, for network queries and to sort addresses numerically.
+	IPNumber string `json:"-" gorm:"not null;size:33;default:'';index:idx_computers_ip_number"`
+	// SubnetID is only read from writes and not stored: the subnet whose next
+	// free address replaces an IPAddress of "auto", or that IPAddress must lie
+	// within
+	SubnetID             *uint          `json:"subnet_id,omitempty" gorm:"-"`
+	EmployeeAbbreviation *string        `json:"employee_abbreviation,omitempty" gorm:"size:3"`
+	Description          string         `json:"description" gorm:"size:500"`
+	Version              uint           `json:"version" gorm:"not null;default:1"`
+	Employee             *Employee      `json:"-" gorm:"foreignKey:EmployeeAbbreviation;references:Abbreviation;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
+	DeletedAt            gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
 
-// ComputerRepository interface for database operations
+// PatchType identifies the format of a PATCH request body
+type PatchType string
+
+// Supported patch formats
+const (
+	MergePatch PatchType = "application/merge-patch+json"
+	JSONPatch  PatchType = "application/json-patch+json"
+)
+
+// ComputerRepository interface for database operations.
+// Update only succeeds if the stored version equals computer.Version, Delete
+// only checks the version when it is non-zero. A mismatch returns ErrVersionConflict.
+// Outbox messages passed to Create and Update are stored in the same transaction.
 type ComputerRepository interface {
-	Create(computer *Computer) error
-	GetAll() ([]Computer, error)
-	GetByID(id uint) (*Computer, error)
-	GetByEmployeeAbbreviation(abbr string) ([]Computer, error)
-	Update(computer *Computer) error
-	Delete(id uint) error
-	CountByEmployee(abbr string) (int64, error)
+	Create(ctx context.Context, computer *Computer, messages ...*OutboxMessage) error
+	GetAll(ctx context.Context, opts ComputerQueryOptions) (*ComputerPage, error)
+	// Stream calls fn for every computer matching the filters of opts, in the
+	// order of opts.Sort, reading them row by row instead of all at once.
+	// Pagination options are ignored; an error from fn stops the stream.
+	Stream(ctx context.Context, opts ComputerQueryOptions, fn func(computer *Computer) error) error
+	// Search returns the computers matching every word of the query, ranked
+	// by relevance
+	Search(ctx context.Context, opts ComputerSearchOptions) ([]ComputerSearchHit, error)
+	GetByID(ctx context.Context, id uint) (*Computer, error)
+	GetByEmployeeAbbreviation(ctx context.Context, abbr string, includeDeleted bool) ([]Computer, error)
+	// FindDuplicateIPAddresses returns the IP addresses held by several
+	// computers, only those within network if it is valid
+	FindDuplicateIPAddresses(ctx context.Context, network netip.Prefix) ([]DuplicateIPAddress, error)
+	GetByMACAddress(ctx context.Context, mac string) (*Computer, error)
+	GetDeletedByID(ctx context.Context, id uint) (*Computer, error)
+	Update(ctx context.Context, computer *Computer, messages ...*OutboxMessage) error
+	Delete(ctx context.Context, id uint, version uint) error
+	Restore(ctx context.Context, computer *Computer, messages ...*OutboxMessage) error
+	Purge(ctx context.Context, deletedBefore time.Time) ([]Computer, error)
+	// Count counts the computers that are not deleted and those that are
+	Count(ctx context.Context) (active int64, deleted int64, err error)
+	CountByEmployee(ctx context.Context, abbr string) (int64, error)
+	// CountByEmployees counts the computers of every employee that has any
+	CountByEmployees(ctx context.Context) (map[string]int64, error)
+
+	// Transaction runs fn with a repository bound to a single database
+	// transaction, committing if fn returns nil and rolling back otherwise
+	Transaction(ctx context.Context, fn func(tx ComputerRepository) error) error
+	// LockByID retrieves a computer and locks its row until the transaction ends
+	LockByID(ctx context.Context, id uint) (*Computer, error)
+	// LockEmployee retrieves an employee and locks its row until the transaction
+	// ends, serialising concurrent assignments to the same employee
+	LockEmployee(ctx context.Context, abbr string) (*Employee, error)
+	// LockSubnet retrieves a subnet and locks its row until the transaction
+	// ends, serialising concurrent allocations of its addresses
+	LockSubnet(ctx context.Context, id uint) (*Subnet, error)
+	// GetSubnetByIPAddress retrieves the subnet an address lies within
+	GetSubnetByIPAddress(ctx context.Context, ip string) (*Subnet, error)
+	// GetIPAddressesInNetwork returns the distinct addresses within network
+	// held by computers, in address order, and the number of those computers
+	GetIPAddressesInNetwork(ctx context.Context, network netip.Prefix) ([]netip.Addr, int64, error)
+	// RecordAudit appends audit entries, within the transaction when called on one
+	RecordAudit(ctx context.Context, entries ...*AuditEntry) error
+	// QueueMessages stores outbox messages that belong to no single computer
+	// write, within the transaction when called on one
+	QueueMessages(ctx context.Context, messages ...*OutboxMessage) error
 }
 
-// ComputerService interface for business logic
+// ComputerService interface for business logic. Methods take the request
+// context, which carries the trace and, for writes, the actor and request ID
+// for the audit log.
 type ComputerService interface {
-	CreateComputer(computer *Computer) error
-	GetAllComputers() ([]Computer, error)
-	GetComputerByID(id uint) (*Computer, error)
-	GetComputersByEmployee(abbr string) ([]Computer, error)
-	UpdateComputer(computer *Computer) error
-	DeleteComputer(id uint) error
+	CreateComputer(ctx context.Context, computer *Computer) error
+	GetAllComputers(ctx context.Context, opts ComputerQueryOptions) (*ComputerPage, error)
+	// ExportComputers streams every computer matching opts to fn, without
+	// pagination. An employee filter must name an existing employee.
+	ExportComputers(ctx context.Context, opts ComputerQueryOptions, fn func(computer *Computer) error) error
+	SearchComputers(ctx context.Context, opts ComputerSearchOptions) ([]ComputerSearchHit, error)
+	GetComputerByID(ctx context.Context, id uint) (*Computer, error)
+	GetComputersByEmployee(ctx context.Context, abbr string, includeDeleted bool) ([]Computer, error)
+	// FindDuplicateIPAddresses reports the IP addresses held by several
+	// computers, optionally only within a network
+	FindDuplicateIPAddresses(ctx context.Context, network netip.Prefix) ([]DuplicateIPAddress, error)
+	UpdateComputer(ctx context.Context, computer *Computer) error
+	PatchComputer(ctx context.Context, id uint, version uint, patchType PatchType, patch []byte) (*Computer, error)
+	DeleteComputer(ctx context.Context, id uint, version uint) error
+	RestoreComputer(ctx context.Context, id uint) (*Computer, error)
+	PurgeComputers(ctx context.Context, olderThan *time.Duration) (int, error)
+	// ImportComputers creates or, matched by MAC address, updates computers in
+	// bulk and reports the outcome of every row
+	ImportComputers(ctx context.Context, computers []Computer, opts ImportOptions) (*ImportResult, error)
 }
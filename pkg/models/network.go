@@ -0,0 +1,104 @@
+package models
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DuplicateIPAddress is an IP address held by more than one computer
+type DuplicateIPAddress struct {
+	IPAddress string     `json:"ip_address"`
+	Computers []Computer `json:"computers"`
+}
+
+// ParseIPNetwork parses a network in CIDR notation such as 10.20.0.0/16 and
+// returns it masked, so 10.20.1.5/16 is read as 10.20.0.0/16. IPv4-mapped
+// IPv6 networks are read as IPv4, like addresses are stored.
+func ParseIPNetwork(s string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(strings.TrimSpace(s))
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid network %q, expected CIDR notation such as 10.20.0.0/16", s)
+	}
+	if addr := prefix.Addr(); addr.Is4In6() && prefix.Bits() >= 96 {
+		prefix = netip.PrefixFrom(addr.Unmap(), prefix.Bits()-96)
+	}
+	return prefix.Masked(), nil
+}
+
+// IPNumber returns an IP address as a number: its family, 4 or 6, followed
+// by the address in hex. Numbers compare like the addresses, IPv4 before
+// IPv6 as in PostgreSQL, so a network is a range of them. It returns an empty
+// string for an invalid address or one with a zone, which PostgreSQL cannot
+// read as inet either.
+func IPNumber(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || addr.Zone() != "" {
+		return ""
+	}
+	return ipNumber(addr)
+}
+
+func ipNumber(addr netip.Addr) string {
+	if addr.Is4() {
+		b := addr.As4()
+		return "4" + hex.EncodeToString(b[:])
+	}
+	b := addr.As16()
+	return "6" + hex.EncodeToString(b[:])
+}
+
+// ipNumberRange returns the first and last number of a network
+func ipNumberRange(network netip.Prefix) (first, last string) {
+	from, to := prefixRange(network)
+	return ipNumber(from), ipNumber(to)
+}
+
+// ipNetworkCondition selects the computers with an address in network. On
+// PostgreSQL addresses are compared as inet, using the GiST index of
+// migration 6, which only casts addresses with an IP number: computers stored
+// before addresses were validated may hold unparsable ones. Other databases
+// compare the range of IP numbers, using their index.
+func ipNetworkCondition(db *gorm.DB, network netip.Prefix) clause.Expr {
+	if db.Dialector.Name() == "postgres" {
+		return gorm.Expr("(CASE WHEN ip_number <> '' THEN ip_address::inet END) <<= ?::inet", network.String())
+	}
+	first, last := ipNumberRange(network)
+	return gorm.Expr("ip_number BETWEEN ? AND ?", first, last)
+}
+
+// FindDuplicateIPAddresses returns the IP addresses, optionally within a
+// network, held by more than one computer that is not deleted, in address order
+func (r *computerRepository) FindDuplicateIPAddresses(ctx context.Context, network netip.Prefix) ([]DuplicateIPAddress, error) {
+	duplicates := r.db.WithContext(ctx).Model(&Computer{}).
+		Select("ip_address").
+		Group("ip_address").
+		Having("COUNT(*) > 1")
+	if network.IsValid() {
+		duplicates = duplicates.Where(ipNetworkCondition(r.db, network))
+	}
+
+	var computers []Computer
+	err := r.db.WithContext(ctx).
+		Where("ip_address IN (?)", duplicates).
+		Order("ip_number, id").
+		Find(&computers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := []DuplicateIPAddress{}
+	for _, computer := range computers {
+		if n := len(result); n == 0 || result[n-1].IPAddress != computer.IPAddress {
+			result = append(result, DuplicateIPAddress{IPAddress: computer.IPAddress})
+		}
+		last := &result[len(result)-1]
+		last.Computers = append(last.Computers, computer)
+	}
+	return result, nil
+}
@@ -0,0 +1,168 @@
+package models_test
+
+import (
+	"context"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"net/netip"
+	"testing"
+)
+
+func TestParseIPNetwork(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"10.20.0.0/16", "10.20.0.0/16", false},
+		{" 10.20.1.5/16 ", "10.20.0.0/16", false},
+		{"2001:DB8::/32", "2001:db8::/32", false},
+		{"::ffff:10.20.0.0/112", "10.20.0.0/16", false},
+		{"10.20.0.0", "", true},
+		{"10.20.0.0/33", "", true},
+		{"fe80::/10%eth0", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := models.ParseIPNetwork(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseIPNetwork(%q): expected error, got %s", tt.input, got)
+			}
+			continue
+		}
+		if err != nil || got.String() != tt.want {
+			t.Errorf("ParseIPNetwork(%q) = %s, %v, want %s", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestIPNumber(t *testing.T) {
+	if got := models.IPNumber("10.20.1.5"); got != "40a140105" {
+		t.Errorf("Unexpected IPv4 number %s", got)
+	}
+	if got := models.IPNumber("2001:db8::1"); got != "620010db8000000000000000000000001" {
+		t.Errorf("Unexpected IPv6 number %s", got)
+	}
+	if models.IPNumber("10.0.0.9") >= models.IPNumber("10.0.0.10") || models.IPNumber("255.255.255.255") >= models.IPNumber("::") {
+		t.Error("Expected IP numbers to compare like addresses")
+	}
+	if got := models.IPNumber("not an address"); got != "" {
+		t.Errorf("Expected no number for an invalid address, got %s", got)
+	}
+	if got := models.IPNumber("fe80::1%eth0"); got != "" {
+		t.Errorf("Expected no number for an address with a zone, got %s", got)
+	}
+}
+
+func seedNetworkComputers(t *testing.T, repo models.ComputerRepository, addresses ...string) {
+	t.Helper()
+
+	for i, ip := range addresses {
+		computer := &models.Computer{
+			MACAddress:   fmt.Sprintf("00:11:22:33:44:%02d", i+1),
+			ComputerName: fmt.Sprintf("Computer %02d", i+1),
+			IPAddress:    ip,
+		}
+		if err := repo.Create(context.Background(), computer); err != nil {
+			t.Fatalf("Failed to create computer: %v", err)
+		}
+	}
+}
+
+func TestGetAllInNetwork(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+	seedNetworkComputers(t, repo, "10.20.0.10", "10.19.255.255", "10.20.255.255", "10.21.0.0", "10.20.0.9", "2001:db8::1")
+
+	tests := []struct {
+		network string
+		want    []string
+	}{
+		{"10.20.0.0/16", []string{"10.20.0.9", "10.20.0.10", "10.20.255.255"}},
+		{"10.20.0.8/31", []string{"10.20.0.9"}},
+		{"0.0.0.0/0", []string{"10.19.255.255", "10.20.0.9", "10.20.0.10", "10.20.255.255", "10.21.0.0"}},
+		{"2001:db8::/32", []string{"2001:db8::1"}},
+		{"::/0", []string{"2001:db8::1"}},
+		{"192.168.0.0/16", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			opts := models.ComputerQueryOptions{
+				Limit:     10,
+				IPNetwork: netip.MustParsePrefix(tt.network),
+				Sort:      []models.SortField{{Field: "ip_address"}},
+			}
+			page, err := repo.GetAll(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			var got []string
+			for _, computer := range page.Items {
+				got = append(got, computer.IPAddress)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) || page.Total != int64(len(tt.want)) {
+				t.Errorf("Expected %v in address order, got %v (total %d)", tt.want, got, page.Total)
+			}
+		})
+	}
+}
+
+func TestGetAllSortsAddressesNumerically(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+	seedNetworkComputers(t, repo, "10.0.0.10", "10.0.0.9", "10.0.0.100", "10.0.0.1")
+
+	// Cursors continue in address order
+	opts := models.ComputerQueryOptions{Limit: 2, Sort: []models.SortField{{Field: "ip_address"}}}
+	var got []string
+	for {
+		page, err := repo.GetAll(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		for _, computer := range page.Items {
+			got = append(got, computer.IPAddress)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+
+	if fmt.Sprint(got) != "[10.0.0.1 10.0.0.9 10.0.0.10 10.0.0.100]" {
+		t.Errorf("Expected addresses in numeric order, got %v", got)
+	}
+}
+
+func TestFindDuplicateIPAddresses(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+	seedNetworkComputers(t, repo, "10.0.0.5", "10.0.0.5", "192.168.1.1", "10.0.0.1", "192.168.1.1", "10.0.0.5", "10.0.0.2")
+	ctx := context.Background()
+
+	duplicates, err := repo.FindDuplicateIPAddresses(ctx, netip.Prefix{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(duplicates) != 2 {
+		t.Fatalf("Expected 2 duplicate addresses, got %+v", duplicates)
+	}
+	if duplicates[0].IPAddress != "10.0.0.5" || len(duplicates[0].Computers) != 3 || duplicates[0].Computers[0].ID != 1 {
+		t.Errorf("Unexpected first duplicate %+v", duplicates[0])
+	}
+	if duplicates[1].IPAddress != "192.168.1.1" || len(duplicates[1].Computers) != 2 {
+		t.Errorf("Unexpected second duplicate %+v", duplicates[1])
+	}
+
+	duplicates, _ = repo.FindDuplicateIPAddresses(ctx, netip.MustParsePrefix("192.168.0.0/16"))
+	if len(duplicates) != 1 || duplicates[0].IPAddress != "192.168.1.1" {
+		t.Errorf("Expected only the duplicate within the network, got %+v", duplicates)
+	}
+
+	// Deleted computers do not hold their address
+	repo.Delete(ctx, 3, 0)
+	duplicates, _ = repo.FindDuplicateIPAddresses(ctx, netip.Prefix{})
+	if len(duplicates) != 1 || duplicates[0].IPAddress != "10.0.0.5" {
+		t.Errorf("Expected deleted computers to be ignored, got %+v", duplicates)
+	}
+}
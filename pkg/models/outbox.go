@@ -0,0 +1,58 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// Outbox message states
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxFailed    = "failed"
+)
+
+// OutboxMessage is a notification persisted together with the change that
+// caused it and delivered asynchronously by the outbox dispatcher. RequestID
+// and TraceParent identify the request that queued it, if any, so the delivery
+// can be logged and traced as part of it. LeasedUntil is set while a
+// dispatcher delivers the message.
+type OutboxMessage struct {
+	ID            uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Kind          string     `json:"kind" gorm:"not null;size:50"`
+	Payload       string     `json:"payload" gorm:"not null;type:text"`
+	Status        string     `json:"status" gorm:"not null;size:20;index:idx_outbox_due,priority:1"`
+	Attempts      int        `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" gorm:"not null;index:idx_outbox_due,priority:2"`
+	LastError     string     `json:"last_error,omitempty" gorm:"size:1000"`
+	RequestID     string     `json:"request_id,omitempty" gorm:"size:128"`
+	TraceParent   string     `json:"-" gorm:"size:55"`
+	LeasedUntil   *time.Time `json:"leased_until,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// OutboxQueryOptions holds filters for listing outbox messages
+type OutboxQueryOptions struct {
+	Status        string
+	CreatedBefore *time.Time
+	Limit         int
+}
+
+// OutboxRepository interface for outbox database operations
+type OutboxRepository interface {
+	GetByID(ctx context.Context, id uint) (*OutboxMessage, error)
+	GetAll(ctx context.Context, opts OutboxQueryOptions) ([]OutboxMessage, error)
+	GetDue(ctx context.Context, now time.Time, limit int) ([]OutboxMessage, error)
+	Claim(ctx context.Context, message *OutboxMessage, leaseUntil time.Time) (bool, error)
+	Replay(ctx context.Context, message *OutboxMessage, now time.Time) (bool, error)
+	Update(ctx context.Context, message *OutboxMessage) error
+}
+
+// OutboxService interface for inspecting and replaying outbox messages
+type OutboxService interface {
+	GetMessages(ctx context.Context, opts OutboxQueryOptions) ([]OutboxMessage, error)
+	GetStuckMessages(ctx context.Context, limit int) ([]OutboxMessage, error)
+	ReplayMessage(ctx context.Context, id uint) (*OutboxMessage, error)
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// GetByID retrieves an outbox message by ID
+func (r *outboxRepository) GetByID(ctx context.Context, id uint) (*OutboxMessage, error) {
+	var message OutboxMessage
+	err := r.db.WithContext(ctx).First(&message, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// GetAll retrieves outbox messages matching the given filters, oldest first
+func (r *outboxRepository) GetAll(ctx context.Context, opts OutboxQueryOptions) ([]OutboxMessage, error) {
+	query := r.db.WithContext(ctx).Order("id")
+	if opts.Status != "" {
+		query = query.Where("status = ?", opts.Status)
+	}
+	if opts.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *opts.CreatedBefore)
+	}
+	if opts.Limit > 0 {
+		query = query.Limit(opts.Limit)
+	}
+
+	var messages []OutboxMessage
+	err := query.Find(&messages).Error
+	return messages, err
+}
+
+// GetDue retrieves pending messages whose next attempt is due
+func (r *outboxRepository) GetDue(ctx context.Context, now time.Time, limit int) ([]OutboxMessage, error) {
+	var messages []OutboxMessage
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", OutboxPending, now).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+// Claim leases a due message for one delivery attempt by incrementing its
+// attempt counter and moving its lease and next attempt to leaseUntil. It
+// returns false if another dispatcher claimed the message first.
+func (r *outboxRepository) Claim(ctx context.Context, message *OutboxMessage, leaseUntil time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&OutboxMessage{}).
+		Where("id = ? AND status = ? AND attempts = ?", message.ID, OutboxPending, message.Attempts).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"next_attempt_at": leaseUntil,
+			"leased_until":    leaseUntil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	message.Attempts++
+	message.NextAttemptAt = leaseUntil
+	message.LeasedUntil = &leaseUntil
+	return true, nil
+}
+
+// Replay makes an undelivered message due at now with a fresh attempt budget.
+// It returns false if the message was delivered, claimed or replayed since it
+// was read, or if its lease has not expired by now.
+func (r *outboxRepository) Replay(ctx context.Context, message *OutboxMessage, now time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&OutboxMessage{}).
+		Where("id = ? AND status <> ? AND attempts = ?", message.ID, OutboxDelivered, message.Attempts).
+		Where("leased_until IS NULL OR leased_until <= ?", now).
+		Updates(map[string]interface{}{
+			"status":          OutboxPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+			"leased_until":    nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	message.Status = OutboxPending
+	message.Attempts = 0
+	message.NextAttemptAt = now
+	message.LeasedUntil = nil
+	return true, nil
+}
+
+// Update saves the delivery state of a message
+func (r *outboxRepository) Update(ctx context.Context, message *OutboxMessage) error {
+	return r.db.WithContext(ctx).Save(message).Error
+}
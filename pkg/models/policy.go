@@ -0,0 +1,61 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// Policy scopes, from least to most specific
+const (
+	PolicyScopeGlobal     = "global"
+	PolicyScopeDepartment = "department"
+	PolicyScopeEmployee   = "employee"
+)
+
+// Threshold actions
+const (
+	ThresholdWarn  = "warn"
+	ThresholdBlock = "block"
+)
+
+// AssignmentPolicy defines computer count thresholds for a scope. The most
+// specific policy applies: employee, then department, then global.
+type AssignmentPolicy struct {
+	ID         uint              `json:"id" gorm:"primaryKey;autoIncrement"`
+	Scope      string            `json:"scope" gorm:"not null;size:20;uniqueIndex:idx_policy_scope"`
+	Subject    string            `json:"subject" gorm:"not null;size:100;uniqueIndex:idx_policy_scope"`
+	Thresholds []PolicyThreshold `json:"thresholds" gorm:"foreignKey:PolicyID;constraint:OnDelete:CASCADE"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// PolicyThreshold triggers its action once an employee reaches Count computers.
+// Warn thresholds queue a notification with Level, block thresholds reject the assignment.
+type PolicyThreshold struct {
+	ID       uint   `json:"-" gorm:"primaryKey;autoIncrement"`
+	PolicyID uint   `json:"-" gorm:"not null;index"`
+	Count    int    `json:"count" gorm:"not null"`
+	Action   string `json:"action" gorm:"not null;size:10"`
+	Level    string `json:"level,omitempty" gorm:"size:20"`
+}
+
+// PolicyRepository interface for policy database operations
+type PolicyRepository interface {
+	Create(ctx context.Context, policy *AssignmentPolicy) error
+	GetAll(ctx context.Context) ([]AssignmentPolicy, error)
+	GetByID(ctx context.Context, id uint) (*AssignmentPolicy, error)
+	GetByScope(ctx context.Context, scope, subject string) (*AssignmentPolicy, error)
+	GetApplicable(ctx context.Context, employee *Employee) ([]AssignmentPolicy, error)
+	Update(ctx context.Context, policy *AssignmentPolicy) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// PolicyService interface for policy business logic
+type PolicyService interface {
+	CreatePolicy(ctx context.Context, policy *AssignmentPolicy) error
+	GetAllPolicies(ctx context.Context) ([]AssignmentPolicy, error)
+	GetPolicy(ctx context.Context, id uint) (*AssignmentPolicy, error)
+	UpdatePolicy(ctx context.Context, policy *AssignmentPolicy) error
+	DeletePolicy(ctx context.Context, id uint) error
+	ApplyPolicies(ctx context.Context, policies []AssignmentPolicy) error
+}
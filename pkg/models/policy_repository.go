@@ -0,0 +1,95 @@
+package models
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+type policyRepository struct {
+	db *gorm.DB
+}
+
+// NewPolicyRepository creates a new policy repository
+func NewPolicyRepository(db *gorm.DB) PolicyRepository {
+	return &policyRepository{db: db}
+}
+
+// Create adds a new policy with its thresholds
+func (r *policyRepository) Create(ctx context.Context, policy *AssignmentPolicy) error {
+	return r.db.WithContext(ctx).Create(policy).Error
+}
+
+// GetAll retrieves all policies with their thresholds
+func (r *policyRepository) GetAll(ctx context.Context) ([]AssignmentPolicy, error) {
+	var policies []AssignmentPolicy
+	err := r.db.WithContext(ctx).Preload("Thresholds", orderThresholds).Order("id").Find(&policies).Error
+	return policies, err
+}
+
+// GetByID retrieves a policy by ID
+func (r *policyRepository) GetByID(ctx context.Context, id uint) (*AssignmentPolicy, error) {
+	var policy AssignmentPolicy
+	err := r.db.WithContext(ctx).Preload("Thresholds", orderThresholds).First(&policy, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// GetByScope retrieves the policy for a scope and subject
+func (r *policyRepository) GetByScope(ctx context.Context, scope, subject string) (*AssignmentPolicy, error) {
+	var policy AssignmentPolicy
+	err := r.db.WithContext(ctx).Preload("Thresholds", orderThresholds).
+		Where("scope = ? AND subject = ?", scope, subject).
+		First(&policy).Error
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// GetApplicable retrieves the global, department and employee policies that may apply to an employee
+func (r *policyRepository) GetApplicable(ctx context.Context, employee *Employee) ([]AssignmentPolicy, error) {
+	var policies []AssignmentPolicy
+	err := r.db.WithContext(ctx).Preload("Thresholds", orderThresholds).
+		Where("scope = ?", PolicyScopeGlobal).
+		Or("scope = ? AND subject = ?", PolicyScopeDepartment, employee.Department).
+		Or("scope = ? AND subject = ?", PolicyScopeEmployee, employee.Abbreviation).
+		Find(&policies).Error
+	return policies, err
+}
+
+// Update replaces a policy and its thresholds
+func (r *policyRepository) Update(ctx context.Context, policy *AssignmentPolicy) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(policy).Select("scope", "subject", "updated_at").Updates(policy).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("policy_id = ?", policy.ID).Delete(&PolicyThreshold{}).Error; err != nil {
+			return err
+		}
+		for i := range policy.Thresholds {
+			policy.Thresholds[i].ID = 0
+			policy.Thresholds[i].PolicyID = policy.ID
+		}
+		if len(policy.Thresholds) == 0 {
+			return nil
+		}
+		return tx.Create(&policy.Thresholds).Error
+	})
+}
+
+// Delete removes a policy and its thresholds
+func (r *policyRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("policy_id = ?", id).Delete(&PolicyThreshold{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&AssignmentPolicy{}, id).Error
+	})
+}
+
+// orderThresholds sorts preloaded thresholds by count
+func orderThresholds(db *gorm.DB) *gorm.DB {
+	return db.Order("count")
+}
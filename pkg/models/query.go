@@ -0,0 +1,212 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// Pagination limits for list endpoints
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+// or does not belong to the requested sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SortField is a single term of a sort specification
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// ComputerQueryOptions holds filtering, sorting and pagination options for listing computers
+type ComputerQueryOptions struct {
+	// Pagination: either Offset or Cursor is used, Cursor takes precedence
+	Limit  int
+	Offset int
+	Cursor string
+
+	// Filters
+	ComputerName string
+	IPAddress    string
+	// IPNetwork selects the computers with an address in the network, if valid
+	IPNetwork            netip.Prefix
+	MACAddress           string
+	EmployeeAbbreviation string
+	Assigned             *bool
+	CreatedAfter         *time.Time
+	CreatedBefore        *time.Time
+	UpdatedAfter         *time.Time
+	UpdatedBefore        *time.Time
+	IncludeDeleted       bool
+
+	// Sort order, defaults to ascending ID
+	Sort []SortField
+}
+
+// ComputerPage is one page of a computer listing
+type ComputerPage struct {
+	Items      []Computer
+	Total      int64
+	Limit      int
+	Offset     int
+	NextCursor string
+	PrevCursor string
+}
+
+// computerSortColumns maps public sort fields to SQL expressions
+var computerSortColumns = map[string]string{
+	"id":                    "id",
+	"computer_name":         "computer_name",
+	"ip_address":            "ip_number",
+	"mac_address":           "mac_address",
+	"employee_abbreviation": "COALESCE(employee_abbreviation, '')",
+	"created_at":            "created_at",
+	"updated_at":            "updated_at",
+}
+
+// ParseSort parses a sort specification such as "computer_name,-created_at"
+func ParseSort(spec string) ([]SortField, error) {
+	var fields []SortField
+	if strings.TrimSpace(spec) == "" {
+		return fields, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		field := SortField{Field: part}
+		if strings.HasPrefix(part, "-") {
+			field = SortField{Field: part[1:], Desc: true}
+		} else if strings.HasPrefix(part, "+") {
+			field.Field = part[1:]
+		}
+
+		if _, ok := computerSortColumns[field.Field]; !ok {
+			return nil, fmt.Errorf("unsupported sort field %q", field.Field)
+		}
+		if seen[field.Field] {
+			return nil, fmt.Errorf("duplicate sort field %q", field.Field)
+		}
+		seen[field.Field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// sortKey returns the sort fields including the ID tie-breaker
+func sortKey(sort []SortField) []SortField {
+	key := append([]SortField(nil), sort...)
+	for _, f := range key {
+		if f.Field == "id" {
+			return key
+		}
+	}
+	return append(key, SortField{Field: "id"})
+}
+
+// sortSignature returns a canonical string for a sort specification
+func sortSignature(sort []SortField) string {
+	parts := make([]string, len(sort))
+	for i, f := range sort {
+		if f.Desc {
+			parts[i] = "-" + f.Field
+		} else {
+			parts[i] = f.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// pageCursor is the decoded form of an opaque pagination cursor
+type pageCursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+	Before bool     `json:"b,omitempty"`
+}
+
+// encodeCursor builds a cursor positioned at the given computer
+func encodeCursor(sort []SortField, computer *Computer, before bool) string {
+	cursor := pageCursor{
+		Sort:   sortSignature(sort),
+		Before: before,
+	}
+	for _, f := range sort {
+		cursor.Values = append(cursor.Values, cursorValue(computer, f.Field))
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor decodes a cursor and checks it matches the sort specification
+func decodeCursor(raw string, sort []SortField) (*pageCursor, []interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, nil, ErrInvalidCursor
+	}
+
+	var cursor pageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, nil, ErrInvalidCursor
+	}
+	if cursor.Sort != sortSignature(sort) || len(cursor.Values) != len(sort) {
+		return nil, nil, fmt.Errorf("%w: cursor does not match sort order", ErrInvalidCursor)
+	}
+
+	values := make([]interface{}, len(sort))
+	for i, f := range sort {
+		value, err := parseCursorValue(f.Field, cursor.Values[i])
+		if err != nil {
+			return nil, nil, ErrInvalidCursor
+		}
+		values[i] = value
+	}
+	return &cursor, values, nil
+}
+
+// cursorValue returns the string form of a sort field for the given computer
+func cursorValue(computer *Computer, field string) string {
+	switch field {
+	case "id":
+		return fmt.Sprint(computer.ID)
+	case "computer_name":
+		return computer.ComputerName
+	case "ip_address":
+		return computer.IPNumber
+	case "mac_address":
+		return computer.MACAddress
+	case "employee_abbreviation":
+		if computer.EmployeeAbbreviation != nil {
+			return *computer.EmployeeAbbreviation
+		}
+		return ""
+	case "created_at":
+		return computer.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		return computer.UpdatedAt.Format(time.RFC3339Nano)
+	}
+	return ""
+}
+
+// parseCursorValue converts a cursor value back to the type of its column
+func parseCursorValue(field, value string) (interface{}, error) {
+	switch field {
+	case "id":
+		var id uint
+		if _, err := fmt.Sscan(value, &id); err != nil {
+			return nil, err
+		}
+		return id, nil
+	case "created_at", "updated_at":
+		return time.Parse(time.RFC3339Nano, value)
+	}
+	return value, nil
+}
@@ -1,7 +1,14 @@
 package models
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type computerRepository struct {
@@ -13,22 +20,212 @@ func NewComputerRepository(db *gorm.DB) ComputerRepository {
 	return &computerRepository{db: db}
 }
 
-// Create adds a new computer to the database
-func (r *computerRepository) Create(computer *Computer) error {
-	return r.db.Create(computer).Error
+// Create adds a new computer and its outbox messages to the database
+func (r *computerRepository) Create(ctx context.Context, computer *Computer, messages ...*OutboxMessage) error {
+	computer.Version = 1
+	computer.IPNumber = IPNumber(computer.IPAddress)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(computer).Error; err != nil {
+			return translateComputerError(err)
+		}
+		return createOutboxMessages(tx, messages)
+	})
 }
 
-// GetAll retrieves all computers
-func (r *computerRepository) GetAll() ([]Computer, error) {
+// GetAll retrieves a filtered, sorted page of computers
+func (r *computerRepository) GetAll(ctx context.Context, opts ComputerQueryOptions) (*ComputerPage, error) {
+	page := &ComputerPage{Limit: opts.Limit, Offset: opts.Offset}
+
+	if err := r.filtered(ctx, opts).Count(&page.Total).Error; err != nil {
+		return nil, err
+	}
+
+	sort := sortKey(opts.Sort)
+	query := r.filtered(ctx, opts)
+
+	backward := false
+	if opts.Cursor != "" {
+		cursor, values, err := decodeCursor(opts.Cursor, sort)
+		if err != nil {
+			return nil, err
+		}
+		backward = cursor.Before
+		page.Offset = 0
+		query = query.Where(keysetCondition(sort, values, backward))
+	} else if opts.Offset > 0 {
+		query = query.Offset(opts.Offset)
+	}
+
+	for _, f := range sort {
+		desc := f.Desc != backward
+		if desc {
+			query = query.Order(computerSortColumns[f.Field] + " DESC")
+		} else {
+			query = query.Order(computerSortColumns[f.Field] + " ASC")
+		}
+	}
+
+	// Fetch one extra row to find out whether another page exists
 	var computers []Computer
-	err := r.db.Find(&computers).Error
-	return computers, err
+	if err := query.Limit(opts.Limit + 1).Find(&computers).Error; err != nil {
+		return nil, err
+	}
+
+	hasMore := len(computers) > opts.Limit
+	if hasMore {
+		computers = computers[:opts.Limit]
+	}
+	if backward {
+		for i, j := 0, len(computers)-1; i < j; i, j = i+1, j-1 {
+			computers[i], computers[j] = computers[j], computers[i]
+		}
+	}
+	page.Items = computers
+
+	if len(computers) == 0 {
+		return page, nil
+	}
+	first, last := &computers[0], &computers[len(computers)-1]
+	if backward {
+		if hasMore {
+			page.PrevCursor = encodeCursor(sort, first, true)
+		}
+		page.NextCursor = encodeCursor(sort, last, false)
+	} else {
+		if hasMore {
+			page.NextCursor = encodeCursor(sort, last, false)
+		}
+		if opts.Cursor != "" || opts.Offset > 0 {
+			page.PrevCursor = encodeCursor(sort, first, true)
+		}
+	}
+
+	return page, nil
+}
+
+// Stream reads the computers matching opts one row at a time
+func (r *computerRepository) Stream(ctx context.Context, opts ComputerQueryOptions, fn func(computer *Computer) error) error {
+	query := r.filtered(ctx, opts)
+	for _, f := range sortKey(opts.Sort) {
+		if f.Desc {
+			query = query.Order(computerSortColumns[f.Field] + " DESC")
+		} else {
+			query = query.Order(computerSortColumns[f.Field] + " ASC")
+		}
+	}
+
+	return streamComputers(query, fn)
+}
+
+// streamComputers calls fn for every computer query returns, one row at a time
+func streamComputers(query *gorm.DB, fn func(computer *Computer) error) error {
+	rows, err := query.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var computer Computer
+		if err := query.ScanRows(rows, &computer); err != nil {
+			return err
+		}
+		if err := fn(&computer); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// createOutboxMessages stores pending outbox messages within a transaction
+func createOutboxMessages(tx *gorm.DB, messages []*OutboxMessage) error {
+	for _, message := range messages {
+		message.Status = OutboxPending
+		if message.NextAttemptAt.IsZero() {
+			message.NextAttemptAt = time.Now()
+		}
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filtered returns a query with all filters of opts applied
+func (r *computerRepository) filtered(ctx context.Context, opts ComputerQueryOptions) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&Computer{})
+	if opts.IncludeDeleted {
+		query = query.Unscoped()
+	}
+
+	if opts.ComputerName != "" {
+		query = query.Where("LOWER(computer_name) LIKE ?", "%"+strings.ToLower(opts.ComputerName)+"%")
+	}
+	if opts.IPAddress != "" {
+		query = query.Where("ip_address = ?", opts.IPAddress)
+	}
+	if opts.IPNetwork.IsValid() {
+		query = query.Where(ipNetworkCondition(r.db, opts.IPNetwork))
+	}
+	if opts.MACAddress != "" {
+		query = query.Where("mac_address = ?", opts.MACAddress)
+	}
+	if opts.EmployeeAbbreviation != "" {
+		query = query.Where("employee_abbreviation = ?", opts.EmployeeAbbreviation)
+	}
+	if opts.Assigned != nil {
+		if *opts.Assigned {
+			query = query.Where("employee_abbreviation IS NOT NULL AND employee_abbreviation <> ''")
+		} else {
+			query = query.Where("employee_abbreviation IS NULL OR employee_abbreviation = ''")
+		}
+	}
+	if opts.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *opts.CreatedAfter)
+	}
+	if opts.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *opts.CreatedBefore)
+	}
+	if opts.UpdatedAfter != nil {
+		query = query.Where("updated_at >= ?", *opts.UpdatedAfter)
+	}
+	if opts.UpdatedBefore != nil {
+		query = query.Where("updated_at < ?", *opts.UpdatedBefore)
+	}
+
+	return query
+}
+
+// keysetCondition builds the WHERE clause selecting rows after (or before)
+// the cursor position for the given sort order
+func keysetCondition(sort []SortField, values []interface{}, backward bool) clause.Expr {
+	var clauses []string
+	var args []interface{}
+
+	for i, f := range sort {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, computerSortColumns[sort[j].Field]+" = ?")
+			args = append(args, values[j])
+		}
+
+		op := ">"
+		if f.Desc != backward {
+			op = "<"
+		}
+		terms = append(terms, fmt.Sprintf("%s %s ?", computerSortColumns[f.Field], op))
+		args = append(args, values[i])
+
+		clauses = append(clauses, "("+strings.Join(terms, " AND ")+")")
+	}
+
+	return gorm.Expr(strings.Join(clauses, " OR "), args...)
 }
 
 // GetByID retrieves a computer by ID
-func (r *computerRepository) GetByID(id uint) (*Computer, error) {
+func (r *computerRepository) GetByID(ctx context.Context, id uint) (*Computer, error) {
 	var computer Computer
-	err := r.db.First(&computer, id).Error
+	err := r.db.WithContext(ctx).First(&computer, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -36,25 +233,218 @@ func (r *computerRepository) GetByID(id uint) (*Computer, error) {
 }
 
 // GetByEmployeeAbbreviation retrieves computers by employee abbreviation
-func (r *computerRepository) GetByEmployeeAbbreviation(abbr string) ([]Computer, error) {
+func (r *computerRepository) GetByEmployeeAbbreviation(ctx context.Context, abbr string, includeDeleted bool) ([]Computer, error) {
+	query := r.db.WithContext(ctx)
+	if includeDeleted {
+		query = query.Unscoped()
+	}
+
 	var computers []Computer
-	err := r.db.Where("employee_abbreviation = ?", abbr).Find(&computers).Error
+	err := query.Where("employee_abbreviation = ?", abbr).Find(&computers).Error
 	return computers, err
 }
 
-// Update updates a computer
-func (r *computerRepository) Update(computer *Computer) error {
-	return r.db.Save(computer).Error
+// GetByMACAddress retrieves the computer that is not deleted with the given MAC address
+func (r *computerRepository) GetByMACAddress(ctx context.Context, mac string) (*Computer, error) {
+	var computer Computer
+	err := r.db.WithContext(ctx).Where("mac_address = ?", mac).First(&computer).Error
+	if err != nil {
+		return nil, err
+	}
+	return &computer, nil
+}
+
+// GetDeletedByID retrieves a deleted computer that has not been purged yet
+func (r *computerRepository) GetDeletedByID(ctx context.Context, id uint) (*Computer, error) {
+	var computer Computer
+	err := r.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(&computer, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &computer, nil
+}
+
+// Update updates a computer if its stored version still matches computer.Version,
+// increments the version and stores the outbox messages in the same transaction.
+// The version and update time are set here and the deletion time only by
+// Delete and Restore, whatever computer holds.
+func (r *computerRepository) Update(ctx context.Context, computer *Computer, messages ...*OutboxMessage) error {
+	expected, previousUpdate := computer.Version, computer.UpdatedAt
+	computer.Version = expected + 1
+	computer.UpdatedAt = time.Now()
+	computer.IPNumber = IPNumber(computer.IPAddress)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Computer{}).
+			Where("id = ? AND version = ?", computer.ID, expected).
+			UpdateColumns(map[string]interface{}{"version": computer.Version, "updated_at": computer.UpdatedAt})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+
+		err := tx.Model(computer).
+			Select("*").
+			Omit("id", "created_at", "updated_at", "version", "deleted_at").
+			Updates(computer).Error
+		if err != nil {
+			return translateComputerError(err)
+		}
+		return createOutboxMessages(tx, messages)
+	})
+	if err != nil {
+		computer.Version, computer.UpdatedAt = expected, previousUpdate
+	}
+	return err
 }
 
-// Delete removes a computer by ID
-func (r *computerRepository) Delete(id uint) error {
-	return r.db.Delete(&Computer{}, id).Error
+// Delete marks a computer as deleted, optionally only if it has the given version
+func (r *computerRepository) Delete(ctx context.Context, id uint, version uint) error {
+	query := r.db.WithContext(ctx).Where("id = ?", id)
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+
+	result := query.Delete(&Computer{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 && version != 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// Restore clears the deletion mark of a deleted computer, increments its
+// version and stores the outbox messages in the same transaction
+func (r *computerRepository) Restore(ctx context.Context, computer *Computer, messages ...*OutboxMessage) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(computer).
+			Where("deleted_at IS NOT NULL").
+			Updates(map[string]interface{}{
+				"deleted_at": nil,
+				"version":    gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return translateComputerError(result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+
+		computer.DeletedAt = gorm.DeletedAt{}
+		computer.Version++
+		return createOutboxMessages(tx, messages)
+	})
+}
+
+// Purge permanently removes computers deleted before the given time and
+// returns them
+func (r *computerRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]Computer, error) {
+	var computers []Computer
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+			Find(&computers).Error
+		if err != nil || len(computers) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(computers))
+		for i := range computers {
+			ids[i] = computers[i].ID
+		}
+		return tx.Unscoped().Delete(&Computer{}, ids).Error
+	})
+	return computers, err
+}
+
+// Transaction runs fn inside a database transaction
+func (r *computerRepository) Transaction(ctx context.Context, fn func(tx ComputerRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&computerRepository{db: tx})
+	})
+}
+
+// LockByID retrieves a computer with SELECT ... FOR UPDATE. SQLite has no row
+// locks and relies on transactions taking the write lock when they begin.
+func (r *computerRepository) LockByID(ctx context.Context, id uint) (*Computer, error) {
+	var computer Computer
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&computer, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &computer, nil
+}
+
+// LockEmployee retrieves an employee with SELECT ... FOR UPDATE
+func (r *computerRepository) LockEmployee(ctx context.Context, abbr string) (*Employee, error) {
+	var employee Employee
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Where("abbreviation = ?", abbr).First(&employee).Error
+	if err != nil {
+		return nil, err
+	}
+	return &employee, nil
+}
+
+// Count counts active and deleted computers in a single query
+func (r *computerRepository) Count(ctx context.Context) (int64, int64, error) {
+	var row struct {
+		Active  int64
+		Deleted int64
+	}
+	err := r.db.WithContext(ctx).Unscoped().Model(&Computer{}).
+		Select("COUNT(CASE WHEN deleted_at IS NULL THEN 1 END) AS active, COUNT(deleted_at) AS deleted").
+		Scan(&row).Error
+	return row.Active, row.Deleted, err
 }
 
 // CountByEmployee counts computers assigned to an employee
-func (r *computerRepository) CountByEmployee(abbr string) (int64, error) {
+func (r *computerRepository) CountByEmployee(ctx context.Context, abbr string) (int64, error) {
 	var count int64
-	err := r.db.Model(&Computer{}).Where("employee_abbreviation = ?", abbr).Count(&count).Error
+	err := r.db.WithContext(ctx).Model(&Computer{}).Where("employee_abbreviation = ?", abbr).Count(&count).Error
 	return count, err
 }
+
+// CountByEmployees counts computers per assigned employee
+func (r *computerRepository) CountByEmployees(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		EmployeeAbbreviation string
+		Count                int64
+	}
+	err := r.db.WithContext(ctx).Model(&Computer{}).
+		Select("employee_abbreviation, COUNT(*) AS count").
+		Where("employee_abbreviation IS NOT NULL").
+		Group("employee_abbreviation").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.EmployeeAbbreviation] = row.Count
+	}
+	return counts, nil
+}
+
+// RecordAudit appends audit entries to the audit log
+func (r *computerRepository) RecordAudit(ctx context.Context, entries ...*AuditEntry) error {
+	return createAuditEntries(r.db.WithContext(ctx), entries)
+}
+
+// QueueMessages stores outbox messages
+func (r *computerRepository) QueueMessages(ctx context.Context, messages ...*OutboxMessage) error {
+	return createOutboxMessages(r.db.WithContext(ctx), messages)
+}
+
+// translateComputerError reports unique violations as ErrDuplicateMACAddress,
+// the only unique column of computers besides the primary key
+func translateComputerError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return fmt.Errorf("%w: %v", ErrDuplicateMACAddress, err)
+	}
+	return err
+}
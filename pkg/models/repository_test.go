@@ -0,0 +1,585 @@
+package models_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"greenbone-case-study/internal/db"
+	"greenbone-case-study/pkg/models"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// newTestDB opens a database migrated by the SQL migrations, so the
+// repositories are tested against the schema they run on
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	database, err := db.InitDatabase(filepath.Join(t.TempDir(), "computers.db"), "sqlite")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(database) })
+
+	if err := database.Create(&models.Employee{Abbreviation: "abc", Name: "Test"}).Error; err != nil {
+		t.Fatalf("Failed to create employee: %v", err)
+	}
+	return database
+}
+
+func seedComputers(t *testing.T, repo models.ComputerRepository, n int) {
+	t.Helper()
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= n; i++ {
+		computer := &models.Computer{
+			MACAddress:   fmt.Sprintf("00:11:22:33:44:%02d", i),
+			ComputerName: fmt.Sprintf("Computer %02d", i%5),
+			IPAddress:    fmt.Sprintf("10.0.0.%d", i),
+			CreatedAt:    base.Add(time.Duration(i) * time.Hour),
+		}
+		if i%2 == 0 {
+			abbr := "abc"
+			computer.EmployeeAbbreviation = &abbr
+		}
+		if err := repo.Create(context.Background(), computer); err != nil {
+			t.Fatalf("Failed to create computer: %v", err)
+		}
+	}
+}
+
+func TestGetAllFilters(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+	seedComputers(t, repo, 10)
+
+	assigned, unassigned := true, false
+	after := time.Date(2024, 1, 1, 5, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		opts models.ComputerQueryOptions
+		want int64
+	}{
+		{"no filter", models.ComputerQueryOptions{}, 10},
+		{"name substring", models.ComputerQueryOptions{ComputerName: "computer 01"}, 2},
+		{"ip address", models.ComputerQueryOptions{IPAddress: "10.0.0.3"}, 1},
+		{"mac address", models.ComputerQueryOptions{MACAddress: "00:11:22:33:44:04"}, 1},
+		{"employee", models.ComputerQueryOptions{EmployeeAbbreviation: "abc"}, 5},
+		{"assigned", models.ComputerQueryOptions{Assigned: &assigned}, 5},
+		{"unassigned", models.ComputerQueryOptions{Assigned: &unassigned}, 5},
+		{"created after", models.ComputerQueryOptions{CreatedAfter: &after}, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Limit = models.DefaultPageLimit
+			page, err := repo.GetAll(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if page.Total != tt.want || int64(len(page.Items)) != tt.want {
+				t.Errorf("Expected %d computers, got total %d and %d items", tt.want, page.Total, len(page.Items))
+			}
+		})
+	}
+}
+
+func TestGetAllOffsetPagination(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+	seedComputers(t, repo, 5)
+
+	page, err := repo.GetAll(context.Background(), models.ComputerQueryOptions{Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if page.Total != 5 {
+		t.Errorf("Expected total 5, got %d", page.Total)
+	}
+	if len(page.Items) != 2 || page.Items[0].ID != 3 || page.Items[1].ID != 4 {
+		t.Errorf("Expected computers 3 and 4, got %+v", page.Items)
+	}
+	if page.NextCursor == "" || page.PrevCursor == "" {
+		t.Error("Expected next and prev cursors")
+	}
+}
+
+func TestGetAllCursorPagination(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+	seedComputers(t, repo, 7)
+
+	sort, _ := models.ParseSort("-created_at")
+	opts := models.ComputerQueryOptions{Limit: 3, Sort: sort}
+
+	var seen []uint
+	for {
+		page, err := repo.GetAll(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		for _, c := range page.Items {
+			seen = append(seen, c.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+
+	want := []uint{7, 6, 5, 4, 3, 2, 1}
+	if fmt.Sprint(seen) != fmt.Sprint(want) {
+		t.Fatalf("Expected order %v, got %v", want, seen)
+	}
+
+	// Walk back from the last page
+	page, _ := repo.GetAll(context.Background(), opts)
+	opts.Cursor = page.PrevCursor
+	prev, err := repo.GetAll(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(prev.Items) != 3 || prev.Items[0].ID != 4 || prev.Items[2].ID != 2 {
+		t.Errorf("Expected computers 4..2 on previous page, got %+v", prev.Items)
+	}
+}
+
+func TestGetAllCursorSortMismatch(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+	seedComputers(t, repo, 3)
+
+	page, _ := repo.GetAll(context.Background(), models.ComputerQueryOptions{Limit: 1})
+
+	sort, _ := models.ParseSort("computer_name")
+	_, err := repo.GetAll(context.Background(), models.ComputerQueryOptions{Limit: 1, Cursor: page.NextCursor, Sort: sort})
+	if err == nil {
+		t.Error("Expected error for cursor with different sort order")
+	}
+}
+
+func TestStream(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+	seedComputers(t, repo, 10)
+
+	var ids []uint
+	opts := models.ComputerQueryOptions{EmployeeAbbreviation: "abc", Limit: 1, Sort: []models.SortField{{Field: "created_at", Desc: true}}}
+	err := repo.Stream(context.Background(), opts, func(computer *models.Computer) error {
+		ids = append(ids, computer.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if fmt.Sprint(ids) != "[10 8 6 4 2]" {
+		t.Errorf("Expected every assigned computer newest first, got %v", ids)
+	}
+
+	stop := errors.New("stop")
+	streamed := 0
+	err = repo.Stream(context.Background(), models.ComputerQueryOptions{}, func(computer *models.Computer) error {
+		streamed++
+		return stop
+	})
+	if !errors.Is(err, stop) || streamed != 1 {
+		t.Errorf("Expected the stream to stop at the first error, got %v after %d computers", err, streamed)
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	sort, err := models.ParseSort("computer_name,-created_at")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(sort) != 2 || sort[0].Desc || !sort[1].Desc {
+		t.Errorf("Unexpected sort fields: %+v", sort)
+	}
+
+	for _, spec := range []string{"unknown", "id,-id"} {
+		if _, err := models.ParseSort(spec); err == nil {
+			t.Errorf("Expected error for sort %q", spec)
+		}
+	}
+}
+
+func TestUpdateIsConditionalOnVersion(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+	seedComputers(t, repo, 1)
+
+	first, _ := repo.GetByID(context.Background(), 1)
+	second, _ := repo.GetByID(context.Background(), 1)
+
+	first.ComputerName = "First"
+	if err := repo.Update(context.Background(), first); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if first.Version != 2 {
+		t.Errorf("Expected version 2, got %d", first.Version)
+	}
+
+	second.ComputerName = "Second"
+	if err := repo.Update(context.Background(), second); !errors.Is(err, models.ErrVersionConflict) {
+		t.Fatalf("Expected ErrVersionConflict, got %v", err)
+	}
+
+	stored, _ := repo.GetByID(context.Background(), 1)
+	if stored.ComputerName != "First" {
+		t.Errorf("Expected stored name First, got %s", stored.ComputerName)
+	}
+	if stored.CreatedAt.IsZero() {
+		t.Error("Expected created_at to be preserved")
+	}
+
+	if err := repo.Delete(context.Background(), 1, 1); !errors.Is(err, models.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict for stale delete, got %v", err)
+	}
+	if err := repo.Delete(context.Background(), 1, 2); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+}
+
+func TestUpdateKeepsDeletionTime(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+	seedComputers(t, repo, 1)
+
+	computer, _ := repo.GetByID(context.Background(), 1)
+	computer.ComputerName = "Renamed"
+	computer.DeletedAt = gorm.DeletedAt{Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	if err := repo.Update(context.Background(), computer); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	stored, err := repo.GetByID(context.Background(), 1)
+	if err != nil || stored.ComputerName != "Renamed" || stored.Version != 2 {
+		t.Errorf("Expected the computer to be updated and not deleted, got %+v, %v", stored, err)
+	}
+}
+
+func TestEmployeeForeignKey(t *testing.T) {
+	database := newTestDB(t)
+	repo := models.NewComputerRepository(database)
+	employees := models.NewEmployeeRepository(database)
+	seedComputers(t, repo, 2)
+
+	unknown := "zzz"
+	err := repo.Create(context.Background(), &models.Computer{MACAddress: "00:11:22:33:44:99", ComputerName: "Test", IPAddress: "10.0.0.99", EmployeeAbbreviation: &unknown})
+	if err == nil {
+		t.Error("Expected foreign key error for unknown employee")
+	}
+
+	if err := employees.Delete(context.Background(), "abc"); err == nil {
+		t.Error("Expected foreign key error deleting employee with computers")
+	}
+}
+
+func TestCreateStoresOutboxMessagesAtomically(t *testing.T) {
+	database := newTestDB(t)
+	repo := models.NewComputerRepository(database)
+	outbox := models.NewOutboxRepository(database)
+
+	computer := &models.Computer{MACAddress: "00:11:22:33:44:55", ComputerName: "Test", IPAddress: "10.0.0.1"}
+	if err := repo.Create(context.Background(), computer, &models.OutboxMessage{Kind: "test", Payload: "{}"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// A failing insert must not leave its message behind
+	duplicate := &models.Computer{MACAddress: "00:11:22:33:44:55", ComputerName: "Duplicate", IPAddress: "10.0.0.2"}
+	if err := repo.Create(context.Background(), duplicate, &models.OutboxMessage{Kind: "test", Payload: "{}"}); err == nil {
+		t.Fatal("Expected duplicate MAC error")
+	}
+
+	due, err := outbox.GetDue(context.Background(), time.Now(), 10)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(due) != 1 || due[0].Status != models.OutboxPending {
+		t.Fatalf("Expected 1 pending message, got %+v", due)
+	}
+
+	claimed, err := outbox.Claim(context.Background(), &due[0], time.Now().Add(time.Minute))
+	if err != nil || !claimed {
+		t.Fatalf("Expected message to be claimed, got %v, %v", claimed, err)
+	}
+
+	// A second dispatcher holding the stale copy loses the race
+	stale := due[0]
+	stale.Attempts = 0
+	if claimed, _ := outbox.Claim(context.Background(), &stale, time.Now().Add(time.Minute)); claimed {
+		t.Error("Expected stale claim to fail")
+	}
+	if due, _ := outbox.GetDue(context.Background(), time.Now(), 10); len(due) != 0 {
+		t.Errorf("Expected leased message to be hidden, got %d due", len(due))
+	}
+
+	// Neither a stale copy nor a leased message is replayed
+	if replayed, _ := outbox.Replay(context.Background(), &stale, time.Now()); replayed {
+		t.Error("Expected stale replay to fail")
+	}
+	if replayed, _ := outbox.Replay(context.Background(), &due[0], time.Now()); replayed {
+		t.Error("Expected replay of a leased message to fail")
+	}
+	if replayed, err := outbox.Replay(context.Background(), &due[0], time.Now().Add(2*time.Minute)); err != nil || !replayed {
+		t.Errorf("Expected replay after the lease expired, got %v, %v", replayed, err)
+	}
+	if due[0].Attempts != 0 || due[0].LeasedUntil != nil {
+		t.Errorf("Expected a fresh attempt budget, got %+v", due[0])
+	}
+}
+
+func TestPolicyRepository(t *testing.T) {
+	repo := models.NewPolicyRepository(newTestDB(t))
+
+	policies := []*models.AssignmentPolicy{
+		{Scope: models.PolicyScopeGlobal, Thresholds: []models.PolicyThreshold{{Count: 3, Action: models.ThresholdWarn, Level: "warning"}}},
+		{Scope: models.PolicyScopeDepartment, Subject: "IT", Thresholds: []models.PolicyThreshold{{Count: 5, Action: models.ThresholdBlock}}},
+		{Scope: models.PolicyScopeDepartment, Subject: "Sales", Thresholds: []models.PolicyThreshold{{Count: 2, Action: models.ThresholdBlock}}},
+		{Scope: models.PolicyScopeEmployee, Subject: "abc", Thresholds: []models.PolicyThreshold{{Count: 4, Action: models.ThresholdWarn, Level: "info"}}},
+	}
+	for _, policy := range policies {
+		if err := repo.Create(context.Background(), policy); err != nil {
+			t.Fatalf("Failed to create policy: %v", err)
+		}
+	}
+
+	applicable, err := repo.GetApplicable(context.Background(), &models.Employee{Abbreviation: "abc", Department: "IT"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(applicable) != 3 {
+		t.Fatalf("Expected 3 applicable policies, got %d", len(applicable))
+	}
+	for _, policy := range applicable {
+		if policy.Subject == "Sales" {
+			t.Error("Expected Sales policy not to apply")
+		}
+		if len(policy.Thresholds) != 1 {
+			t.Errorf("Expected thresholds to be loaded, got %+v", policy)
+		}
+	}
+
+	// Updating replaces the thresholds
+	update := &models.AssignmentPolicy{ID: policies[1].ID, Scope: models.PolicyScopeDepartment, Subject: "IT", Thresholds: []models.PolicyThreshold{
+		{Count: 6, Action: models.ThresholdBlock},
+		{Count: 4, Action: models.ThresholdWarn, Level: "critical"},
+	}}
+	if err := repo.Update(context.Background(), update); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	stored, err := repo.GetByScope(context.Background(), models.PolicyScopeDepartment, "IT")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(stored.Thresholds) != 2 || stored.Thresholds[0].Count != 4 || stored.Thresholds[1].Count != 6 {
+		t.Errorf("Expected thresholds 4 and 6, got %+v", stored.Thresholds)
+	}
+
+	if err := repo.Delete(context.Background(), stored.ID); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := repo.GetByID(context.Background(), stored.ID); err == nil {
+		t.Error("Expected deleted policy to be gone")
+	}
+}
+
+func TestAuditRepository(t *testing.T) {
+	database := newTestDB(t)
+	repo := models.NewComputerRepository(database)
+	audit := models.NewAuditRepository(database)
+
+	entries := []*models.AuditEntry{
+		{EntityType: models.AuditEntityComputer, EntityID: 1, Action: models.AuditCreate, Actor: "alice", Changes: models.AuditChanges{"ip_address": {To: "10.0.0.1"}}},
+		{EntityType: models.AuditEntityComputer, EntityID: 1, Action: models.AuditUpdate, Actor: "bob", RequestID: "req-2", Changes: models.AuditChanges{"ip_address": {From: "10.0.0.1", To: "10.0.0.2"}}},
+		{EntityType: models.AuditEntityComputer, EntityID: 2, Action: models.AuditCreate, Actor: "alice"},
+	}
+	if err := repo.RecordAudit(context.Background(), entries...); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts models.AuditQueryOptions
+		want int
+	}{
+		{"all", models.AuditQueryOptions{}, 3},
+		{"entity", models.AuditQueryOptions{EntityType: models.AuditEntityComputer, EntityID: 1}, 2},
+		{"actor", models.AuditQueryOptions{Actor: "alice"}, 2},
+		{"limit", models.AuditQueryOptions{Limit: 1}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.opts.Limit == 0 {
+				tt.opts.Limit = 10
+			}
+			found, total, err := audit.GetAll(context.Background(), tt.opts)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if len(found) != tt.want {
+				t.Errorf("Expected %d entries, got %d (total %d)", tt.want, len(found), total)
+			}
+		})
+	}
+
+	found, _, _ := audit.GetAll(context.Background(), models.AuditQueryOptions{Actor: "bob", Limit: 10})
+	if len(found) != 1 || found[0].Changes["ip_address"].To != "10.0.0.2" || found[0].RequestID != "req-2" {
+		t.Errorf("Expected stored changes to round-trip, got %+v", found)
+	}
+}
+
+func TestAPIKeyRepository(t *testing.T) {
+	repo := models.NewAPIKeyRepository(newTestDB(t))
+
+	key := &models.APIKey{Name: "dashboard", Role: models.RoleViewer, Prefix: "cmk_01234567", Hash: "hash-1"}
+	if err := repo.Create(context.Background(), key); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := repo.Create(context.Background(), &models.APIKey{Name: "dashboard", Role: models.RoleAdmin, Hash: "hash-2"}); err == nil {
+		t.Error("Expected duplicate name error")
+	}
+
+	found, err := repo.GetByHash(context.Background(), "hash-1")
+	if err != nil || found.ID != key.ID {
+		t.Fatalf("Expected key %d by hash, got %+v, %v", key.ID, found, err)
+	}
+	if _, err := repo.GetByHash(context.Background(), "hash-2"); err == nil {
+		t.Error("Expected unknown hash to be missing")
+	}
+
+	now := time.Now()
+	if err := repo.TouchLastUsed(context.Background(), key.ID, now); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	found.RevokedAt = &now
+	if err := repo.Update(context.Background(), found); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	found, err = repo.GetByName(context.Background(), "dashboard")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if found.LastUsedAt == nil || found.RevokedAt == nil {
+		t.Errorf("Expected last use and revocation to be stored, got %+v", found)
+	}
+}
+
+func TestSoftDeleteRestoreAndPurge(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+	seedComputers(t, repo, 2)
+
+	if err := repo.Delete(context.Background(), 2, 0); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if _, err := repo.GetByID(context.Background(), 2); err == nil {
+		t.Error("Expected deleted computer to be hidden")
+	}
+	if page, _ := repo.GetAll(context.Background(), models.ComputerQueryOptions{Limit: 10}); page.Total != 1 {
+		t.Errorf("Expected 1 computer, got %d", page.Total)
+	}
+	if page, _ := repo.GetAll(context.Background(), models.ComputerQueryOptions{Limit: 10, IncludeDeleted: true}); page.Total != 2 {
+		t.Errorf("Expected 2 computers including deleted, got %d", page.Total)
+	}
+	if computers, _ := repo.GetByEmployeeAbbreviation(context.Background(), "abc", false); len(computers) != 0 {
+		t.Errorf("Expected no active computers for abc, got %d", len(computers))
+	}
+	if computers, _ := repo.GetByEmployeeAbbreviation(context.Background(), "abc", true); len(computers) != 1 {
+		t.Errorf("Expected 1 computer for abc including deleted, got %d", len(computers))
+	}
+
+	// The MAC address of a deleted computer can be reused, but only once
+	deleted, err := repo.GetDeletedByID(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	reuse := &models.Computer{MACAddress: deleted.MACAddress, ComputerName: "Reuse", IPAddress: "10.0.0.50"}
+	if err := repo.Create(context.Background(), reuse); err != nil {
+		t.Fatalf("Expected MAC address of deleted computer to be reusable, got: %v", err)
+	}
+	duplicate := &models.Computer{MACAddress: deleted.MACAddress, ComputerName: "Duplicate", IPAddress: "10.0.0.51"}
+	if err := repo.Create(context.Background(), duplicate); !errors.Is(err, models.ErrDuplicateMACAddress) {
+		t.Errorf("Expected ErrDuplicateMACAddress among active computers, got: %v", err)
+	}
+
+	if err := repo.Restore(context.Background(), deleted); !errors.Is(err, models.ErrDuplicateMACAddress) {
+		t.Errorf("Expected restore to fail with ErrDuplicateMACAddress while the MAC address is in use, got: %v", err)
+	}
+	if err := repo.Delete(context.Background(), reuse.ID, 0); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := repo.Restore(context.Background(), deleted); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	restored, err := repo.GetByID(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("Expected restored computer, got: %v", err)
+	}
+	if restored.Version != 2 {
+		t.Errorf("Expected version 2 after restore, got %d", restored.Version)
+	}
+
+	// Only computers deleted before the cutoff are purged
+	if purged, _ := repo.Purge(context.Background(), time.Now().Add(-time.Hour)); len(purged) != 0 {
+		t.Errorf("Expected nothing to purge, got %d", len(purged))
+	}
+	purged, err := repo.Purge(context.Background(), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(purged) != 1 || purged[0].ID != reuse.ID {
+		t.Errorf("Expected computer %d to be purged, got %+v", reuse.ID, purged)
+	}
+	if page, _ := repo.GetAll(context.Background(), models.ComputerQueryOptions{Limit: 10, IncludeDeleted: true}); page.Total != 2 {
+		t.Errorf("Expected 2 computers after purge, got %d", page.Total)
+	}
+}
+
+func TestCountByEmployees(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+	seedComputers(t, repo, 6)
+
+	// Deleted computers are not counted
+	if err := repo.Delete(context.Background(), 2, 0); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	counts, err := repo.CountByEmployees(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(counts) != 1 || counts["abc"] != 2 {
+		t.Errorf("Expected 2 computers for abc only, got %v", counts)
+	}
+}
+
+func TestCountComputers(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+	seedComputers(t, repo, 3)
+	if err := repo.Delete(context.Background(), 2, 0); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	active, deleted, err := repo.Count(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if active != 2 || deleted != 1 {
+		t.Errorf("Expected 2 active and 1 deleted computers, got %d and %d", active, deleted)
+	}
+}
+
+func TestQueriesUseContext(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+	seedComputers(t, repo, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := repo.GetByID(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the query to be cancelled, got: %v", err)
+	}
+	if _, err := repo.GetAll(ctx, models.ComputerQueryOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the query to be cancelled, got: %v", err)
+	}
+}
@@ -0,0 +1,432 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Search limits
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+	// MaxSearchTerms bounds the number of words of a search query
+	MaxSearchTerms = 10
+)
+
+// searchCandidateFactor bounds the candidates a search scores to this many
+// times its limit
+const searchCandidateFactor = 10
+
+// fuzzyThreshold is the trigram similarity from which a word of a computer
+// name counts as a misspelt match. Candidates are selected in SQL with a lower
+// threshold, as pg_trgm measures word similarity slightly differently.
+const (
+	fuzzyThreshold    = 0.4
+	fuzzySQLThreshold = 0.3
+)
+
+// Weights of the ways a search term can match a computer
+const (
+	scoreExact       = 10
+	scoreName        = 8
+	scoreEmployee    = 8
+	scoreNamePrefix  = 5
+	scoreIPRange     = 5
+	scoreMACFragment = 4
+	scoreNameContain = 3
+	scoreFuzzy       = 3
+	scoreDescription = 1
+)
+
+// ComputerSearchOptions holds a search query. Every word of Query must match
+// a computer, in its name, description, MAC or IP address or employee.
+type ComputerSearchOptions struct {
+	Query          string
+	Limit          int
+	IncludeDeleted bool
+}
+
+// ComputerSearchHit is a computer found by a search, with its relevance and
+// the fields the query matched
+type ComputerSearchHit struct {
+	Computer
+	Score   float64  `json:"score"`
+	Matches []string `json:"matches"`
+}
+
+// searchTerm is a word of a search query with the ways it can be read
+type searchTerm struct {
+	// text is the lowercased word
+	text string
+	// mac holds the hex digits of a word that can be part of a MAC address
+	mac string
+	// addr is set for a complete IP address, prefix for a CIDR
+	addr   netip.Addr
+	prefix netip.Prefix
+	// ipPrefix is set for the start of an IP address such as 10.20.
+	ipPrefix bool
+}
+
+// SearchTerms splits a search query into its words
+func SearchTerms(query string) []string {
+	return strings.Fields(query)
+}
+
+// parseSearchTerms reads the words of a search query
+func parseSearchTerms(query string) []searchTerm {
+	words := SearchTerms(query)
+	terms := make([]searchTerm, len(words))
+	for i, word := range words {
+		term := searchTerm{text: strings.ToLower(word)}
+
+		if prefix, err := netip.ParsePrefix(word); err == nil {
+			term.prefix = prefix.Masked()
+		} else if addr, err := netip.ParseAddr(word); err == nil {
+			term.addr = addr.Unmap()
+		} else {
+			term.ipPrefix = isIPPrefix(term.text)
+		}
+
+		if hex := strings.NewReplacer(":", "", "-", "", ".", "").Replace(term.text); len(hex) >= 4 && len(hex) <= 12 &&
+			isHex(hex) && !isDottedDecimal(term.text) {
+			term.mac = hex
+		}
+		terms[i] = term
+	}
+	return terms
+}
+
+// scoreComputer rates how well a computer matches every term, or returns
+// zero if a term does not match at all
+func scoreComputer(terms []searchTerm, computer *Computer) (float64, []string) {
+	name := strings.ToLower(computer.ComputerName)
+	words := strings.FieldsFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	description := strings.ToLower(computer.Description)
+	mac := strings.ReplaceAll(computer.MACAddress, ":", "")
+	addr, _ := netip.ParseAddr(computer.IPAddress)
+
+	total := 0.0
+	matched := make(map[string]bool)
+	for _, term := range terms {
+		score := 0.0
+		match := func(field string, weight float64) {
+			score += weight
+			matched[field] = true
+		}
+
+		switch {
+		case name == term.text:
+			match("computer_name", scoreName)
+		case hasWordPrefix(words, term.text):
+			match("computer_name", scoreNamePrefix)
+		case strings.Contains(name, term.text):
+			match("computer_name", scoreNameContain)
+		case len([]rune(term.text)) >= 3:
+			if similarity := bestSimilarity(words, term.text); similarity >= fuzzyThreshold {
+				match("computer_name", scoreFuzzy*similarity)
+			}
+		}
+		if strings.Contains(description, term.text) {
+			match("description", scoreDescription)
+		}
+		if computer.EmployeeAbbreviation != nil && *computer.EmployeeAbbreviation == term.text {
+			match("employee_abbreviation", scoreEmployee)
+		}
+		if term.mac != "" {
+			if mac == term.mac {
+				match("mac_address", scoreExact)
+			} else if strings.Contains(mac, term.mac) {
+				match("mac_address", scoreMACFragment)
+			}
+		}
+		switch {
+		case term.addr.IsValid() && addr == term.addr:
+			match("ip_address", scoreExact)
+		case term.prefix.IsValid() && term.prefix.Contains(addr):
+			match("ip_address", scoreIPRange)
+		case term.ipPrefix && strings.HasPrefix(computer.IPAddress, term.text):
+			match("ip_address", scoreIPRange)
+		}
+
+		if score == 0 {
+			return 0, nil
+		}
+		total += score
+	}
+
+	matches := make([]string, 0, len(matched))
+	for field := range matched {
+		matches = append(matches, field)
+	}
+	sort.Strings(matches)
+	return math.Round(total*100) / 100, matches
+}
+
+// Search finds the computers matching every term of the query, best first.
+// Candidates are selected in SQL: on PostgreSQL with full-text search,
+// LIKE and pg_trgm, served by the indexes of migration 5; on other databases
+// with LIKE alone. The database ranks them by searchRank and returns only the
+// best opts.Limit times searchCandidateFactor, which are then ranked alike by
+// scoreComputer; only the best opts.Limit are kept in memory.
+func (r *computerRepository) Search(ctx context.Context, opts ComputerSearchOptions) ([]ComputerSearchHit, error) {
+	terms := parseSearchTerms(opts.Query)
+	if len(terms) == 0 {
+		return []ComputerSearchHit{}, nil
+	}
+
+	hits := make([]ComputerSearchHit, 0, opts.Limit)
+	search := func(db *gorm.DB) error {
+		query := db.Model(&Computer{})
+		if opts.IncludeDeleted {
+			query = query.Unscoped()
+		}
+		// A term that cannot be narrowed down in SQL cannot be ranked there
+		// either, so its candidates are not capped
+		narrowed := true
+		ranks := make([]string, len(terms))
+		var rankArgs []interface{}
+		for i, term := range terms {
+			if condition, ok := searchCondition(db, term); ok {
+				query = query.Where(condition)
+			} else {
+				narrowed = false
+			}
+			rank, args := searchRank(db, term)
+			ranks[i] = "(" + rank + ")"
+			rankArgs = append(rankArgs, args...)
+		}
+		query = query.Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                strings.Join(ranks, " + ") + " DESC, id",
+			Vars:               rankArgs,
+			WithoutParentheses: true,
+		}})
+		if narrowed {
+			query = query.Limit(opts.Limit * searchCandidateFactor)
+		}
+
+		return streamComputers(query, func(computer *Computer) error {
+			score, matches := scoreComputer(terms, computer)
+			if score == 0 {
+				return nil
+			}
+
+			// Keep the best hits ordered by score, then ID
+			i := sort.Search(len(hits), func(i int) bool { return hits[i].Score < score })
+			if i >= opts.Limit {
+				return nil
+			}
+			if len(hits) < opts.Limit {
+				hits = append(hits, ComputerSearchHit{})
+			}
+			copy(hits[i+1:], hits[i:])
+			hits[i] = ComputerSearchHit{Computer: *computer, Score: score, Matches: matches}
+			return nil
+		})
+	}
+
+	var err error
+	if r.db.Dialector.Name() == "postgres" {
+		// The threshold of the <% operator is a setting, set for the
+		// transaction of the search only
+		err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			err := tx.Exec("SELECT set_config('pg_trgm.word_similarity_threshold', ?, true)", strconv.FormatFloat(fuzzySQLThreshold, 'f', -1, 64)).Error
+			if err != nil {
+				return err
+			}
+			return search(tx)
+		})
+	} else {
+		err = search(r.db.WithContext(ctx))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hits, nil
+}
+
+// searchCondition selects the computers a term can match in SQL, a superset
+// of those scoreComputer accepts: pg_trgm may select a few misspelt names
+// that scoreComputer then rejects. It returns false if a term cannot be
+// narrowed down in SQL.
+func searchCondition(db *gorm.DB, term searchTerm) (clause.Expr, bool) {
+	postgres := db.Dialector.Name() == "postgres"
+	// SQLite lowercases ASCII letters only
+	if !postgres && !isASCII(term.text) {
+		return clause.Expr{}, false
+	}
+
+	contains := "%" + escapeLike(term.text) + "%"
+	conditions := []string{
+		`LOWER(computer_name) LIKE ? ESCAPE '\'`,
+		`LOWER(description) LIKE ? ESCAPE '\'`,
+		"employee_abbreviation = ?",
+	}
+	args := []interface{}{contains, contains, term.text}
+
+	if postgres {
+		conditions = append(conditions,
+			"to_tsvector('simple', computer_name || ' ' || COALESCE(description, '')) @@ plainto_tsquery('simple', ?)",
+			"? <% LOWER(computer_name)")
+		args = append(args, term.text, term.text)
+	} else if runes := []rune(term.text); len(runes) >= 3 {
+		// A word similar enough to a term shares at least two of its
+		// trigrams, so it contains two adjacent letters of the term
+		seen := make(map[string]bool)
+		for i := 0; i+2 <= len(runes); i++ {
+			pair := string(runes[i : i+2])
+			if !seen[pair] {
+				seen[pair] = true
+				conditions = append(conditions, `LOWER(computer_name) LIKE ? ESCAPE '\'`)
+				args = append(args, "%"+escapeLike(pair)+"%")
+			}
+		}
+	}
+	if term.mac != "" {
+		conditions = append(conditions, "REPLACE(mac_address, ':', '') LIKE ?")
+		args = append(args, "%"+term.mac+"%")
+	}
+	switch {
+	case term.addr.IsValid():
+		conditions = append(conditions, "ip_address = ?")
+		args = append(args, term.addr.String())
+	case term.prefix.IsValid():
+		network := ipNetworkCondition(db, term.prefix)
+		conditions = append(conditions, network.SQL)
+		args = append(args, network.Vars...)
+	case term.ipPrefix:
+		conditions = append(conditions, `ip_address LIKE ? ESCAPE '\'`)
+		args = append(args, escapeLike(term.text)+"%")
+	}
+	return gorm.Expr("("+strings.Join(conditions, " OR ")+")", args...), true
+}
+
+// searchRank rates in SQL how well a computer matches a term, weighed like
+// scoreComputer though only roughly: names are matched as a whole, not by
+// word. On PostgreSQL full-text rank and trigram similarity add to it.
+func searchRank(db *gorm.DB, term searchTerm) (string, []interface{}) {
+	text := escapeLike(term.text)
+	rank := fmt.Sprintf(`CASE WHEN LOWER(computer_name) = ? THEN %d WHEN LOWER(computer_name) LIKE ? ESCAPE '\' THEN %d `+
+		`WHEN LOWER(computer_name) LIKE ? ESCAPE '\' THEN %d ELSE 0 END`+
+		` + CASE WHEN employee_abbreviation = ? THEN %d ELSE 0 END`+
+		` + CASE WHEN LOWER(description) LIKE ? ESCAPE '\' THEN %d ELSE 0 END`,
+		scoreName, scoreNamePrefix, scoreNameContain, scoreEmployee, scoreDescription)
+	args := []interface{}{term.text, text + "%", "%" + text + "%", term.text, "%" + text + "%"}
+
+	if db.Dialector.Name() == "postgres" {
+		rank += fmt.Sprintf(" + ts_rank(to_tsvector('simple', computer_name || ' ' || COALESCE(description, '')), plainto_tsquery('simple', ?))"+
+			" + %d * word_similarity(?, LOWER(computer_name))", scoreFuzzy)
+		args = append(args, term.text, term.text)
+	}
+	if term.mac != "" {
+		rank += fmt.Sprintf(" + CASE WHEN REPLACE(mac_address, ':', '') = ? THEN %d WHEN REPLACE(mac_address, ':', '') LIKE ? THEN %d ELSE 0 END",
+			scoreExact, scoreMACFragment)
+		args = append(args, term.mac, "%"+term.mac+"%")
+	}
+	if term.addr.IsValid() {
+		rank += fmt.Sprintf(" + CASE WHEN ip_address = ? THEN %d ELSE 0 END", scoreExact)
+		args = append(args, term.addr.String())
+	}
+	return rank, args
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// hasWordPrefix reports whether a word starts with prefix
+func hasWordPrefix(words []string, prefix string) bool {
+	for _, word := range words {
+		if strings.HasPrefix(word, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// bestSimilarity returns the highest trigram similarity of term to a word
+func bestSimilarity(words []string, term string) float64 {
+	best := 0.0
+	termTrigrams := trigrams(term)
+	for _, word := range words {
+		if similarity := trigramSimilarity(termTrigrams, trigrams(word)); similarity > best {
+			best = similarity
+		}
+	}
+	return best
+}
+
+// trigrams returns the trigrams of a word padded like pg_trgm does, with two
+// spaces in front and one behind
+func trigrams(word string) map[string]bool {
+	runes := []rune("  " + word + " ")
+	set := make(map[string]bool, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		set[string(runes[i:i+3])] = true
+	}
+	return set
+}
+
+// trigramSimilarity is the share of trigrams two words have in common
+func trigramSimilarity(a, b map[string]bool) float64 {
+	shared := 0
+	for trigram := range a {
+		if b[trigram] {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// isIPPrefix reports whether s can be the start of an IPv4 address, such as
+// 10.20., or of an IPv6 address, such as 2001:db8:
+func isIPPrefix(s string) bool {
+	if strings.Contains(s, ".") {
+		return isDottedDecimal(s)
+	}
+	return strings.Contains(s, ":") && strings.Trim(s, "0123456789abcdef:") == ""
+}
+
+// isDottedDecimal reports whether s consists of up to four groups of up to
+// three digits separated by dots
+func isDottedDecimal(s string) bool {
+	groups := strings.Split(s, ".")
+	if len(groups) > 4 {
+		return false
+	}
+	for _, group := range groups {
+		if len(group) > 3 || strings.Trim(group, "0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// isASCII reports whether s consists of ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// isHex reports whether s consists of hex digits
+func isHex(s string) bool {
+	return strings.Trim(s, "0123456789abcdef") == ""
+}
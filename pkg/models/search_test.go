@@ -0,0 +1,124 @@
+package models_test
+
+import (
+	"context"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"testing"
+)
+
+func seedSearchComputers(t *testing.T, repo models.ComputerRepository) {
+	t.Helper()
+
+	abbr := "abc"
+	computers := []*models.Computer{
+		{MACAddress: "00:11:22:aa:bb:01", ComputerName: "Dell Latitude 5420", IPAddress: "10.20.1.15", Description: "Finance laptop"},
+		{MACAddress: "00:11:22:aa:bb:02", ComputerName: "Latitude", IPAddress: "10.20.2.7", EmployeeAbbreviation: &abbr},
+		{MACAddress: "00:11:22:cc:dd:03", ComputerName: "MacBook Pro", IPAddress: "192.168.1.20", Description: "Spare for the latitude fleet"},
+		{MACAddress: "00:11:22:cc:dd:04", ComputerName: "ThinkPad X1", IPAddress: "2001:db8::4", EmployeeAbbreviation: &abbr},
+		{MACAddress: "00:11:22:ee:ff:05", ComputerName: "Büro WS_01", IPAddress: "10.30.0.5"},
+	}
+	for _, computer := range computers {
+		if err := repo.Create(context.Background(), computer); err != nil {
+			t.Fatalf("Failed to create computer: %v", err)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+	seedSearchComputers(t, repo)
+
+	tests := []struct {
+		name  string
+		query string
+		ids   []uint
+	}{
+		{"exact name ranks first", "latitude", []uint{2, 1, 3}},
+		{"misspelt name", "lattitude", []uint{1, 2}},
+		{"every word must match", "Latitude finance", []uint{1}},
+		{"employee", "abc", []uint{2, 4}},
+		{"MAC fragment in any spelling", "AA-BB", []uint{1, 2}},
+		{"complete MAC address", "0011.22cc.dd03", []uint{3}},
+		{"IP prefix", "10.20.", []uint{1, 2}},
+		{"CIDR", "10.20.2.0/24", []uint{2}},
+		{"IPv6 CIDR", "2001:db8::/32", []uint{4}},
+		{"complete IP address", "192.168.1.20", []uint{3}},
+		{"non-ASCII letters", "BÜRO", []uint{5}},
+		{"wildcards are literal", "s_", []uint{5}},
+		{"no match", "chromebook", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := repo.Search(context.Background(), models.ComputerSearchOptions{Query: tt.query, Limit: 10})
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			var ids []uint
+			for _, hit := range hits {
+				ids = append(ids, hit.ID)
+			}
+			if len(ids) != len(tt.ids) {
+				t.Fatalf("Expected computers %v, got %v", tt.ids, ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.ids[i] {
+					t.Fatalf("Expected computers %v, got %v", tt.ids, ids)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchReportsMatchesAndLimit(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+	seedSearchComputers(t, repo)
+
+	hits, err := repo.Search(context.Background(), models.ComputerSearchOptions{Query: "latitude", Limit: 1})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ID != 2 {
+		t.Fatalf("Expected only the best hit, got %+v", hits)
+	}
+	if hits[0].Score != models.ScoreName || len(hits[0].Matches) != 1 || hits[0].Matches[0] != "computer_name" {
+		t.Errorf("Expected an exact name match, got score %v and matches %v", hits[0].Score, hits[0].Matches)
+	}
+
+	// Deleted computers are only found on request
+	repo.Delete(context.Background(), 2, 0)
+	hits, _ = repo.Search(context.Background(), models.ComputerSearchOptions{Query: "latitude", Limit: 10})
+	if len(hits) != 2 {
+		t.Errorf("Expected the deleted computer to be skipped, got %d hits", len(hits))
+	}
+	hits, _ = repo.Search(context.Background(), models.ComputerSearchOptions{Query: "latitude", Limit: 10, IncludeDeleted: true})
+	if len(hits) != 3 {
+		t.Errorf("Expected the deleted computer to be found, got %d hits", len(hits))
+	}
+}
+
+func TestSearchRanksCandidatesBeforeCappingThem(t *testing.T) {
+	repo := models.NewComputerRepository(newTestDB(t))
+
+	// Weak matches by description come first, the exact name match last
+	for i := 0; i < 15; i++ {
+		computer := &models.Computer{MACAddress: fmt.Sprintf("00:11:22:33:44:%02x", i), ComputerName: fmt.Sprintf("PC %d", i),
+			IPAddress: "10.0.0.1", Description: "Spare for the latitude fleet"}
+		if err := repo.Create(context.Background(), computer); err != nil {
+			t.Fatalf("Failed to create computer: %v", err)
+		}
+	}
+	best := &models.Computer{MACAddress: "00:11:22:33:44:ff", ComputerName: "Latitude", IPAddress: "10.0.0.2"}
+	if err := repo.Create(context.Background(), best); err != nil {
+		t.Fatalf("Failed to create computer: %v", err)
+	}
+
+	hits, err := repo.Search(context.Background(), models.ComputerSearchOptions{Query: "latitude", Limit: 1})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ID != best.ID {
+		t.Errorf("Expected the exact name match among the capped candidates, got %+v", hits)
+	}
+}
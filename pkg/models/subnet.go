@@ -0,0 +1,297 @@
+package models
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+	"sort"
+	"time"
+)
+
+// AutoIPAddress is the IP address of a computer that is to be given the next
+// free address of its subnet
+const AutoIPAddress = "auto"
+
+// IPRange is an inclusive range of IP addresses. A range of one address may
+// leave End empty.
+type IPRange struct {
+	Start string `json:"start"`
+	End   string `json:"end,omitempty"`
+}
+
+// IPRanges is a list of IP ranges stored as JSON text
+type IPRanges []IPRange
+
+// Value implements driver.Valuer
+func (r IPRanges) Value() (driver.Value, error) {
+	if r == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (r *IPRanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*r = IPRanges{}
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	case []byte:
+		return json.Unmarshal(v, r)
+	}
+	return fmt.Errorf("cannot scan %T into IPRanges", value)
+}
+
+// Subnet is a network whose addresses are managed: computers can be given its
+// next free address, which is neither the gateway nor reserved. Subnets do not
+// overlap.
+type Subnet struct {
+	ID             uint     `json:"id" gorm:"primaryKey;autoIncrement"`
+	CIDR           string   `json:"cidr" gorm:"column:cidr;not null;size:49;uniqueIndex:idx_subnets_cidr"`
+	Gateway        string   `json:"gateway,omitempty" gorm:"size:45"`
+	ReservedRanges IPRanges `json:"reserved_ranges" gorm:"not null;type:text"`
+	Description    string   `json:"description" gorm:"size:500"`
+	// FirstIPNumber and LastIPNumber are the IP numbers of the first and last
+	// address, see IPNumber. They are set on every write, to find the subnet
+	// of an address.
+	FirstIPNumber string    `json:"-" gorm:"not null;size:33;index:idx_subnets_ip_numbers,priority:1"`
+	LastIPNumber  string    `json:"-" gorm:"not null;size:33;index:idx_subnets_ip_numbers,priority:2"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// SubnetUtilization counts the addresses of a subnet. Reserved addresses are
+// the network and broadcast address, the gateway and the reserved ranges; the
+// others are allocatable. Used counts the addresses held by computers,
+// including reserved ones, and Free the allocatable addresses no computer
+// holds. Counts are exact, as IPv6 subnets may have more than 2^64 addresses.
+type SubnetUtilization struct {
+	SubnetID    uint     `json:"subnet_id"`
+	CIDR        string   `json:"cidr"`
+	Addresses   *big.Int `json:"addresses"`
+	Reserved    *big.Int `json:"reserved"`
+	Allocatable *big.Int `json:"allocatable"`
+	Used        int      `json:"used"`
+	Free        *big.Int `json:"free"`
+	Computers   int64    `json:"computers"`
+	// Utilization is the share of allocatable addresses in use, in percent
+	Utilization float64 `json:"utilization"`
+}
+
+// SubnetRepository interface for subnet database operations
+type SubnetRepository interface {
+	Create(ctx context.Context, subnet *Subnet) error
+	GetAll(ctx context.Context) ([]Subnet, error)
+	GetByID(ctx context.Context, id uint) (*Subnet, error)
+	// GetOverlapping returns the subnets sharing an address with network
+	GetOverlapping(ctx context.Context, network netip.Prefix) ([]Subnet, error)
+	Update(ctx context.Context, subnet *Subnet) error
+	Delete(ctx context.Context, id uint) error
+
+	// Transaction runs fn with a repository bound to a single database
+	// transaction that no other Transaction writes subnets alongside, so
+	// checks for overlaps hold until the write is committed
+	Transaction(ctx context.Context, fn func(tx SubnetRepository) error) error
+}
+
+// SubnetService interface for subnet business logic
+type SubnetService interface {
+	CreateSubnet(ctx context.Context, subnet *Subnet) error
+	GetAllSubnets(ctx context.Context) ([]Subnet, error)
+	GetSubnet(ctx context.Context, id uint) (*Subnet, error)
+	UpdateSubnet(ctx context.Context, subnet *Subnet) error
+	DeleteSubnet(ctx context.Context, id uint) error
+	GetSubnetUtilization(ctx context.Context, id uint) (*SubnetUtilization, error)
+}
+
+// Prefix returns the network of a subnet, or an invalid prefix if its CIDR is
+// invalid
+func (s *Subnet) Prefix() netip.Prefix {
+	prefix, err := ParseIPNetwork(s.CIDR)
+	if err != nil {
+		return netip.Prefix{}
+	}
+	return prefix
+}
+
+// addrRange is an inclusive range of addresses of one family
+type addrRange struct {
+	from, to netip.Addr
+}
+
+// reservedRanges returns the addresses of a subnet that are not allocatable,
+// sorted and merged: the network address, the broadcast address of IPv4
+// subnets or the subnet-router anycast address of IPv6 ones, the gateway and
+// the reserved ranges. Point-to-point /31 and /127 subnets and single
+// addresses reserve neither the network nor the broadcast address.
+func (s *Subnet) reservedRanges() []addrRange {
+	prefix := s.Prefix()
+	first, last := prefixRange(prefix)
+
+	var ranges []addrRange
+	if hostBits := first.BitLen() - prefix.Bits(); hostBits >= 2 {
+		ranges = append(ranges, addrRange{first, first})
+		if first.Is4() {
+			ranges = append(ranges, addrRange{last, last})
+		}
+	}
+	if gateway, err := netip.ParseAddr(s.Gateway); err == nil {
+		ranges = append(ranges, addrRange{gateway.Unmap(), gateway.Unmap()})
+	}
+	for _, r := range s.ReservedRanges {
+		if from, to, err := r.Addrs(); err == nil {
+			ranges = append(ranges, addrRange{from, to})
+		}
+	}
+	return mergeRanges(ranges)
+}
+
+// IsReserved reports whether an address of a subnet is not allocatable, see
+// SubnetUtilization
+func (s *Subnet) IsReserved(addr netip.Addr) bool {
+	return inRanges(s.reservedRanges(), addr.Unmap())
+}
+
+// Addrs parses the start and end of a range
+func (r IPRange) Addrs() (from, to netip.Addr, err error) {
+	from, err = netip.ParseAddr(r.Start)
+	if err != nil {
+		return netip.Addr{}, netip.Addr{}, fmt.Errorf("invalid start address %q", r.Start)
+	}
+	to = from
+	if r.End != "" {
+		if to, err = netip.ParseAddr(r.End); err != nil {
+			return netip.Addr{}, netip.Addr{}, fmt.Errorf("invalid end address %q", r.End)
+		}
+	}
+	from, to = from.Unmap(), to.Unmap()
+	if from.BitLen() != to.BitLen() || to.Less(from) {
+		return netip.Addr{}, netip.Addr{}, fmt.Errorf("end address %s is before start address %s", to, from)
+	}
+	return from, to, nil
+}
+
+// NextFreeAddress returns the lowest address of a subnet that is neither
+// reserved nor used, or false if there is none
+func (s *Subnet) NextFreeAddress(used []netip.Addr) (netip.Addr, bool) {
+	first, last := prefixRange(s.Prefix())
+	taken := s.reservedRanges()
+	for _, addr := range used {
+		taken = append(taken, addrRange{addr, addr})
+	}
+
+	candidate := first
+	for _, r := range mergeRanges(taken) {
+		if candidate.Less(r.from) {
+			break
+		}
+		if r.to.Less(candidate) {
+			continue
+		}
+		// The range may end the address space
+		if candidate = r.to.Next(); !candidate.IsValid() {
+			return netip.Addr{}, false
+		}
+	}
+	if !first.IsValid() || last.Less(candidate) {
+		return netip.Addr{}, false
+	}
+	return candidate, true
+}
+
+// Utilization counts the addresses of a subnet, given the distinct addresses
+// within it held by computers and the number of those computers
+func (s *Subnet) Utilization(used []netip.Addr, computers int64) *SubnetUtilization {
+	prefix := s.Prefix()
+	first, last := prefixRange(prefix)
+	reserved := s.reservedRanges()
+
+	utilization := &SubnetUtilization{
+		SubnetID:  s.ID,
+		CIDR:      s.CIDR,
+		Addresses: rangeSize(addrRange{first, last}),
+		Reserved:  new(big.Int),
+		Used:      len(used),
+		Computers: computers,
+	}
+	for _, r := range reserved {
+		utilization.Reserved.Add(utilization.Reserved, rangeSize(r))
+	}
+	utilization.Allocatable = new(big.Int).Sub(utilization.Addresses, utilization.Reserved)
+
+	allocated := 0
+	for _, addr := range used {
+		if !inRanges(reserved, addr) {
+			allocated++
+		}
+	}
+	utilization.Free = new(big.Int).Sub(utilization.Allocatable, big.NewInt(int64(allocated)))
+	if utilization.Allocatable.Sign() > 0 {
+		share, _ := new(big.Float).Quo(big.NewFloat(float64(allocated)), new(big.Float).SetInt(utilization.Allocatable)).Float64()
+		utilization.Utilization = math.Round(share*10000) / 100
+	}
+	return utilization
+}
+
+// prefixRange returns the first and last address of a network
+func prefixRange(prefix netip.Prefix) (first, last netip.Addr) {
+	if !prefix.IsValid() {
+		return netip.Addr{}, netip.Addr{}
+	}
+	first = prefix.Masked().Addr()
+	b := first.AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	last, _ = netip.AddrFromSlice(b)
+	return first, last
+}
+
+// mergeRanges sorts ranges and merges the ones that overlap or adjoin
+func mergeRanges(ranges []addrRange) []addrRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].from.Less(ranges[j].from) })
+
+	var merged []addrRange
+	for _, r := range ranges {
+		if n := len(merged); n > 0 {
+			prev := &merged[n-1]
+			if next := prev.to.Next(); !prev.to.Less(r.from) || next == r.from {
+				if prev.to.Less(r.to) {
+					prev.to = r.to
+				}
+				continue
+			}
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// inRanges reports whether addr lies within one of ranges
+func inRanges(ranges []addrRange, addr netip.Addr) bool {
+	for _, r := range ranges {
+		if !addr.Less(r.from) && !r.to.Less(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// rangeSize returns the number of addresses in a range
+func rangeSize(r addrRange) *big.Int {
+	if !r.from.IsValid() {
+		return new(big.Int)
+	}
+	size := new(big.Int).Sub(new(big.Int).SetBytes(r.to.AsSlice()), new(big.Int).SetBytes(r.from.AsSlice()))
+	return size.Add(size, big.NewInt(1))
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"context"
+	"net/netip"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type subnetRepository struct {
+	db *gorm.DB
+}
+
+// NewSubnetRepository creates a new subnet repository
+func NewSubnetRepository(db *gorm.DB) SubnetRepository {
+	return &subnetRepository{db: db}
+}
+
+// Create adds a new subnet
+func (r *subnetRepository) Create(ctx context.Context, subnet *Subnet) error {
+	setSubnetIPNumbers(subnet)
+	return r.db.WithContext(ctx).Create(subnet).Error
+}
+
+// GetAll retrieves all subnets in address order
+func (r *subnetRepository) GetAll(ctx context.Context) ([]Subnet, error) {
+	var subnets []Subnet
+	err := r.db.WithContext(ctx).Order("first_ip_number").Find(&subnets).Error
+	return subnets, err
+}
+
+// GetByID retrieves a subnet by ID
+func (r *subnetRepository) GetByID(ctx context.Context, id uint) (*Subnet, error) {
+	var subnet Subnet
+	err := r.db.WithContext(ctx).First(&subnet, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subnet, nil
+}
+
+// GetOverlapping retrieves the subnets whose address range meets the one of network
+func (r *subnetRepository) GetOverlapping(ctx context.Context, network netip.Prefix) ([]Subnet, error) {
+	first, last := ipNumberRange(network)
+	var subnets []Subnet
+	err := r.db.WithContext(ctx).
+		Where("first_ip_number <= ? AND last_ip_number >= ?", last, first).
+		Order("first_ip_number").
+		Find(&subnets).Error
+	return subnets, err
+}
+
+// Update replaces a subnet
+func (r *subnetRepository) Update(ctx context.Context, subnet *Subnet) error {
+	setSubnetIPNumbers(subnet)
+	return r.db.WithContext(ctx).Model(subnet).
+		Select("cidr", "gateway", "reserved_ranges", "description", "first_ip_number", "last_ip_number", "updated_at").
+		Updates(subnet).Error
+}
+
+// Delete removes a subnet. Computers keep their addresses.
+func (r *subnetRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&Subnet{}, id).Error
+}
+
+// Transaction runs fn inside a database transaction. PostgreSQL locks the
+// subnets table against other writers, but not against readers or the
+// allocations locking a subnet row; SQLite transactions are already
+// serialised, as they begin immediately.
+func (r *subnetRepository) Transaction(ctx context.Context, fn func(tx SubnetRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("LOCK TABLE subnets IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+				return err
+			}
+		}
+		return fn(&subnetRepository{db: tx})
+	})
+}
+
+// setSubnetIPNumbers sets the IP numbers of the first and last address of a subnet
+func setSubnetIPNumbers(subnet *Subnet) {
+	if subnet.ReservedRanges == nil {
+		subnet.ReservedRanges = IPRanges{}
+	}
+	subnet.FirstIPNumber, subnet.LastIPNumber = "", ""
+	if prefix := subnet.Prefix(); prefix.IsValid() {
+		subnet.FirstIPNumber, subnet.LastIPNumber = ipNumberRange(prefix)
+	}
+}
+
+// LockSubnet retrieves a subnet with SELECT ... FOR UPDATE
+func (r *computerRepository) LockSubnet(ctx context.Context, id uint) (*Subnet, error) {
+	var subnet Subnet
+	err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&subnet, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subnet, nil
+}
+
+// GetSubnetByIPAddress retrieves the subnet an address lies within
+func (r *computerRepository) GetSubnetByIPAddress(ctx context.Context, ip string) (*Subnet, error) {
+	number := IPNumber(ip)
+	if number == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var subnet Subnet
+	err := r.db.WithContext(ctx).
+		Where("first_ip_number <= ? AND last_ip_number >= ?", number, number).
+		First(&subnet).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subnet, nil
+}
+
+// GetIPAddressesInNetwork retrieves the distinct addresses within network held
+// by computers that are not deleted, in address order, and the number of those
+// computers
+func (r *computerRepository) GetIPAddressesInNetwork(ctx context.Context, network netip.Prefix) ([]netip.Addr, int64, error) {
+	var rows []struct {
+		IPAddress string
+		Computers int64
+	}
+	err := r.db.WithContext(ctx).Model(&Computer{}).
+		Select("ip_address, COUNT(*) AS computers").
+		Where(ipNetworkCondition(r.db, network)).
+		Group("ip_address, ip_number").
+		Order("ip_number").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	addresses := make([]netip.Addr, 0, len(rows))
+	var computers int64
+	for _, row := range rows {
+		addr, err := netip.ParseAddr(row.IPAddress)
+		if err != nil {
+			continue
+		}
+		addresses = append(addresses, addr.Unmap())
+		computers += row.Computers
+	}
+	return addresses, computers, nil
+}
@@ -0,0 +1,126 @@
+package models_test
+
+import (
+	"context"
+	"greenbone-case-study/pkg/models"
+	"net/netip"
+	"testing"
+)
+
+func parseAddrs(addresses ...string) []netip.Addr {
+	addrs := make([]netip.Addr, len(addresses))
+	for i, ip := range addresses {
+		addrs[i] = netip.MustParseAddr(ip)
+	}
+	return addrs
+}
+
+func TestSubnetNextFreeAddress(t *testing.T) {
+	tests := []struct {
+		name   string
+		subnet models.Subnet
+		used   []netip.Addr
+		want   string
+	}{
+		{"skips network address and gateway", models.Subnet{CIDR: "10.20.0.0/24", Gateway: "10.20.0.1"}, nil, "10.20.0.2"},
+		{"skips reserved ranges and used addresses",
+			models.Subnet{CIDR: "10.20.0.0/24", Gateway: "10.20.0.1", ReservedRanges: models.IPRanges{{Start: "10.20.0.2", End: "10.20.0.9"}}},
+			parseAddrs("10.20.0.10", "10.20.0.11", "10.20.0.13"), "10.20.0.12"},
+		{"fills gaps first", models.Subnet{CIDR: "10.20.0.0/24"}, parseAddrs("10.20.0.1", "10.20.0.3"), "10.20.0.2"},
+		{"skips broadcast address", models.Subnet{CIDR: "10.20.0.0/30", Gateway: "10.20.0.1"}, parseAddrs("10.20.0.2"), ""},
+		{"point-to-point", models.Subnet{CIDR: "10.20.0.0/31"}, parseAddrs("10.20.0.0"), "10.20.0.1"},
+		{"single address", models.Subnet{CIDR: "10.20.0.7/32"}, nil, "10.20.0.7"},
+		{"IPv6 has no broadcast address", models.Subnet{CIDR: "2001:db8::/126", Gateway: "2001:db8::1"}, parseAddrs("2001:db8::2"), "2001:db8::3"},
+		{"end of the address space", models.Subnet{CIDR: "255.255.255.254/31"}, parseAddrs("255.255.255.254", "255.255.255.255"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, ok := tt.subnet.NextFreeAddress(tt.used)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("Expected no free address, got %s", addr)
+				}
+				return
+			}
+			if !ok || addr.String() != tt.want {
+				t.Errorf("Expected %s, got %s (%v)", tt.want, addr, ok)
+			}
+		})
+	}
+}
+
+func TestSubnetUtilization(t *testing.T) {
+	subnet := models.Subnet{
+		ID:             1,
+		CIDR:           "10.20.0.0/24",
+		Gateway:        "10.20.0.1",
+		ReservedRanges: models.IPRanges{{Start: "10.20.0.2", End: "10.20.0.9"}, {Start: "10.20.0.200"}},
+	}
+
+	// A reserved address in use does not count as allocated
+	utilization := subnet.Utilization(parseAddrs("10.20.0.5", "10.20.0.10", "10.20.0.11"), 4)
+	if utilization.Addresses.Int64() != 256 || utilization.Reserved.Int64() != 12 || utilization.Allocatable.Int64() != 244 {
+		t.Errorf("Unexpected address counts %+v", utilization)
+	}
+	if utilization.Used != 3 || utilization.Computers != 4 || utilization.Free.Int64() != 242 || utilization.Utilization != 0.82 {
+		t.Errorf("Unexpected usage %+v", utilization)
+	}
+
+	// IPv6 subnets are counted exactly
+	utilization = (&models.Subnet{CIDR: "2001:db8::/64"}).Utilization(nil, 0)
+	if utilization.Addresses.String() != "18446744073709551616" || utilization.Free.String() != "18446744073709551615" {
+		t.Errorf("Unexpected IPv6 counts %+v", utilization)
+	}
+}
+
+func TestSubnetRepository(t *testing.T) {
+	database := newTestDB(t)
+	repo := models.NewSubnetRepository(database)
+	computers := models.NewComputerRepository(database)
+	ctx := context.Background()
+
+	subnet := &models.Subnet{CIDR: "10.20.0.0/24", Gateway: "10.20.0.1", ReservedRanges: models.IPRanges{{Start: "10.20.0.2", End: "10.20.0.9"}}}
+	if err := repo.Create(ctx, subnet); err != nil {
+		t.Fatalf("Failed to create subnet: %v", err)
+	}
+	if err := repo.Create(ctx, &models.Subnet{CIDR: "2001:db8::/64"}); err != nil {
+		t.Fatalf("Failed to create subnet: %v", err)
+	}
+
+	stored, err := repo.GetByID(ctx, subnet.ID)
+	if err != nil || len(stored.ReservedRanges) != 1 || stored.ReservedRanges[0].End != "10.20.0.9" {
+		t.Fatalf("Expected the reserved ranges to be stored, got %+v, %v", stored, err)
+	}
+
+	overlapping, _ := repo.GetOverlapping(ctx, netip.MustParsePrefix("10.0.0.0/8"))
+	if len(overlapping) != 1 || overlapping[0].ID != subnet.ID {
+		t.Errorf("Expected the enclosed subnet to overlap, got %+v", overlapping)
+	}
+	overlapping, _ = repo.GetOverlapping(ctx, netip.MustParsePrefix("10.20.0.128/25"))
+	if len(overlapping) != 1 {
+		t.Errorf("Expected the enclosing subnet to overlap, got %+v", overlapping)
+	}
+	overlapping, _ = repo.GetOverlapping(ctx, netip.MustParsePrefix("10.20.1.0/24"))
+	if len(overlapping) != 0 {
+		t.Errorf("Expected no overlap with the next subnet, got %+v", overlapping)
+	}
+
+	if found, err := computers.GetSubnetByIPAddress(ctx, "2001:db8::42"); err != nil || found.CIDR != "2001:db8::/64" {
+		t.Errorf("Expected the IPv6 subnet, got %+v, %v", found, err)
+	}
+	if _, err := computers.GetSubnetByIPAddress(ctx, "10.20.1.1"); err == nil {
+		t.Error("Expected no subnet for an unmanaged address")
+	}
+
+	// Distinct addresses in order, deleted computers excluded
+	seedNetworkComputers(t, computers, "10.20.0.20", "10.20.0.10", "10.20.0.20", "10.20.1.5", "10.20.0.30")
+	computers.Delete(ctx, 5, 0)
+	used, count, err := computers.GetIPAddressesInNetwork(ctx, subnet.Prefix())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(used) != 2 || used[0].String() != "10.20.0.10" || used[1].String() != "10.20.0.20" || count != 3 {
+		t.Errorf("Unexpected addresses %v of %d computers", used, count)
+	}
+}
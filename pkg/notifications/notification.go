@@ -5,9 +5,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"greenbone-case-study/pkg/metrics"
+	"greenbone-case-study/pkg/tracing"
+	"log/slog"
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Notification represents a notification message
@@ -27,7 +34,7 @@ type NotificationClient interface {
 type httpNotificationClient struct {
 	client  *http.Client
 	baseURL string
-	logger  *log.Logger
+	logger  *slog.Logger
 }
 
 // NewNotificationClient creates a new HTTP notification client
@@ -37,7 +44,7 @@ func NewNotificationClient(baseURL string) NotificationClient {
 			Timeout: 10 * time.Second,
 		},
 		baseURL: baseURL,
-		logger:  log.New(log.Writer(), "[NOTIFICATION] ", log.LstdFlags),
+		logger:  slog.Default().With("component", "notifications"),
 	}
 }
 
@@ -46,8 +53,13 @@ func (c *httpNotificationClient) SendNotification(notification Notification) err
 	return c.SendNotificationWithContext(context.Background(), notification)
 }
 
-// SendNotificationWithContext sends a notification with context and retry logic
-func (c *httpNotificationClient) SendNotificationWithContext(ctx context.Context, notification Notification) error {
+// SendNotificationWithContext sends a notification with context and retry logic.
+// Each attempt is traced and passes the trace context on in the traceparent header.
+func (c *httpNotificationClient) SendNotificationWithContext(ctx context.Context, notification Notification) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "notifications.Send",
+		trace.WithAttributes(attribute.String("employee.abbreviation", notification.EmployeeAbbreviation)))
+	defer func() { tracing.End(span, err) }()
+
 	const maxRetries = 3
 	const baseDelay = 1 * time.Second
 
@@ -61,8 +73,8 @@ func (c *httpNotificationClient) SendNotificationWithContext(ctx context.Context
 		return fmt.Errorf("failed to marshal notification: %w", err)
 	}
 
-	c.logger.Printf("Sending notification for employee %s (level: %s)",
-		notification.EmployeeAbbreviation, notification.Level)
+	c.logger.InfoContext(ctx, "Sending notification",
+		"employee", notification.EmployeeAbbreviation, "level", notification.Level)
 
 	var lastErr error
 	for attempt := 1; attempt <= maxRetries; attempt++ {
@@ -81,11 +93,13 @@ func (c *httpNotificationClient) SendNotificationWithContext(ctx context.Context
 		req.Header.Set("Content-Type", "application/json")
 		req.Header.Set("User-Agent", "Computer-Management-API/1.0")
 
-		resp, err := c.client.Do(req)
+		metrics.NotificationAttempts.Inc()
+		resp, err := c.do(ctx, req, attempt)
 		if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			resp.Body.Close()
-			c.logger.Printf("Notification sent successfully for employee %s (attempt %d)",
-				notification.EmployeeAbbreviation, attempt)
+			c.logger.InfoContext(ctx, "Notification sent",
+				"employee", notification.EmployeeAbbreviation, "attempt", attempt)
+			metrics.Notifications.WithLabelValues(metrics.ResultSent).Inc()
 			return nil
 		}
 
@@ -102,18 +116,63 @@ func (c *httpNotificationClient) SendNotificationWithContext(ctx context.Context
 			jitter := time.Duration(attempt*100) * time.Millisecond
 			totalDelay := delay + jitter
 
-			c.logger.Printf("Notification attempt %d failed for employee %s, retrying in %v: %v",
-				attempt, notification.EmployeeAbbreviation, totalDelay, lastErr)
+			c.logger.WarnContext(ctx, "Notification attempt failed, retrying",
+				"employee", notification.EmployeeAbbreviation, "attempt", attempt, "delay", totalDelay, "error", lastErr)
 
 			select {
 			case <-ctx.Done():
 				return fmt.Errorf("notification cancelled during retry: %w", ctx.Err())
 			case <-time.After(totalDelay):
 			}
+			metrics.NotificationRetries.Inc()
 		}
 	}
 
-	c.logger.Printf("Notification failed after %d attempts for employee %s: %v",
-		maxRetries, notification.EmployeeAbbreviation, lastErr)
+	c.logger.ErrorContext(ctx, "Notification failed",
+		"employee", notification.EmployeeAbbreviation, "attempts", maxRetries, "error", lastErr)
+	metrics.Notifications.WithLabelValues(metrics.ResultFailed).Inc()
 	return fmt.Errorf("notification failed after %d attempts: %w", maxRetries, lastErr)
 }
+
+// do sends one attempt in a client span whose trace context is added to req
+func (c *httpNotificationClient) do(ctx context.Context, req *http.Request, attempt int) (*http.Response, error) {
+	ctx, span := tracing.Tracer().Start(ctx, req.Method+" /api/notify",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(req.URL.String()),
+			attribute.Int("notification.attempt", attempt),
+		))
+
+	tracing.Inject(ctx, req.Header)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		tracing.End(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	span.End()
+	return resp, nil
+}
+
+// Probe checks that the notification service answers a GET of url, such as
+// its health endpoint, with a 2xx status
+func Probe(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -3,11 +3,18 @@ package notifications
 import (
 	"context"
 	"encoding/json"
+	"greenbone-case-study/pkg/metrics"
+	"greenbone-case-study/pkg/tracing"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestNotificationClient_SendNotification(t *testing.T) {
@@ -110,11 +117,25 @@ func TestNotificationClient_SendNotificationMaxRetries(t *testing.T) {
 		Message:              "Test notification",
 	}
 
+	attemptsBefore := testutil.ToFloat64(metrics.NotificationAttempts)
+	retriesBefore := testutil.ToFloat64(metrics.NotificationRetries)
+	failedBefore := testutil.ToFloat64(metrics.Notifications.WithLabelValues(metrics.ResultFailed))
+
 	err := client.SendNotification(notification)
 	if err == nil {
 		t.Error("Expected error after max retries, got nil")
 	}
 
+	if got := testutil.ToFloat64(metrics.NotificationAttempts) - attemptsBefore; got != 3 {
+		t.Errorf("Expected 3 attempts to be counted, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.NotificationRetries) - retriesBefore; got != 2 {
+		t.Errorf("Expected 2 retries to be counted, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.Notifications.WithLabelValues(metrics.ResultFailed)) - failedBefore; got != 1 {
+		t.Errorf("Expected 1 failed notification to be counted, got %v", got)
+	}
+
 	if attempts != 3 {
 		t.Errorf("Expected 3 attempts, got %d", attempts)
 	}
@@ -232,3 +253,42 @@ func TestNotificationClient_InvalidURL(t *testing.T) {
 		t.Error("Expected error for invalid URL, got nil")
 	}
 }
+
+func TestNotificationClient_PropagatesTraceContext(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "request")
+	err := NewNotificationClient(server.URL).SendNotificationWithContext(ctx, Notification{
+		Level:                "warning",
+		EmployeeAbbreviation: "abc",
+		Message:              "Test notification",
+	})
+	parent.End()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	traceID := parent.SpanContext().TraceID().String()
+	if !strings.HasPrefix(traceparent, "00-"+traceID+"-") {
+		t.Errorf("Expected traceparent in trace %s, got %q", traceID, traceparent)
+	}
+
+	// request, notifications.Send and the attempt, ended innermost first
+	spans := exporter.GetSpans()
+	if len(spans) != 3 || spans[0].Name != "POST /api/notify" || spans[1].Name != "notifications.Send" {
+		t.Fatalf("Unexpected spans %v", spans)
+	}
+	if !strings.Contains(traceparent, spans[0].SpanContext.SpanID().String()) {
+		t.Errorf("Expected the attempt span to be the remote parent, got %q", traceparent)
+	}
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"strings"
+	"time"
+)
+
+const (
+	// apiKeyPrefix marks API keys so they are recognisable, e.g. in leaked logs
+	apiKeyPrefix = "cmk_"
+	// apiKeyDisplayLength is how much of a key is kept as its display prefix
+	apiKeyDisplayLength = 12
+	// minAPIKeyLength is the shortest key EnsureKey accepts from configuration
+	minAPIKeyLength = 32
+	// apiKeyTouchInterval limits how often the last use of a key is written
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	// ErrAPIKeyNotFound is returned when the requested API key does not exist
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrAPIKeyExists is returned when creating an API key whose name is taken
+	ErrAPIKeyExists = errors.New("API key already exists")
+	// ErrAPIKeyRevoked is returned when rotating or revoking a revoked API key
+	ErrAPIKeyRevoked = errors.New("API key is revoked")
+	// ErrInvalidCredentials is returned when a presented key is unknown or revoked
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// WithPrincipal returns a context carrying the authenticated principal
+func WithPrincipal(ctx context.Context, principal *models.Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// PrincipalFromContext returns the authenticated principal stored in ctx, or
+// nil for anonymous requests
+func PrincipalFromContext(ctx context.Context) *models.Principal {
+	principal, _ := ctx.Value(principalKey).(*models.Principal)
+	return principal
+}
+
+type apiKeyService struct {
+	repo models.APIKeyRepository
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(repo models.APIKeyRepository) models.APIKeyService {
+	return &apiKeyService{repo: repo}
+}
+
+// CreateKey creates an API key with a new random secret and returns the secret
+func (s *apiKeyService) CreateKey(ctx context.Context, key *models.APIKey) (string, error) {
+	key.Name = strings.TrimSpace(key.Name)
+	if err := validateAPIKey(key); err != nil {
+		return "", err
+	}
+	if _, err := s.repo.GetByName(ctx, key.Name); err == nil {
+		return "", fmt.Errorf("%w: %s", ErrAPIKeyExists, key.Name)
+	}
+
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		return "", err
+	}
+
+	key.ID = 0
+	key.RotatedAt, key.LastUsedAt, key.RevokedAt = nil, nil, nil
+	setAPIKeySecret(key, secret, true)
+	if err := s.repo.Create(ctx, key); err != nil {
+		return "", fmt.Errorf("failed to create API key: %w", err)
+	}
+	return secret, nil
+}
+
+// GetAllKeys retrieves all API keys, including revoked ones
+func (s *apiKeyService) GetAllKeys(ctx context.Context) ([]models.APIKey, error) {
+	keys, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RotateKey replaces the secret of an API key and returns the new one. The
+// old secret stops working immediately.
+func (s *apiKeyService) RotateKey(ctx context.Context, id uint) (*models.APIKey, string, error) {
+	key, err := s.getActiveKey(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	key.RotatedAt = &now
+	setAPIKeySecret(key, secret, true)
+	if err := s.repo.Update(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("failed to rotate API key: %w", err)
+	}
+	return key, secret, nil
+}
+
+// RevokeKey permanently disables an API key. The key is kept for reference.
+func (s *apiKeyService) RevokeKey(ctx context.Context, id uint) (*models.APIKey, error) {
+	key, err := s.getActiveKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	key.RevokedAt = &now
+	if err := s.repo.Update(ctx, key); err != nil {
+		return nil, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return key, nil
+}
+
+// EnsureKey creates or replaces the API key with the given name so that it
+// has the given secret and role, e.g. to bootstrap the first admin key from
+// configuration. A revoked key of that name is reactivated.
+func (s *apiKeyService) EnsureKey(ctx context.Context, name, role, secret string) error {
+	if len(secret) < minAPIKeyLength {
+		return invalidField("secret", fmt.Sprintf("must be at least %d characters", minAPIKeyLength))
+	}
+
+	key, err := s.repo.GetByName(ctx, name)
+	if err != nil {
+		key = &models.APIKey{Name: name, Role: role}
+		if err := validateAPIKey(key); err != nil {
+			return err
+		}
+		setAPIKeySecret(key, secret, false)
+		if err := s.repo.Create(ctx, key); err != nil {
+			return fmt.Errorf("failed to create API key %s: %w", name, err)
+		}
+		return nil
+	}
+
+	key.Role = role
+	if err := validateAPIKey(key); err != nil {
+		return err
+	}
+	key.RevokedAt = nil
+	setAPIKeySecret(key, secret, false)
+	if err := s.repo.Update(ctx, key); err != nil {
+		return fmt.Errorf("failed to update API key %s: %w", name, err)
+	}
+	return nil
+}
+
+// Authenticate returns the principal of an active API key, or
+// ErrInvalidCredentials
+func (s *apiKeyService) Authenticate(ctx context.Context, secret string) (*models.Principal, error) {
+	key, err := s.repo.GetByHash(ctx, hashAPIKey(secret))
+	if err != nil || key.RevokedAt != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Last use is informational, so a failed write does not fail the request
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		_ = s.repo.TouchLastUsed(ctx, key.ID, now)
+	}
+
+	return &models.Principal{
+		Subject: key.Name,
+		Role:    key.Role,
+		Method:  models.AuthMethodAPIKey,
+	}, nil
+}
+
+// getActiveKey retrieves an API key that is not revoked
+func (s *apiKeyService) getActiveKey(ctx context.Context, id uint) (*models.APIKey, error) {
+	if id == 0 {
+		return nil, errInvalidID
+	}
+
+	key, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, notFound(ErrAPIKeyNotFound, err)
+	}
+	if key.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAPIKeyRevoked, key.Name)
+	}
+	return key, nil
+}
+
+// validateAPIKey validates API key input data and reports every invalid field
+func validateAPIKey(key *models.APIKey) error {
+	verr := &ValidationError{}
+	if key.Name == "" {
+		verr.Add("name", "is required")
+	} else if len(key.Name) > 100 {
+		verr.Add("name", "must be at most 100 characters")
+	}
+	if !models.ValidRole(key.Role) {
+		verr.Add("role", "must be viewer, operator or admin")
+	}
+	return verr.errorOrNil()
+}
+
+// newAPIKeySecret returns a new random API key
+func newAPIKeySecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return apiKeyPrefix + hex.EncodeToString(b), nil
+}
+
+// setAPIKeySecret stores the hash of secret in key. The display prefix is only
+// kept for generated secrets, whose first characters reveal little.
+func setAPIKeySecret(key *models.APIKey, secret string, generated bool) {
+	key.Hash = hashAPIKey(secret)
+	key.Prefix = ""
+	if generated {
+		key.Prefix = secret[:apiKeyDisplayLength]
+	}
+}
+
+// hashAPIKey returns the hex SHA-256 hash of an API key. Keys are random and
+// long, so a fast unsalted hash is enough and allows lookup by hash.
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
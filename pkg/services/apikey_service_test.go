@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"greenbone-case-study/pkg/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Mock API key repository for testing
+type mockAPIKeyRepository struct {
+	keys    map[uint]*models.APIKey
+	nextID  uint
+	touches int
+}
+
+func newMockAPIKeyRepository() *mockAPIKeyRepository {
+	return &mockAPIKeyRepository{
+		keys:   make(map[uint]*models.APIKey),
+		nextID: 1,
+	}
+}
+
+func (m *mockAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	key.ID = m.nextID
+	m.nextID++
+	stored := *key
+	m.keys[key.ID] = &stored
+	return nil
+}
+
+func (m *mockAPIKeyRepository) GetAll(ctx context.Context) ([]models.APIKey, error) {
+	var result []models.APIKey
+	for _, key := range m.keys {
+		result = append(result, *key)
+	}
+	return result, nil
+}
+
+func (m *mockAPIKeyRepository) GetByID(ctx context.Context, id uint) (*models.APIKey, error) {
+	key, exists := m.keys[id]
+	if !exists {
+		return nil, errors.New("record not found")
+	}
+	result := *key
+	return &result, nil
+}
+
+func (m *mockAPIKeyRepository) GetByName(ctx context.Context, name string) (*models.APIKey, error) {
+	for _, key := range m.keys {
+		if key.Name == name {
+			result := *key
+			return &result, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (m *mockAPIKeyRepository) GetByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	for _, key := range m.keys {
+		if key.Hash == hash {
+			result := *key
+			return &result, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (m *mockAPIKeyRepository) Update(ctx context.Context, key *models.APIKey) error {
+	stored := *key
+	m.keys[key.ID] = &stored
+	return nil
+}
+
+func (m *mockAPIKeyRepository) TouchLastUsed(ctx context.Context, id uint, at time.Time) error {
+	m.touches++
+	m.keys[id].LastUsedAt = &at
+	return nil
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	repo := newMockAPIKeyRepository()
+	service := NewAPIKeyService(repo)
+
+	key := &models.APIKey{Name: "dashboard", Role: models.RoleViewer}
+	secret, err := service.CreateKey(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.HasPrefix(secret, apiKeyPrefix) || key.Prefix != secret[:apiKeyDisplayLength] {
+		t.Errorf("Unexpected secret %q with prefix %q", secret, key.Prefix)
+	}
+	if key.Hash == secret || key.Hash != hashAPIKey(secret) {
+		t.Error("Expected only the hash of the secret to be stored")
+	}
+
+	principal, err := service.Authenticate(context.Background(), secret)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if principal.Subject != "dashboard" || principal.Role != models.RoleViewer || principal.Method != models.AuthMethodAPIKey {
+		t.Errorf("Unexpected principal %+v", principal)
+	}
+
+	// Last use is only written once per interval
+	service.Authenticate(context.Background(), secret)
+	if repo.touches != 1 {
+		t.Errorf("Expected 1 last-use update, got %d", repo.touches)
+	}
+
+	if _, err := service.CreateKey(context.Background(), &models.APIKey{Name: "dashboard", Role: models.RoleViewer}); !errors.Is(err, ErrAPIKeyExists) {
+		t.Errorf("Expected ErrAPIKeyExists, got: %v", err)
+	}
+
+	// Rotation invalidates the old secret
+	_, rotated, err := service.RotateKey(context.Background(), key.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := service.Authenticate(context.Background(), secret); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected old secret to be rejected, got: %v", err)
+	}
+	if _, err := service.Authenticate(context.Background(), rotated); err != nil {
+		t.Errorf("Expected rotated secret to work, got: %v", err)
+	}
+
+	if _, err := service.RevokeKey(context.Background(), key.ID); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := service.Authenticate(context.Background(), rotated); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected revoked key to be rejected, got: %v", err)
+	}
+	if _, _, err := service.RotateKey(context.Background(), key.ID); !errors.Is(err, ErrAPIKeyRevoked) {
+		t.Errorf("Expected ErrAPIKeyRevoked, got: %v", err)
+	}
+	if _, err := service.RevokeKey(context.Background(), 42); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("Expected ErrAPIKeyNotFound, got: %v", err)
+	}
+}
+
+func TestCreateAPIKeyValidation(t *testing.T) {
+	service := NewAPIKeyService(newMockAPIKeyRepository())
+
+	_, err := service.CreateKey(context.Background(), &models.APIKey{Name: " ", Role: "root"})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Errors) != 2 {
+		t.Errorf("Expected name and role to be invalid, got: %v", err)
+	}
+}
+
+func TestEnsureAPIKey(t *testing.T) {
+	repo := newMockAPIKeyRepository()
+	service := NewAPIKeyService(repo)
+
+	if err := service.EnsureKey(context.Background(), "bootstrap", models.RoleAdmin, "short"); err == nil {
+		t.Error("Expected short secrets to be rejected")
+	}
+
+	first := strings.Repeat("a", minAPIKeyLength)
+	second := strings.Repeat("b", minAPIKeyLength)
+	if err := service.EnsureKey(context.Background(), "bootstrap", models.RoleAdmin, first); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if err := service.EnsureKey(context.Background(), "bootstrap", models.RoleAdmin, second); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(repo.keys) != 1 {
+		t.Fatalf("Expected 1 key, got %d", len(repo.keys))
+	}
+	if repo.keys[1].Prefix != "" {
+		t.Errorf("Expected no display prefix for configured keys, got %q", repo.keys[1].Prefix)
+	}
+	if _, err := service.Authenticate(context.Background(), first); err == nil {
+		t.Error("Expected replaced secret to be rejected")
+	}
+	principal, err := service.Authenticate(context.Background(), second)
+	if err != nil || principal.Role != models.RoleAdmin {
+		t.Errorf("Expected admin principal, got %+v, %v", principal, err)
+	}
+}
+
+func TestActorFromPrincipal(t *testing.T) {
+	ctx := WithPrincipal(context.Background(), &models.Principal{Subject: "dashboard", Role: models.RoleViewer})
+	if actor := ActorFromContext(ctx); actor != "dashboard" {
+		t.Errorf("Expected actor dashboard, got %q", actor)
+	}
+	if actor := ActorFromContext(WithActor(ctx, "alice")); actor != "alice" {
+		t.Errorf("Expected explicit actor alice, got %q", actor)
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"reflect"
+)
+
+const (
+	defaultAuditListLimit = 100
+	maxAuditListLimit     = 500
+
+	// anonymousActor is recorded for changes made without an authenticated principal
+	anonymousActor = "anonymous"
+)
+
+// auditIgnoredFields are bookkeeping fields, and the subnet_id of a write
+// which is not stored, left out of audit diffs
+var auditIgnoredFields = map[string]bool{
+	"id":         true,
+	"version":    true,
+	"created_at": true,
+	"updated_at": true,
+	"subnet_id":  true,
+}
+
+type contextKey int
+
+const (
+	actorKey contextKey = iota
+	requestIDKey
+	principalKey
+)
+
+// WithActor returns a context carrying the actor recorded in the audit log
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// ActorFromContext returns the actor stored in ctx, else the subject of the
+// authenticated principal, or "anonymous"
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey).(string); ok && actor != "" {
+		return actor
+	}
+	if principal := PrincipalFromContext(ctx); principal != nil && principal.Subject != "" {
+		return principal.Subject
+	}
+	return anonymousActor
+}
+
+// WithRequestID returns a context carrying the ID of the current request
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, if any
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+type auditService struct {
+	repo models.AuditRepository
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(repo models.AuditRepository) models.AuditService {
+	return &auditService{repo: repo}
+}
+
+// GetAuditLog retrieves audit entries matching the given filters, newest first
+func (s *auditService) GetAuditLog(ctx context.Context, opts models.AuditQueryOptions) (*models.AuditPage, error) {
+	if err := validateLimitOffset(opts.Limit, opts.Offset); err != nil {
+		return nil, err
+	}
+	if opts.Limit == 0 {
+		opts.Limit = defaultAuditListLimit
+	}
+	if opts.Limit > maxAuditListLimit {
+		opts.Limit = maxAuditListLimit
+	}
+
+	entries, total, err := s.repo.GetAll(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+	return &models.AuditPage{Items: entries, Total: total, Limit: opts.Limit, Offset: opts.Offset}, nil
+}
+
+// GetComputerHistory retrieves the audit entries of a computer, including deleted ones
+func (s *auditService) GetComputerHistory(ctx context.Context, id uint, limit, offset int) (*models.AuditPage, error) {
+	if id == 0 {
+		return nil, errInvalidID
+	}
+
+	return s.GetAuditLog(ctx, models.AuditQueryOptions{
+		EntityType: models.AuditEntityComputer,
+		EntityID:   id,
+		Limit:      limit,
+		Offset:     offset,
+	})
+}
+
+// computerAuditEntry builds the audit entry for a computer change. before is
+// nil for creations and after is nil for deletions.
+func computerAuditEntry(ctx context.Context, action string, before, after *models.Computer) (*models.AuditEntry, error) {
+	changes, err := diffComputers(before, after)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build audit entry: %w", err)
+	}
+
+	entry := &models.AuditEntry{
+		EntityType: models.AuditEntityComputer,
+		Action:     action,
+		Actor:      ActorFromContext(ctx),
+		RequestID:  RequestIDFromContext(ctx),
+		Changes:    changes,
+	}
+	if after != nil {
+		entry.EntityID = after.ID
+	} else if before != nil {
+		entry.EntityID = before.ID
+	}
+	return entry, nil
+}
+
+// diffComputers returns the fields that differ between two versions of a
+// computer, keyed by their JSON names
+func diffComputers(before, after *models.Computer) (models.AuditChanges, error) {
+	from, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := models.AuditChanges{}
+	for field := range from {
+		if !reflect.DeepEqual(from[field], to[field]) {
+			changes[field] = models.FieldChange{From: from[field], To: to[field]}
+		}
+	}
+	for field := range to {
+		if _, seen := from[field]; !seen && to[field] != nil {
+			changes[field] = models.FieldChange{From: nil, To: to[field]}
+		}
+	}
+	return changes, nil
+}
+
+// auditFields returns the audited fields of a computer by JSON name
+func auditFields(computer *models.Computer) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if computer == nil {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(computer)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for field := range auditIgnoredFields {
+		delete(fields, field)
+	}
+	return fields, nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"greenbone-case-study/pkg/models"
+	"testing"
+)
+
+func TestComputerChangesAreAudited(t *testing.T) {
+	repo := newMockRepository()
+	repo.employees.employees["xyz"] = &models.Employee{Abbreviation: "xyz", Name: "xyz", Active: true}
+	service := NewComputerService(repo, repo.employees, repo.policies)
+
+	ctx := WithRequestID(WithActor(context.Background(), "alice"), "req-1")
+
+	computer := &models.Computer{MACAddress: "00:11:22:33:44:55", ComputerName: "Test", IPAddress: "10.0.0.1"}
+	if err := service.CreateComputer(ctx, computer); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	update := *computer
+	update.IPAddress = "10.0.0.2"
+	if err := service.UpdateComputer(ctx, &update); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	abbr := "xyz"
+	reassign := update
+	reassign.EmployeeAbbreviation = &abbr
+	if err := service.UpdateComputer(context.Background(), &reassign); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if err := service.DeleteComputer(ctx, computer.ID, 0); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(repo.audits) != 4 {
+		t.Fatalf("Expected 4 audit entries, got %d", len(repo.audits))
+	}
+
+	wantActions := []string{models.AuditCreate, models.AuditUpdate, models.AuditReassign, models.AuditDelete}
+	for i, entry := range repo.audits {
+		if entry.Action != wantActions[i] {
+			t.Errorf("Entry %d: expected action %s, got %s", i, wantActions[i], entry.Action)
+		}
+		if entry.EntityType != models.AuditEntityComputer || entry.EntityID != computer.ID {
+			t.Errorf("Entry %d: expected computer %d, got %s %d", i, computer.ID, entry.EntityType, entry.EntityID)
+		}
+	}
+
+	created := repo.audits[0]
+	if created.Actor != "alice" || created.RequestID != "req-1" {
+		t.Errorf("Expected actor alice and request req-1, got %s and %s", created.Actor, created.RequestID)
+	}
+	if created.Changes["mac_address"].To != "00:11:22:33:44:55" || created.Changes["mac_address"].From != nil {
+		t.Errorf("Expected mac_address to be recorded as created, got %+v", created.Changes)
+	}
+
+	updated := repo.audits[1]
+	if len(updated.Changes) != 1 || updated.Changes["ip_address"] != (models.FieldChange{From: "10.0.0.1", To: "10.0.0.2"}) {
+		t.Errorf("Expected only the ip_address change, got %+v", updated.Changes)
+	}
+
+	reassigned := repo.audits[2]
+	if reassigned.Actor != anonymousActor {
+		t.Errorf("Expected anonymous actor without context, got %s", reassigned.Actor)
+	}
+	if reassigned.Changes["employee_abbreviation"].To != "xyz" {
+		t.Errorf("Expected employee_abbreviation change, got %+v", reassigned.Changes)
+	}
+
+	deleted := repo.audits[3]
+	if deleted.Changes["computer_name"].From != "Test" || deleted.Changes["computer_name"].To != nil {
+		t.Errorf("Expected computer_name to be recorded as removed, got %+v", deleted.Changes)
+	}
+}
+
+func TestDiffComputersIgnoresBookkeeping(t *testing.T) {
+	before := &models.Computer{ID: 1, MACAddress: "00:11:22:33:44:55", ComputerName: "Test", IPAddress: "10.0.0.1", Version: 1}
+	after := *before
+	after.Version = 2
+
+	changes, err := diffComputers(before, &after)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Expected no changes, got %+v", changes)
+	}
+}
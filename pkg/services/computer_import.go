@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"log/slog"
+	"sort"
+)
+
+// errImportRolledBack rolls back the transaction of a dry run or of an atomic
+// import with failed rows
+var errImportRolledBack = errors.New("import rolled back")
+
+// ImportComputers creates the computers whose MAC address is not in use and
+// updates the ones whose MAC address is, replacing all their fields. Every row
+// is validated and checked against the assignment policy like a single write;
+// rows that fail are reported with their errors. Atomic imports run in one
+// transaction that is rolled back if any row fails, best-effort imports write
+// each row in its own transaction. Instead of a notification per row, the
+// computer limits of every employee who received computers are evaluated once
+// after the batch.
+func (s *computerService) ImportComputers(ctx context.Context, computers []models.Computer, opts models.ImportOptions) (*models.ImportResult, error) {
+	if opts.Mode == "" {
+		opts.Mode = models.ImportAtomic
+	}
+	if opts.Mode != models.ImportAtomic && opts.Mode != models.ImportBestEffort {
+		return nil, invalidField("mode", fmt.Sprintf("must be %s or %s", models.ImportAtomic, models.ImportBestEffort))
+	}
+	if len(computers) == 0 {
+		return nil, invalidField("computers", "must contain at least one computer")
+	}
+	if len(computers) > models.MaxImportRows {
+		return nil, invalidField("computers", fmt.Sprintf("must not contain more than %d computers", models.MaxImportRows))
+	}
+
+	result := &models.ImportResult{
+		Mode:          opts.Mode,
+		DryRun:        opts.DryRun,
+		Rows:          make([]models.ImportRowResult, len(computers)),
+		Notifications: []models.ImportNotification{},
+	}
+	s.validateImport(computers, result.Rows)
+
+	// Employees who received a computer, whose limits are evaluated at the end
+	assigned := make(map[string]bool)
+	importRows := func(repo models.ComputerRepository) error {
+		for i := range computers {
+			row := &result.Rows[i]
+			if row.Action == models.ImportFailed {
+				continue
+			}
+			var employee string
+			err := repo.Transaction(ctx, func(tx models.ComputerRepository) error {
+				var err error
+				employee, err = s.importRow(ctx, tx, &computers[i], row)
+				return err
+			})
+			if err != nil {
+				if err := failImportRow(row, err); err != nil {
+					return err
+				}
+				continue
+			}
+			if employee != "" {
+				assigned[employee] = true
+			}
+		}
+		return nil
+	}
+
+	var err error
+	if opts.Mode == models.ImportBestEffort && !opts.DryRun {
+		if err = importRows(s.repo); err == nil {
+			err = s.repo.Transaction(ctx, func(tx models.ComputerRepository) error {
+				return s.notifyImport(ctx, tx, assigned, result, true)
+			})
+		}
+	} else {
+		// Rows run in nested transactions, so a failed row leaves no trace
+		// even when the others are kept
+		err = s.repo.Transaction(ctx, func(tx models.ComputerRepository) error {
+			if err := importRows(tx); err != nil {
+				return err
+			}
+			if opts.Mode == models.ImportAtomic && countImportRows(result.Rows, models.ImportFailed) > 0 {
+				return errImportRolledBack
+			}
+			if err := s.notifyImport(ctx, tx, assigned, result, !opts.DryRun); err != nil {
+				return err
+			}
+			if opts.DryRun {
+				return errImportRolledBack
+			}
+			return nil
+		})
+	}
+	if err != nil && !errors.Is(err, errImportRolledBack) {
+		return nil, err
+	}
+	result.Applied = err == nil
+	if !result.Applied {
+		// IDs of rolled back inserts were never committed
+		for i := range result.Rows {
+			if result.Rows[i].Action == models.ImportCreated {
+				result.Rows[i].ComputerID = 0
+			}
+		}
+	}
+
+	result.Created = countImportRows(result.Rows, models.ImportCreated)
+	result.Updated = countImportRows(result.Rows, models.ImportUpdated)
+	result.Unchanged = countImportRows(result.Rows, models.ImportUnchanged)
+	result.Failed = countImportRows(result.Rows, models.ImportFailed)
+
+	slog.InfoContext(ctx, "Imported computers", "mode", result.Mode, "dry_run", result.DryRun, "applied", result.Applied,
+		"created", result.Created, "updated", result.Updated, "unchanged", result.Unchanged, "failed", result.Failed)
+	return result, nil
+}
+
+// validateImport validates every row and fails the ones that are invalid or
+// repeat the MAC address of an earlier row
+func (s *computerService) validateImport(computers []models.Computer, rows []models.ImportRowResult) {
+	seen := make(map[string]int, len(computers))
+	for i := range computers {
+		computer := &computers[i]
+		computer.ID = 0
+		computer.Version = 0
+
+		row := &rows[i]
+		row.Row = i + 1
+		err := s.validateComputer(computer)
+		row.MACAddress = computer.MACAddress
+		if err != nil {
+			failImportRow(row, err)
+			continue
+		}
+
+		if first, exists := seen[computer.MACAddress]; exists {
+			failImportRow(row, invalidField("mac_address", fmt.Sprintf("repeats the MAC address of row %d", first)))
+			continue
+		}
+		seen[computer.MACAddress] = row.Row
+	}
+}
+
+// importRow creates or updates the computer of a validated row within tx and
+// records the outcome in row. It returns the employee the computer was newly
+// assigned to, if any.
+func (s *computerService) importRow(ctx context.Context, tx models.ComputerRepository, computer *models.Computer, row *models.ImportRowResult) (string, error) {
+	newEmployee := ""
+	if computer.EmployeeAbbreviation != nil {
+		newEmployee = *computer.EmployeeAbbreviation
+	}
+
+	existing, err := tx.GetByMACAddress(ctx, computer.MACAddress)
+	if err != nil {
+		if err := s.assignIPAddress(ctx, tx, computer, ""); err != nil {
+			return "", err
+		}
+		if newEmployee != "" {
+			if _, _, err := s.checkAssignment(ctx, tx, newEmployee); err != nil {
+				return "", err
+			}
+		}
+		if err := tx.Create(ctx, computer); err != nil {
+			return "", macAddressError(computer, fmt.Errorf("failed to create computer: %w", err))
+		}
+		if err := s.audit(ctx, tx, models.AuditCreate, nil, computer); err != nil {
+			return "", err
+		}
+		row.Action = models.ImportCreated
+		row.ComputerID = computer.ID
+		return newEmployee, nil
+	}
+
+	existing, err = tx.LockByID(ctx, existing.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to lock computer: %w", err)
+	}
+	row.ComputerID = existing.ID
+	if err := s.assignIPAddress(ctx, tx, computer, existing.IPAddress); err != nil {
+		return "", err
+	}
+
+	oldEmployee := ""
+	if existing.EmployeeAbbreviation != nil {
+		oldEmployee = *existing.EmployeeAbbreviation
+	}
+	if existing.ComputerName == computer.ComputerName && existing.IPAddress == computer.IPAddress &&
+		existing.Description == computer.Description && oldEmployee == newEmployee {
+		row.Action = models.ImportUnchanged
+		return "", nil
+	}
+
+	if oldEmployee != newEmployee && newEmployee != "" {
+		if _, _, err := s.checkAssignment(ctx, tx, newEmployee); err != nil {
+			return "", err
+		}
+	}
+
+	updated := *existing
+	updated.ComputerName = computer.ComputerName
+	updated.IPAddress = computer.IPAddress
+	updated.EmployeeAbbreviation = computer.EmployeeAbbreviation
+	updated.Description = computer.Description
+	if err := tx.Update(ctx, &updated); err != nil {
+		return "", macAddressError(&updated, fmt.Errorf("failed to update computer: %w", err))
+	}
+
+	action := models.AuditUpdate
+	if oldEmployee != newEmployee {
+		action = models.AuditReassign
+	}
+	if err := s.audit(ctx, tx, action, existing, &updated); err != nil {
+		return "", err
+	}
+	*computer = updated
+	row.Action = models.ImportUpdated
+	if oldEmployee == newEmployee {
+		return "", nil
+	}
+	return newEmployee, nil
+}
+
+// notifyImport evaluates the policies of the employees who received computers
+// against their final counts and reports, and if queue is set queues, one
+// notification for each that reaches a warn threshold
+func (s *computerService) notifyImport(ctx context.Context, tx models.ComputerRepository, assigned map[string]bool, result *models.ImportResult, queue bool) error {
+	abbrs := make([]string, 0, len(assigned))
+	for abbr := range assigned {
+		abbrs = append(abbrs, abbr)
+	}
+	sort.Strings(abbrs)
+
+	var messages []*models.OutboxMessage
+	for _, abbr := range abbrs {
+		employee, err := tx.LockEmployee(ctx, abbr)
+		if err != nil {
+			return fmt.Errorf("failed to lock employee %s: %w", abbr, err)
+		}
+		count, err := tx.CountByEmployee(ctx, abbr)
+		if err != nil {
+			return fmt.Errorf("failed to count employee computers: %w", err)
+		}
+		policy, err := policyFor(ctx, s.policyRepo, employee)
+		if err != nil {
+			return err
+		}
+
+		_, warn := evaluatePolicy(policy, int(count))
+		if warn == nil {
+			continue
+		}
+		result.Notifications = append(result.Notifications, models.ImportNotification{
+			EmployeeAbbreviation: abbr,
+			Computers:            int(count),
+			Level:                warn.Level,
+		})
+		if !queue {
+			continue
+		}
+
+		slog.InfoContext(ctx, "Employee reached computer limit, queueing notification",
+			"employee", abbr, "computers", count, "level", warn.Level)
+		message, err := computerLimitMessage(ctx, abbr, int(count), warn.Level)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, message)
+	}
+
+	if err := tx.QueueMessages(ctx, messages...); err != nil {
+		return fmt.Errorf("failed to queue notifications: %w", err)
+	}
+	return nil
+}
+
+// failImportRow records why a row failed. Errors that are not about the row,
+// such as a failed query, are returned so they abort the import.
+func failImportRow(row *models.ImportRowResult, err error) error {
+	row.Action = models.ImportFailed
+	row.ComputerID = 0
+
+	var verr *ValidationError
+	switch {
+	case errors.As(err, &verr):
+		for _, fieldErr := range verr.Errors {
+			row.Errors = append(row.Errors, models.ImportRowError{Field: fieldErr.Field, Message: fieldErr.Message})
+		}
+	case errors.Is(err, ErrAssignmentBlocked):
+		row.Errors = append(row.Errors, models.ImportRowError{Field: "employee_abbreviation", Message: err.Error()})
+	case errors.Is(err, ErrSubnetExhausted), errors.Is(err, ErrIPAddressInUse):
+		row.Errors = append(row.Errors, models.ImportRowError{Field: "ip_address", Message: err.Error()})
+	case errors.Is(err, ErrMACAddressInUse), errors.Is(err, models.ErrVersionConflict):
+		row.Errors = append(row.Errors, models.ImportRowError{Message: err.Error()})
+	default:
+		return err
+	}
+	return nil
+}
+
+// countImportRows counts the rows with the given action
+func countImportRows(rows []models.ImportRowResult, action string) int {
+	count := 0
+	for _, row := range rows {
+		if row.Action == action {
+			count++
+		}
+	}
+	return count
+}
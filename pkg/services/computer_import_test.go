@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"greenbone-case-study/internal/db"
+	"greenbone-case-study/pkg/models"
+	"greenbone-case-study/pkg/notifications"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func newImportTestService(t *testing.T) (models.ComputerService, *gorm.DB) {
+	t.Helper()
+
+	database, err := db.InitDatabase(filepath.Join(t.TempDir(), "computers.db"), "sqlite")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(database) })
+
+	employeeRepo := models.NewEmployeeRepository(database)
+	employeeRepo.Create(context.Background(), &models.Employee{Abbreviation: "abc", Name: "Test", Active: true})
+	service := NewComputerService(models.NewComputerRepository(database), employeeRepo, models.NewPolicyRepository(database))
+	return service, database
+}
+
+func importRows(n int, abbr string) []models.Computer {
+	computers := make([]models.Computer, n)
+	for i := range computers {
+		computers[i] = models.Computer{
+			MACAddress:           fmt.Sprintf("00-11-22-33-44-%02X", i),
+			ComputerName:         fmt.Sprintf("Computer %d", i),
+			IPAddress:            fmt.Sprintf("10.0.0.%d", i+1),
+			EmployeeAbbreviation: &abbr,
+		}
+	}
+	return computers
+}
+
+func countComputers(t *testing.T, database *gorm.DB) int64 {
+	t.Helper()
+	var count int64
+	database.Model(&models.Computer{}).Count(&count)
+	return count
+}
+
+func TestImportComputersNotifiesOncePerEmployee(t *testing.T) {
+	service, database := newImportTestService(t)
+	ctx := context.Background()
+
+	result, err := service.ImportComputers(ctx, importRows(5, "abc"), models.ImportOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !result.Applied || result.Mode != models.ImportAtomic || result.Created != 5 || result.Failed != 0 {
+		t.Fatalf("Expected 5 computers to be created atomically, got %+v", result)
+	}
+	if result.Rows[0].MACAddress != "00:11:22:33:44:00" || result.Rows[0].ComputerID == 0 {
+		t.Errorf("Expected rows to report the canonical MAC address and ID, got %+v", result.Rows[0])
+	}
+
+	// One notification for the final count instead of one for each of the
+	// computers 3 to 5
+	messages, err := models.NewOutboxRepository(database).GetAll(ctx, models.OutboxQueryOptions{})
+	if err != nil {
+		t.Fatalf("Failed to load outbox: %v", err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 notification, got %d", len(messages))
+	}
+	var notification notifications.Notification
+	json.Unmarshal([]byte(messages[0].Payload), &notification)
+	if notification.Message != "Employee abc has been assigned 5 computers" {
+		t.Errorf("Unexpected notification: %s", notification.Message)
+	}
+	if len(result.Notifications) != 1 || result.Notifications[0].Computers != 5 {
+		t.Errorf("Expected the notification to be reported, got %+v", result.Notifications)
+	}
+}
+
+func TestImportComputersUpsertsByMACAddress(t *testing.T) {
+	service, database := newImportTestService(t)
+	ctx := context.Background()
+
+	if _, err := service.ImportComputers(ctx, importRows(2, "abc"), models.ImportOptions{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	computers := importRows(3, "abc")
+	computers[1].ComputerName = "Renamed"
+	computers[1].EmployeeAbbreviation = nil
+	result, err := service.ImportComputers(ctx, computers, models.ImportOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	actions := []string{result.Rows[0].Action, result.Rows[1].Action, result.Rows[2].Action}
+	if actions[0] != models.ImportUnchanged || actions[1] != models.ImportUpdated || actions[2] != models.ImportCreated {
+		t.Errorf("Expected unchanged, updated and created rows, got %v", actions)
+	}
+	if count := countComputers(t, database); count != 3 {
+		t.Errorf("Expected 3 computers, got %d", count)
+	}
+
+	var updated models.Computer
+	database.First(&updated, result.Rows[1].ComputerID)
+	if updated.ComputerName != "Renamed" || updated.EmployeeAbbreviation != nil || updated.Version != 2 {
+		t.Errorf("Expected the matched computer to be replaced, got %+v", updated)
+	}
+}
+
+func TestImportComputersAtomicRollsBack(t *testing.T) {
+	service, database := newImportTestService(t)
+
+	computers := importRows(4, "abc")
+	computers[1].IPAddress = "not-an-ip"
+	computers[3].MACAddress = computers[0].MACAddress
+	result, err := service.ImportComputers(context.Background(), computers, models.ImportOptions{Mode: models.ImportAtomic})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Applied || result.Failed != 2 {
+		t.Fatalf("Expected the import to be rolled back with 2 failed rows, got %+v", result)
+	}
+	if errs := result.Rows[1].Errors; len(errs) != 1 || errs[0].Field != "ip_address" {
+		t.Errorf("Expected an ip_address error, got %+v", errs)
+	}
+	if errs := result.Rows[3].Errors; len(errs) != 1 || errs[0].Message != "repeats the MAC address of row 1" {
+		t.Errorf("Expected a repeated MAC address error, got %+v", errs)
+	}
+	if result.Rows[0].Action != models.ImportCreated || result.Rows[0].ComputerID != 0 {
+		t.Errorf("Expected valid rows to report what they would have done, got %+v", result.Rows[0])
+	}
+	if count := countComputers(t, database); count != 0 {
+		t.Errorf("Expected no computers to be stored, got %d", count)
+	}
+}
+
+func TestImportComputersBestEffort(t *testing.T) {
+	service, database := newImportTestService(t)
+	ctx := context.Background()
+
+	policyService := NewPolicyService(models.NewPolicyRepository(database))
+	policyService.CreatePolicy(ctx, &models.AssignmentPolicy{
+		Scope:      models.PolicyScopeGlobal,
+		Thresholds: []models.PolicyThreshold{{Count: 3, Action: models.ThresholdBlock}},
+	})
+
+	computers := importRows(4, "abc")
+	computers[0].ComputerName = ""
+	result, err := service.ImportComputers(ctx, computers, models.ImportOptions{Mode: models.ImportBestEffort})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !result.Applied || result.Created != 2 || result.Failed != 2 {
+		t.Fatalf("Expected 2 created and 2 failed rows, got %+v", result)
+	}
+	if errs := result.Rows[3].Errors; len(errs) != 1 || errs[0].Field != "employee_abbreviation" {
+		t.Errorf("Expected the third assignment to be blocked, got %+v", errs)
+	}
+	if count := countComputers(t, database); count != 2 {
+		t.Errorf("Expected 2 computers to be stored, got %d", count)
+	}
+}
+
+func TestImportComputersDryRun(t *testing.T) {
+	service, database := newImportTestService(t)
+	ctx := context.Background()
+
+	result, err := service.ImportComputers(ctx, importRows(3, "abc"), models.ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Applied || result.Created != 3 {
+		t.Fatalf("Expected 3 rows to be checked but not applied, got %+v", result)
+	}
+	if len(result.Notifications) != 1 {
+		t.Errorf("Expected the notification to be reported, got %+v", result.Notifications)
+	}
+	if count := countComputers(t, database); count != 0 {
+		t.Errorf("Expected no computers to be stored, got %d", count)
+	}
+	messages, _ := models.NewOutboxRepository(database).GetAll(ctx, models.OutboxQueryOptions{})
+	if len(messages) != 0 {
+		t.Errorf("Expected no notifications to be queued, got %d", len(messages))
+	}
+
+	if _, err := service.ImportComputers(ctx, nil, models.ImportOptions{}); err == nil {
+		t.Error("Expected an empty import to be rejected")
+	}
+	if _, err := service.ImportComputers(ctx, importRows(1, "abc"), models.ImportOptions{Mode: "partial"}); err == nil {
+		t.Error("Expected an unknown mode to be rejected")
+	}
+}
+
+func TestImportComputersRejectsDeletedAt(t *testing.T) {
+	service, database := newImportTestService(t)
+
+	computers := importRows(2, "abc")
+	computers[1].DeletedAt = gorm.DeletedAt{Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	result, err := service.ImportComputers(context.Background(), computers, models.ImportOptions{Mode: models.ImportBestEffort})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if errs := result.Rows[1].Errors; result.Created != 1 || len(errs) != 1 || errs[0].Field != "deleted_at" {
+		t.Errorf("Expected the deleted row to fail, got %+v", result)
+	}
+	if count := countComputers(t, database); count != 1 {
+		t.Errorf("Expected 1 computer to be stored, got %d", count)
+	}
+}
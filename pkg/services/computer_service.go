@@ -1,104 +1,213 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"greenbone-case-study/pkg/models"
 	"greenbone-case-study/pkg/notifications"
+	"greenbone-case-study/pkg/tracing"
+	"log/slog"
+	"net/netip"
 	"strings"
+	"time"
 )
 
+var (
+	// ErrComputerNotFound is returned when the requested computer does not exist
+	ErrComputerNotFound = errors.New("computer not found")
+	// ErrComputerNotDeleted is returned when restoring a computer that is not deleted
+	ErrComputerNotDeleted = errors.New("computer is not deleted")
+	// ErrMACAddressInUse is returned when another computer already has the MAC address
+	ErrMACAddressInUse = errors.New("MAC address is in use by another computer")
+)
+
+// DefaultPurgeRetention is how long deleted computers are kept before a purge
+// unless ComputerServiceOptions set another retention
+// removes them, unless the purge asks for a different window
+const DefaultPurgeRetention = 30 * 24 * time.Hour
+
+// ComputerServiceOptions configure a computer service
+type ComputerServiceOptions struct {
+	// StrictIPAddresses rejects new IP addresses that lie within no managed subnet
+	StrictIPAddresses bool
+	// PurgeRetention is how long deleted computers are kept by a purge that
+	// sets no retention of its own; zero means DefaultPurgeRetention
+	PurgeRetention time.Duration
+}
+
 type computerService struct {
 	repo         models.ComputerRepository
-	notifyClient notifications.NotificationClient
+	employeeRepo models.EmployeeRepository
+	policyRepo   models.PolicyRepository
+	options      ComputerServiceOptions
+}
+
+// NewComputerService creates a new computer service. Assignments are checked
+// against the employee's policy; notifications are not sent directly but
+// written to the outbox together with the computer change.
+func NewComputerService(repo models.ComputerRepository, employeeRepo models.EmployeeRepository, policyRepo models.PolicyRepository) models.ComputerService {
+	return NewComputerServiceWithOptions(repo, employeeRepo, policyRepo, ComputerServiceOptions{})
 }
 
-// NewComputerService creates a new computer service
-func NewComputerService(repo models.ComputerRepository, notifyClient notifications.NotificationClient) models.ComputerService {
+// NewComputerServiceWithOptions creates a new computer service configured by options
+func NewComputerServiceWithOptions(repo models.ComputerRepository, employeeRepo models.EmployeeRepository, policyRepo models.PolicyRepository, options ComputerServiceOptions) models.ComputerService {
 	return &computerService{
 		repo:         repo,
-		notifyClient: notifyClient,
+		employeeRepo: employeeRepo,
+		policyRepo:   policyRepo,
+		options:      options,
 	}
 }
 
-// CreateComputer creates a new computer with validation
-func (s *computerService) CreateComputer(computer *models.Computer) error {
+// CreateComputer creates a new computer with validation. The employee's
+// computer count and the insert happen in one transaction with the employee
+// row locked, so concurrent assignments cannot miss or repeat the notification.
+// Likewise an IP address of "auto" is allocated with the subnet row locked.
+func (s *computerService) CreateComputer(ctx context.Context, computer *models.Computer) error {
 	// Validate input
 	if err := s.validateComputer(computer); err != nil {
 		return err
 	}
 
-	// Check if employee already has computers and count them
-	var currentCount int64 = 0
-	if computer.EmployeeAbbreviation != nil && *computer.EmployeeAbbreviation != "" {
-		count, err := s.repo.CountByEmployee(*computer.EmployeeAbbreviation)
-		if err != nil {
-			return fmt.Errorf("failed to count employee computers: %w", err)
+	return s.repo.Transaction(ctx, func(tx models.ComputerRepository) error {
+		if err := s.assignIPAddress(ctx, tx, computer, ""); err != nil {
+			return err
+		}
+
+		var messages []*models.OutboxMessage
+		if computer.EmployeeAbbreviation != nil {
+			message, err := s.assign(ctx, tx, *computer.EmployeeAbbreviation)
+			if err != nil {
+				return err
+			}
+			if message != nil {
+				messages = append(messages, message)
+			}
 		}
-		currentCount = count
+
+		if err := checkMACAddressFree(ctx, tx, computer); err != nil {
+			return err
+		}
+
+		// Create the computer together with its notifications
+		if err := tx.Create(ctx, computer, messages...); err != nil {
+			return macAddressError(computer, fmt.Errorf("failed to create computer: %w", err))
+		}
+		return s.audit(ctx, tx, models.AuditCreate, nil, computer)
+	})
+}
+
+// GetAllComputers retrieves a filtered, sorted page of computers
+func (s *computerService) GetAllComputers(ctx context.Context, opts models.ComputerQueryOptions) (*models.ComputerPage, error) {
+	if err := validateLimitOffset(opts.Limit, opts.Offset); err != nil {
+		return nil, err
+	}
+	if opts.Limit == 0 {
+		opts.Limit = models.DefaultPageLimit
+	}
+	if opts.Limit > models.MaxPageLimit {
+		opts.Limit = models.MaxPageLimit
 	}
 
-	// Create the computer
-	if err := s.repo.Create(computer); err != nil {
-		return fmt.Errorf("failed to create computer: %w", err)
+	page, err := s.repo.GetAll(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get computers: %w", err)
 	}
+	return page, nil
+}
 
-	// Check if employee now has 3 or more computers and send notification
-	if computer.EmployeeAbbreviation != nil && *computer.EmployeeAbbreviation != "" {
-		newCount := currentCount + 1
-		if newCount >= 3 {
-			go s.sendComputerLimitNotification(*computer.EmployeeAbbreviation, int(newCount))
+// ExportComputers streams the computers matching opts to fn
+func (s *computerService) ExportComputers(ctx context.Context, opts models.ComputerQueryOptions, fn func(computer *models.Computer) error) error {
+	if opts.EmployeeAbbreviation != "" {
+		if err := validateEmployeeAbbreviation(opts.EmployeeAbbreviation); err != nil {
+			return invalidField("abbreviation", err.Error())
+		}
+		if _, err := s.employeeRepo.GetByAbbreviation(ctx, opts.EmployeeAbbreviation); err != nil {
+			return notFound(ErrEmployeeNotFound, err)
 		}
 	}
 
+	if err := s.repo.Stream(ctx, opts, fn); err != nil {
+		return fmt.Errorf("failed to export computers: %w", err)
+	}
 	return nil
 }
 
-// GetAllComputers retrieves all computers
-func (s *computerService) GetAllComputers() ([]models.Computer, error) {
-	computers, err := s.repo.GetAll()
+// SearchComputers finds computers by words of their name, description,
+// addresses or employee, best match first
+func (s *computerService) SearchComputers(ctx context.Context, opts models.ComputerSearchOptions) ([]models.ComputerSearchHit, error) {
+	verr := &ValidationError{}
+	if terms := models.SearchTerms(opts.Query); len(terms) == 0 {
+		verr.Add("q", "is required")
+	} else if len(terms) > models.MaxSearchTerms {
+		verr.Add("q", fmt.Sprintf("must not have more than %d words", models.MaxSearchTerms))
+	}
+	if opts.Limit < 0 {
+		verr.Add("limit", "must not be negative")
+	}
+	if err := verr.errorOrNil(); err != nil {
+		return nil, err
+	}
+	if opts.Limit == 0 {
+		opts.Limit = models.DefaultSearchLimit
+	}
+	if opts.Limit > models.MaxSearchLimit {
+		opts.Limit = models.MaxSearchLimit
+	}
+
+	hits, err := s.repo.Search(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get computers: %w", err)
+		return nil, fmt.Errorf("failed to search computers: %w", err)
 	}
-	return computers, nil
+	return hits, nil
 }
 
 // GetComputerByID retrieves a computer by ID
-func (s *computerService) GetComputerByID(id uint) (*models.Computer, error) {
+func (s *computerService) GetComputerByID(ctx context.Context, id uint) (*models.Computer, error) {
 	if id == 0 {
-		return nil, errors.New("invalid computer ID")
+		return nil, errInvalidID
 	}
 
-	computer, err := s.repo.GetByID(id)
+	computer, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get computer: %w", err)
+		return nil, notFound(ErrComputerNotFound, err)
 	}
 	return computer, nil
 }
 
-// GetComputersByEmployee retrieves computers by employee abbreviation
-func (s *computerService) GetComputersByEmployee(abbr string) ([]models.Computer, error) {
-	if err := s.validateEmployeeAbbreviation(abbr); err != nil {
-		return nil, err
+// GetComputersByEmployee retrieves computers by employee abbreviation, deleted
+// ones only if includeDeleted is set
+func (s *computerService) GetComputersByEmployee(ctx context.Context, abbr string, includeDeleted bool) ([]models.Computer, error) {
+	if err := validateEmployeeAbbreviation(abbr); err != nil {
+		return nil, invalidField("abbreviation", err.Error())
+	}
+	if _, err := s.employeeRepo.GetByAbbreviation(ctx, abbr); err != nil {
+		return nil, notFound(ErrEmployeeNotFound, err)
 	}
 
-	computers, err := s.repo.GetByEmployeeAbbreviation(abbr)
+	computers, err := s.repo.GetByEmployeeAbbreviation(ctx, abbr, includeDeleted)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get computers for employee %s: %w", abbr, err)
 	}
 	return computers, nil
 }
 
-// UpdateComputer updates a computer with validation
-func (s *computerService) UpdateComputer(computer *models.Computer) error {
-	if computer.ID == 0 {
-		return errors.New("invalid computer ID")
+// FindDuplicateIPAddresses reports the IP addresses held by several computers
+func (s *computerService) FindDuplicateIPAddresses(ctx context.Context, network netip.Prefix) ([]models.DuplicateIPAddress, error) {
+	duplicates, err := s.repo.FindDuplicateIPAddresses(ctx, network)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate IP addresses: %w", err)
 	}
+	return duplicates, nil
+}
 
-	// Get existing computer to check for employee changes
-	existingComputer, err := s.repo.GetByID(computer.ID)
-	if err != nil {
-		return fmt.Errorf("computer not found: %w", err)
+// UpdateComputer updates a computer with validation. Like CreateComputer, a
+// reassignment counts and writes within one transaction.
+func (s *computerService) UpdateComputer(ctx context.Context, computer *models.Computer) error {
+	if computer.ID == 0 {
+		return errInvalidID
 	}
 
 	// Validate input
@@ -106,102 +215,391 @@ func (s *computerService) UpdateComputer(computer *models.Computer) error {
 		return err
 	}
 
-	// Check if employee assignment changed
-	oldEmployee := ""
-	newEmployee := ""
+	return s.repo.Transaction(ctx, func(tx models.ComputerRepository) error {
+		// Get existing computer to check for employee changes
+		existingComputer, err := tx.LockByID(ctx, computer.ID)
+		if err != nil {
+			return notFound(ErrComputerNotFound, err)
+		}
 
-	if existingComputer.EmployeeAbbreviation != nil {
-		oldEmployee = *existingComputer.EmployeeAbbreviation
-	}
-	if computer.EmployeeAbbreviation != nil {
-		newEmployee = *computer.EmployeeAbbreviation
-	}
+		// Writes are conditional on the version the client has seen, or the one
+		// just read when the client did not send one
+		if computer.Version == 0 {
+			computer.Version = existingComputer.Version
+		} else if computer.Version != existingComputer.Version {
+			return models.ErrVersionConflict
+		}
+		computer.CreatedAt = existingComputer.CreatedAt
 
-	// Update the computer
-	if err := s.repo.Update(computer); err != nil {
-		return fmt.Errorf("failed to update computer: %w", err)
+		if err := s.assignIPAddress(ctx, tx, computer, existingComputer.IPAddress); err != nil {
+			return err
+		}
+
+		// Check if employee assignment changed
+		oldEmployee := ""
+		newEmployee := ""
+
+		if existingComputer.EmployeeAbbreviation != nil {
+			oldEmployee = *existingComputer.EmployeeAbbreviation
+		}
+		if computer.EmployeeAbbreviation != nil {
+			newEmployee = *computer.EmployeeAbbreviation
+		}
+
+		// Only new assignments are checked and counted, existing ones are kept as they are
+		var messages []*models.OutboxMessage
+		if oldEmployee != newEmployee && newEmployee != "" {
+			message, err := s.assign(ctx, tx, newEmployee)
+			if err != nil {
+				return err
+			}
+			if message != nil {
+				messages = append(messages, message)
+			}
+		}
+
+		if err := checkMACAddressFree(ctx, tx, computer); err != nil {
+			return err
+		}
+
+		// Update the computer together with its notifications
+		if err := tx.Update(ctx, computer, messages...); err != nil {
+			return macAddressError(computer, fmt.Errorf("failed to update computer: %w", err))
+		}
+
+		action := models.AuditUpdate
+		if oldEmployee != newEmployee {
+			action = models.AuditReassign
+		}
+		return s.audit(ctx, tx, action, existingComputer, computer)
+	})
+}
+
+// DeleteComputer marks a computer as deleted, if version is non-zero only when it is still current
+func (s *computerService) DeleteComputer(ctx context.Context, id uint, version uint) error {
+	if id == 0 {
+		return errInvalidID
 	}
 
-	// If employee changed, check new employee's computer count
-	if oldEmployee != newEmployee && newEmployee != "" {
-		count, err := s.repo.CountByEmployee(newEmployee)
+	return s.repo.Transaction(ctx, func(tx models.ComputerRepository) error {
+		// Check if computer exists
+		existingComputer, err := tx.LockByID(ctx, id)
 		if err != nil {
-			// Log error but don't fail the update
-			fmt.Printf("Warning: failed to count computers for employee %s: %v\n", newEmployee, err)
-		} else if count >= 3 {
-			go s.sendComputerLimitNotification(newEmployee, int(count))
+			return notFound(ErrComputerNotFound, err)
 		}
-	}
 
-	return nil
+		if err := tx.Delete(ctx, id, version); err != nil {
+			return fmt.Errorf("failed to delete computer: %w", err)
+		}
+		return s.audit(ctx, tx, models.AuditDelete, existingComputer, nil)
+	})
 }
 
-// DeleteComputer deletes a computer by ID
-func (s *computerService) DeleteComputer(id uint) error {
+// RestoreComputer restores a deleted computer. Its employee assignment is
+// checked against the assignment policy like a new one.
+func (s *computerService) RestoreComputer(ctx context.Context, id uint) (*models.Computer, error) {
 	if id == 0 {
-		return errors.New("invalid computer ID")
+		return nil, errInvalidID
 	}
 
-	// Check if computer exists
-	_, err := s.repo.GetByID(id)
+	var computer *models.Computer
+	err := s.repo.Transaction(ctx, func(tx models.ComputerRepository) error {
+		deleted, err := tx.GetDeletedByID(ctx, id)
+		if err != nil {
+			if _, err := tx.GetByID(ctx, id); err == nil {
+				return ErrComputerNotDeleted
+			}
+			return notFound(ErrComputerNotFound, err)
+		}
+
+		if _, err := tx.GetByMACAddress(ctx, deleted.MACAddress); err == nil {
+			return fmt.Errorf("%w: %s", ErrMACAddressInUse, deleted.MACAddress)
+		}
+
+		var messages []*models.OutboxMessage
+		if deleted.EmployeeAbbreviation != nil {
+			message, err := s.assign(ctx, tx, *deleted.EmployeeAbbreviation)
+			if err != nil {
+				return err
+			}
+			if message != nil {
+				messages = append(messages, message)
+			}
+		}
+
+		restored := *deleted
+		if err := tx.Restore(ctx, &restored, messages...); err != nil {
+			return macAddressError(&restored, fmt.Errorf("failed to restore computer: %w", err))
+		}
+		computer = &restored
+		return s.audit(ctx, tx, models.AuditRestore, deleted, computer)
+	})
 	if err != nil {
-		return fmt.Errorf("computer not found: %w", err)
+		return nil, err
 	}
+	return computer, nil
+}
 
-	if err := s.repo.Delete(id); err != nil {
-		return fmt.Errorf("failed to delete computer: %w", err)
+// PurgeComputers permanently removes computers deleted longer than olderThan
+// ago, or the configured retention if it is nil, and returns how many were
+// removed. A retention of zero removes every deleted computer.
+func (s *computerService) PurgeComputers(ctx context.Context, retention *time.Duration) (int, error) {
+	olderThan := s.options.PurgeRetention
+	if olderThan == 0 {
+		olderThan = DefaultPurgeRetention
+	}
+	if retention != nil {
+		olderThan = *retention
+	}
+	if olderThan < 0 {
+		return 0, invalidField("older_than", "must not be negative")
 	}
 
-	return nil
+	purged := 0
+	err := s.repo.Transaction(ctx, func(tx models.ComputerRepository) error {
+		computers, err := tx.Purge(ctx, time.Now().Add(-olderThan))
+		if err != nil {
+			return fmt.Errorf("failed to purge computers: %w", err)
+		}
+
+		for i := range computers {
+			if err := s.audit(ctx, tx, models.AuditPurge, &computers[i], nil); err != nil {
+				return err
+			}
+		}
+		purged = len(computers)
+		return nil
+	})
+	if err == nil {
+		slog.InfoContext(ctx, "Purged deleted computers", "count", purged, "older_than", olderThan)
+	}
+	return purged, err
 }
 
-// validateComputer validates computer input data
+// validateComputer validates computer input data and reports every invalid field
 func (s *computerService) validateComputer(computer *models.Computer) error {
+	// An empty abbreviation means unassigned
+	if computer.EmployeeAbbreviation != nil && *computer.EmployeeAbbreviation == "" {
+		computer.EmployeeAbbreviation = nil
+	}
+
+	verr := &ValidationError{}
+
+	// Addresses are stored in canonical form so duplicates are detected
 	if computer.MACAddress == "" {
-		return errors.New("MAC address is required")
+		verr.Add("mac_address", "is required")
+	} else if mac, err := models.ParseMACAddress(computer.MACAddress); err != nil {
+		verr.Add("mac_address", "must be 6 hex octets, e.g. 00:11:22:aa:bb:cc, 00-11-22-AA-BB-CC, 0011.22aa.bbcc or 001122aabbcc")
+	} else {
+		computer.MACAddress = mac
 	}
+
 	if computer.ComputerName == "" {
-		return errors.New("computer name is required")
+		verr.Add("computer_name", "is required")
 	}
+
+	// An address of "auto" is allocated from the subnet once the subnet is locked
 	if computer.IPAddress == "" {
-		return errors.New("IP address is required")
+		verr.Add("ip_address", "is required")
+	} else if computer.IPAddress == models.AutoIPAddress {
+		if computer.SubnetID == nil {
+			verr.Add("subnet_id", "is required to allocate an IP address")
+		}
+	} else if ip, err := models.ParseIPAddress(computer.IPAddress); err != nil {
+		verr.Add("ip_address", "must be a valid IPv4 or IPv6 address, or auto with a subnet_id")
+	} else {
+		computer.IPAddress = ip
 	}
-
-	// Validate MAC address format (basic validation)
-	if len(computer.MACAddress) != 17 {
-		return errors.New("MAC address must be 17 characters long (XX:XX:XX:XX:XX:XX)")
+	if computer.SubnetID != nil && *computer.SubnetID == 0 {
+		verr.Add("subnet_id", "must be a positive integer")
+	}
+	// Deletion goes through DeleteComputer, so it is audited and only purged
+	// after the retention period
+	if computer.DeletedAt.Valid {
+		verr.Add("deleted_at", "cannot be set, delete or restore the computer instead")
 	}
 
 	// Validate employee abbreviation if provided
-	if computer.EmployeeAbbreviation != nil && *computer.EmployeeAbbreviation != "" {
-		if err := s.validateEmployeeAbbreviation(*computer.EmployeeAbbreviation); err != nil {
-			return err
+	if computer.EmployeeAbbreviation != nil {
+		if err := validateEmployeeAbbreviation(*computer.EmployeeAbbreviation); err != nil {
+			verr.Add("employee_abbreviation", err.Error())
+		}
+	}
+
+	return verr.errorOrNil()
+}
+
+// assignIPAddress settles the IP address of a computer within tx, given its
+// previous address if it is not new. An address of "auto" becomes the next
+// free address of the computer's subnet, whose row is locked so concurrent
+// allocations cannot pick the same address, unless the previous address lies
+// within the subnet and is kept. An explicit address must lie within the
+// subnet if one is given and, if it changed, be neither reserved nor held by
+// another computer, checked while the subnet is locked; with
+// StrictIPAddresses it must lie within any managed subnet if it changed.
+func (s *computerService) assignIPAddress(ctx context.Context, tx models.ComputerRepository, computer *models.Computer, previous string) error {
+	if computer.SubnetID == nil {
+		if s.options.StrictIPAddresses && computer.IPAddress != previous {
+			if _, err := tx.GetSubnetByIPAddress(ctx, computer.IPAddress); err != nil {
+				return invalidField("ip_address", fmt.Sprintf("%s does not lie within a managed subnet", computer.IPAddress))
+			}
 		}
+		return nil
 	}
 
+	subnet, err := tx.LockSubnet(ctx, *computer.SubnetID)
+	if err != nil {
+		return invalidField("subnet_id", fmt.Sprintf("subnet %d does not exist", *computer.SubnetID))
+	}
+	prefix := subnet.Prefix()
+
+	if computer.IPAddress != models.AutoIPAddress {
+		addr, err := netip.ParseAddr(computer.IPAddress)
+		if err != nil || !prefix.Contains(addr) {
+			return invalidField("ip_address", fmt.Sprintf("%s does not lie within subnet %s", computer.IPAddress, subnet.CIDR))
+		}
+		if computer.IPAddress == previous {
+			return nil
+		}
+		if subnet.IsReserved(addr) {
+			return invalidField("ip_address", fmt.Sprintf("%s is reserved in subnet %s", computer.IPAddress, subnet.CIDR))
+		}
+		used, _, err := tx.GetIPAddressesInNetwork(ctx, netip.PrefixFrom(addr, addr.BitLen()))
+		if err != nil {
+			return fmt.Errorf("failed to check address %s: %w", computer.IPAddress, err)
+		}
+		if len(used) > 0 {
+			return fmt.Errorf("%w: %s", ErrIPAddressInUse, computer.IPAddress)
+		}
+		return nil
+	}
+	if addr, err := netip.ParseAddr(previous); err == nil && prefix.Contains(addr) {
+		computer.IPAddress = previous
+		return nil
+	}
+
+	used, _, err := tx.GetIPAddressesInNetwork(ctx, prefix)
+	if err != nil {
+		return fmt.Errorf("failed to get addresses of subnet %s: %w", subnet.CIDR, err)
+	}
+	addr, ok := subnet.NextFreeAddress(used)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSubnetExhausted, subnet.CIDR)
+	}
+	computer.IPAddress = addr.String()
+	slog.InfoContext(ctx, "Allocated IP address", "subnet", subnet.CIDR, "ip_address", computer.IPAddress)
+	return nil
+}
+
+// checkMACAddressFree returns ErrMACAddressInUse if another computer that is
+// not deleted has the computer's MAC address
+func checkMACAddressFree(ctx context.Context, tx models.ComputerRepository, computer *models.Computer) error {
+	other, err := tx.GetByMACAddress(ctx, computer.MACAddress)
+	if err == nil && other.ID != computer.ID {
+		return fmt.Errorf("%w: %s", ErrMACAddressInUse, computer.MACAddress)
+	}
 	return nil
 }
 
-// validateEmployeeAbbreviation validates employee abbreviation
-func (s *computerService) validateEmployeeAbbreviation(abbr string) error {
+// macAddressError reports a unique violation that slipped past
+// checkMACAddressFree, e.g. from a concurrent insert, as ErrMACAddressInUse
+func macAddressError(computer *models.Computer, err error) error {
+	if errors.Is(err, models.ErrDuplicateMACAddress) {
+		return fmt.Errorf("%w: %s", ErrMACAddressInUse, computer.MACAddress)
+	}
+	return err
+}
+
+// assign locks an employee that is about to receive one more computer, checks
+// the assignment and returns the notification to queue if the new count
+// reaches a warn threshold
+func (s *computerService) assign(ctx context.Context, tx models.ComputerRepository, abbr string) (*models.OutboxMessage, error) {
+	newCount, warn, err := s.checkAssignment(ctx, tx, abbr)
+	if err != nil || warn == nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "Employee reached computer limit, queueing notification",
+		"employee", abbr, "computers", newCount, "level", warn.Level)
+	return computerLimitMessage(ctx, abbr, newCount, warn.Level)
+}
+
+// checkAssignment locks an employee that is about to receive one more
+// computer, checks that they are active and evaluates their assignment policy.
+// It returns ErrAssignmentBlocked if the new count reaches a block threshold,
+// and otherwise the new count and the highest warn threshold it reaches, if any.
+func (s *computerService) checkAssignment(ctx context.Context, tx models.ComputerRepository, abbr string) (int, *models.PolicyThreshold, error) {
+	employee, err := tx.LockEmployee(ctx, abbr)
+	if err != nil {
+		return 0, nil, invalidField("employee_abbreviation", fmt.Sprintf("employee %s does not exist", abbr))
+	}
+	if !employee.Active {
+		return 0, nil, invalidField("employee_abbreviation", fmt.Sprintf("employee %s is not active", abbr))
+	}
+
+	count, err := tx.CountByEmployee(ctx, abbr)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to count employee computers: %w", err)
+	}
+
+	policy, err := policyFor(ctx, s.policyRepo, employee)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	newCount := int(count + 1)
+	block, warn := evaluatePolicy(policy, newCount)
+	if block != nil {
+		slog.WarnContext(ctx, "Assignment blocked by policy", "employee", abbr, "computers", newCount, "policy", describePolicy(policy))
+		return 0, nil, fmt.Errorf("%w: employee %s would have %d computers, %s allows fewer than %d",
+			ErrAssignmentBlocked, abbr, newCount, describePolicy(policy), block.Count)
+	}
+	return newCount, warn, nil
+}
+
+// audit records a computer change in the audit log within the transaction
+func (s *computerService) audit(ctx context.Context, tx models.ComputerRepository, action string, before, after *models.Computer) error {
+	entry, err := computerAuditEntry(ctx, action, before, after)
+	if err != nil {
+		return err
+	}
+	if err := tx.RecordAudit(ctx, entry); err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// validateEmployeeAbbreviation validates employee abbreviation. Callers report
+// the error as a FieldError under their own field name.
+func validateEmployeeAbbreviation(abbr string) error {
 	if len(abbr) != 3 {
-		return errors.New("employee abbreviation must be exactly 3 characters")
+		return errors.New("must be exactly 3 characters")
 	}
 	if abbr != strings.ToLower(abbr) {
-		return errors.New("employee abbreviation must be lowercase")
+		return errors.New("must be lowercase")
 	}
 	return nil
 }
 
-// sendComputerLimitNotification sends a notification when employee has 3+ computers
-func (s *computerService) sendComputerLimitNotification(employeeAbbr string, count int) {
+// computerLimitMessage builds the outbox message for an employee who reached a
+// warn threshold. It keeps the request ID and trace context of ctx so the
+// delivery is logged and traced as part of the request that caused it.
+func computerLimitMessage(ctx context.Context, employeeAbbr string, count int, level string) (*models.OutboxMessage, error) {
 	notification := notifications.Notification{
-		Level:                "warning",
+		Level:                level,
 		EmployeeAbbreviation: employeeAbbr,
 		Message:              fmt.Sprintf("Employee %s has been assigned %d computers", employeeAbbr, count),
+		Timestamp:            time.Now().UTC().Format(time.RFC3339),
 	}
 
-	if err := s.notifyClient.SendNotification(notification); err != nil {
-		fmt.Printf("Failed to send notification for employee %s: %v\n", employeeAbbr, err)
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode notification: %w", err)
 	}
+
+	return &models.OutboxMessage{
+		Kind:        ComputerLimitNotification,
+		Payload:     string(payload),
+		RequestID:   RequestIDFromContext(ctx),
+		TraceParent: tracing.TraceParent(ctx),
+	}, nil
 }
@@ -2,50 +2,91 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"greenbone-case-study/internal/db"
 	"greenbone-case-study/pkg/models"
 	"greenbone-case-study/pkg/notifications"
+	"net/netip"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // Mock repository for testing
 type mockComputerRepository struct {
-	computers map[uint]*models.Computer
-	nextID    uint
-	countMap  map[string]int64
+	computers  map[uint]*models.Computer
+	deleted    map[uint]*models.Computer
+	nextID     uint
+	employees  *mockEmployeeRepository
+	policies   *mockPolicyRepository
+	lastQuery  models.ComputerQueryOptions
+	lastSearch models.ComputerSearchOptions
+	outbox     []*models.OutboxMessage
+	audits     []*models.AuditEntry
+	subnets    map[uint]*models.Subnet
+	txCount    int
 }
 
 func newMockRepository() *mockComputerRepository {
 	return &mockComputerRepository{
 		computers: make(map[uint]*models.Computer),
+		deleted:   make(map[uint]*models.Computer),
 		nextID:    1,
-		countMap:  make(map[string]int64),
+		employees: newMockEmployeeRepository("abc"),
+		policies:  newMockPolicyRepository(),
+		subnets:   make(map[uint]*models.Subnet),
 	}
 }
 
-func (m *mockComputerRepository) Create(computer *models.Computer) error {
+func (m *mockComputerRepository) Create(ctx context.Context, computer *models.Computer, messages ...*models.OutboxMessage) error {
 	computer.ID = m.nextID
+	computer.Version = 1
 	m.nextID++
 	m.computers[computer.ID] = computer
-
-	if computer.EmployeeAbbreviation != nil {
-		m.countMap[*computer.EmployeeAbbreviation]++
-	}
-
+	m.outbox = append(m.outbox, messages...)
 	return nil
 }
 
-func (m *mockComputerRepository) GetAll() ([]models.Computer, error) {
+func (m *mockComputerRepository) GetAll(ctx context.Context, opts models.ComputerQueryOptions) (*models.ComputerPage, error) {
+	m.lastQuery = opts
 	var result []models.Computer
 	for _, computer := range m.computers {
 		result = append(result, *computer)
 	}
-	return result, nil
+	if opts.IncludeDeleted {
+		for _, computer := range m.deleted {
+			result = append(result, *computer)
+		}
+	}
+	return &models.ComputerPage{Items: result, Total: int64(len(result)), Limit: opts.Limit, Offset: opts.Offset}, nil
 }
 
-func (m *mockComputerRepository) GetByID(id uint) (*models.Computer, error) {
+func (m *mockComputerRepository) Stream(ctx context.Context, opts models.ComputerQueryOptions, fn func(computer *models.Computer) error) error {
+	m.lastQuery = opts
+	for id := uint(1); id < m.nextID; id++ {
+		if computer, exists := m.computers[id]; exists {
+			if err := fn(computer); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *mockComputerRepository) Search(ctx context.Context, opts models.ComputerSearchOptions) ([]models.ComputerSearchHit, error) {
+	m.lastSearch = opts
+	return []models.ComputerSearchHit{}, nil
+}
+
+func (m *mockComputerRepository) GetByID(ctx context.Context, id uint) (*models.Computer, error) {
 	computer, exists := m.computers[id]
 	if !exists {
 		return nil, errors.New("computer not found")
@@ -53,37 +94,179 @@ func (m *mockComputerRepository) GetByID(id uint) (*models.Computer, error) {
 	return computer, nil
 }
 
-func (m *mockComputerRepository) GetByEmployeeAbbreviation(abbr string) ([]models.Computer, error) {
+func (m *mockComputerRepository) GetByEmployeeAbbreviation(ctx context.Context, abbr string, includeDeleted bool) ([]models.Computer, error) {
 	var result []models.Computer
 	for _, computer := range m.computers {
 		if computer.EmployeeAbbreviation != nil && *computer.EmployeeAbbreviation == abbr {
 			result = append(result, *computer)
 		}
 	}
+	if includeDeleted {
+		for _, computer := range m.deleted {
+			if computer.EmployeeAbbreviation != nil && *computer.EmployeeAbbreviation == abbr {
+				result = append(result, *computer)
+			}
+		}
+	}
 	return result, nil
 }
 
-func (m *mockComputerRepository) Update(computer *models.Computer) error {
-	if _, exists := m.computers[computer.ID]; !exists {
+func (m *mockComputerRepository) FindDuplicateIPAddresses(ctx context.Context, network netip.Prefix) ([]models.DuplicateIPAddress, error) {
+	return []models.DuplicateIPAddress{}, nil
+}
+
+func (m *mockComputerRepository) GetByMACAddress(ctx context.Context, mac string) (*models.Computer, error) {
+	for _, computer := range m.computers {
+		if computer.MACAddress == mac {
+			return computer, nil
+		}
+	}
+	return nil, errors.New("computer not found")
+}
+
+func (m *mockComputerRepository) GetDeletedByID(ctx context.Context, id uint) (*models.Computer, error) {
+	computer, exists := m.deleted[id]
+	if !exists {
+		return nil, errors.New("computer not found")
+	}
+	return computer, nil
+}
+
+func (m *mockComputerRepository) Restore(ctx context.Context, computer *models.Computer, messages ...*models.OutboxMessage) error {
+	delete(m.deleted, computer.ID)
+	computer.DeletedAt = gorm.DeletedAt{}
+	computer.Version++
+	m.computers[computer.ID] = computer
+	m.outbox = append(m.outbox, messages...)
+	return nil
+}
+
+func (m *mockComputerRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]models.Computer, error) {
+	var purged []models.Computer
+	for id, computer := range m.deleted {
+		if computer.DeletedAt.Time.Before(deletedBefore) {
+			purged = append(purged, *computer)
+			delete(m.deleted, id)
+		}
+	}
+	return purged, nil
+}
+
+func (m *mockComputerRepository) Update(ctx context.Context, computer *models.Computer, messages ...*models.OutboxMessage) error {
+	existing, exists := m.computers[computer.ID]
+	if !exists {
 		return errors.New("computer not found")
 	}
+	if computer.Version != existing.Version {
+		return models.ErrVersionConflict
+	}
+	computer.Version++
 	m.computers[computer.ID] = computer
+	m.outbox = append(m.outbox, messages...)
 	return nil
 }
 
-func (m *mockComputerRepository) Delete(id uint) error {
-	if _, exists := m.computers[id]; !exists {
+func (m *mockComputerRepository) Delete(ctx context.Context, id uint, version uint) error {
+	existing, exists := m.computers[id]
+	if !exists {
 		return errors.New("computer not found")
 	}
+	if version != 0 && version != existing.Version {
+		return models.ErrVersionConflict
+	}
 	delete(m.computers, id)
+	existing.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	m.deleted[id] = existing
 	return nil
 }
 
-func (m *mockComputerRepository) CountByEmployee(abbr string) (int64, error) {
-	return m.countMap[abbr], nil
+func (m *mockComputerRepository) Transaction(ctx context.Context, fn func(tx models.ComputerRepository) error) error {
+	m.txCount++
+	return fn(m)
+}
+
+func (m *mockComputerRepository) LockByID(ctx context.Context, id uint) (*models.Computer, error) {
+	return m.GetByID(ctx, id)
+}
+
+func (m *mockComputerRepository) LockEmployee(ctx context.Context, abbr string) (*models.Employee, error) {
+	return m.employees.GetByAbbreviation(ctx, abbr)
+}
+
+func (m *mockComputerRepository) LockSubnet(ctx context.Context, id uint) (*models.Subnet, error) {
+	if subnet, exists := m.subnets[id]; exists {
+		return subnet, nil
+	}
+	return nil, errors.New("subnet not found")
+}
+
+func (m *mockComputerRepository) GetSubnetByIPAddress(ctx context.Context, ip string) (*models.Subnet, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil, err
+	}
+	for _, subnet := range m.subnets {
+		if subnet.Prefix().Contains(addr) {
+			return subnet, nil
+		}
+	}
+	return nil, errors.New("subnet not found")
+}
+
+func (m *mockComputerRepository) GetIPAddressesInNetwork(ctx context.Context, network netip.Prefix) ([]netip.Addr, int64, error) {
+	seen := make(map[netip.Addr]bool)
+	var addresses []netip.Addr
+	var computers int64
+	for _, computer := range m.computers {
+		addr, err := netip.ParseAddr(computer.IPAddress)
+		if err != nil || !network.Contains(addr) {
+			continue
+		}
+		computers++
+		if !seen[addr] {
+			seen[addr] = true
+			addresses = append(addresses, addr)
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i].Less(addresses[j]) })
+	return addresses, computers, nil
+}
+
+func (m *mockComputerRepository) RecordAudit(ctx context.Context, entries ...*models.AuditEntry) error {
+	m.audits = append(m.audits, entries...)
+	return nil
+}
+
+func (m *mockComputerRepository) QueueMessages(ctx context.Context, messages ...*models.OutboxMessage) error {
+	m.outbox = append(m.outbox, messages...)
+	return nil
+}
+
+func (m *mockComputerRepository) Count(ctx context.Context) (int64, int64, error) {
+	return int64(len(m.computers)), int64(len(m.deleted)), nil
+}
+
+func (m *mockComputerRepository) CountByEmployee(ctx context.Context, abbr string) (int64, error) {
+	var count int64
+	for _, computer := range m.computers {
+		if computer.EmployeeAbbreviation != nil && *computer.EmployeeAbbreviation == abbr {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *mockComputerRepository) CountByEmployees(ctx context.Context) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	for _, computer := range m.computers {
+		if computer.EmployeeAbbreviation != nil {
+			counts[*computer.EmployeeAbbreviation]++
+		}
+	}
+	return counts, nil
 }
 
-// Mock notification client for testing - FIXED
+// Mock notification client for testing
 type mockNotificationClient struct {
 	notifications []notifications.Notification
 	shouldFail    bool
@@ -103,8 +286,7 @@ func (m *mockNotificationClient) SendNotificationWithContext(ctx context.Context
 
 func TestCreateComputer(t *testing.T) {
 	repo := newMockRepository()
-	notifyClient := &mockNotificationClient{}
-	service := NewComputerService(repo, notifyClient)
+	service := NewComputerService(repo, repo.employees, repo.policies)
 
 	abbr := "abc"
 	computer := &models.Computer{
@@ -115,7 +297,7 @@ func TestCreateComputer(t *testing.T) {
 		Description:          "Test description",
 	}
 
-	err := service.CreateComputer(computer)
+	err := service.CreateComputer(context.Background(), computer)
 	if err != nil {
 		t.Errorf("Expected no error, got: %v", err)
 	}
@@ -127,8 +309,7 @@ func TestCreateComputer(t *testing.T) {
 
 func TestCreateComputerValidation(t *testing.T) {
 	repo := newMockRepository()
-	notifyClient := &mockNotificationClient{}
-	service := NewComputerService(repo, notifyClient)
+	service := NewComputerService(repo, repo.employees, repo.policies)
 
 	tests := []struct {
 		name     string
@@ -165,7 +346,7 @@ func TestCreateComputerValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := service.CreateComputer(tt.computer)
+			err := service.CreateComputer(context.Background(), tt.computer)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CreateComputer() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -173,10 +354,85 @@ func TestCreateComputerValidation(t *testing.T) {
 	}
 }
 
+func TestCreateComputerReportsAllInvalidFields(t *testing.T) {
+	repo := newMockRepository()
+	service := NewComputerService(repo, repo.employees, repo.policies)
+
+	abbr := "ABC"
+	err := service.CreateComputer(context.Background(), &models.Computer{
+		MACAddress:           "00:11:22",
+		IPAddress:            "10.0.0.256",
+		EmployeeAbbreviation: &abbr,
+	})
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected ValidationError, got: %v", err)
+	}
+	var fields []string
+	for _, fieldErr := range verr.Errors {
+		fields = append(fields, fieldErr.Field)
+	}
+	want := []string{"mac_address", "computer_name", "ip_address", "employee_abbreviation"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("Expected invalid fields %v, got %v", want, fields)
+	}
+}
+
+func TestCreateComputerDuplicateMACAddress(t *testing.T) {
+	repo := newMockRepository()
+	service := NewComputerService(repo, repo.employees, repo.policies)
+	ctx := context.Background()
+
+	if err := service.CreateComputer(ctx, &models.Computer{MACAddress: "00:11:22:33:44:55", ComputerName: "First", IPAddress: "10.0.0.1"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	err := service.CreateComputer(ctx, &models.Computer{MACAddress: "00-11-22-33-44-55", ComputerName: "Second", IPAddress: "10.0.0.2"})
+	if !errors.Is(err, ErrMACAddressInUse) {
+		t.Errorf("Expected ErrMACAddressInUse, got: %v", err)
+	}
+}
+
+func TestCreateComputerCanonicalisesAddresses(t *testing.T) {
+	repo := newMockRepository()
+	service := NewComputerService(repo, repo.employees, repo.policies)
+
+	tests := []struct {
+		mac, ip         string
+		wantMAC, wantIP string
+	}{
+		{"00-11-22-AA-BB-CC", "192.168.1.1", "00:11:22:aa:bb:cc", "192.168.1.1"},
+		{"0011.22aa.bbcd", "2001:DB8:0:0::1", "00:11:22:aa:bb:cd", "2001:db8::1"},
+		{"001122AABBCE", "::ffff:10.0.0.1", "00:11:22:aa:bb:ce", "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		computer := &models.Computer{MACAddress: tt.mac, ComputerName: "Test", IPAddress: tt.ip}
+		if err := service.CreateComputer(context.Background(), computer); err != nil {
+			t.Errorf("%s %s: expected no error, got: %v", tt.mac, tt.ip, err)
+			continue
+		}
+		if computer.MACAddress != tt.wantMAC || computer.IPAddress != tt.wantIP {
+			t.Errorf("Expected %s %s, got %s %s", tt.wantMAC, tt.wantIP, computer.MACAddress, computer.IPAddress)
+		}
+	}
+
+	for _, invalid := range []models.Computer{
+		{MACAddress: "00:11:22-33:44:55", ComputerName: "Test", IPAddress: "10.0.0.1"},
+		{MACAddress: "00:11:22:33:44:gg", ComputerName: "Test", IPAddress: "10.0.0.1"},
+		{MACAddress: "00:11:22:33:44:55", ComputerName: "Test", IPAddress: "10.0.0"},
+		{MACAddress: "00:11:22:33:44:55", ComputerName: "Test", IPAddress: "fe80::1%eth0"},
+	} {
+		computer := invalid
+		if err := service.CreateComputer(context.Background(), &computer); err == nil {
+			t.Errorf("Expected error for %s %s", invalid.MACAddress, invalid.IPAddress)
+		}
+	}
+}
+
 func TestCreateComputerNotificationTrigger(t *testing.T) {
 	repo := newMockRepository()
-	notifyClient := &mockNotificationClient{}
-	service := NewComputerService(repo, notifyClient)
+	service := NewComputerService(repo, repo.employees, repo.policies)
 
 	abbr := "abc"
 
@@ -190,27 +446,478 @@ func TestCreateComputerNotificationTrigger(t *testing.T) {
 			Description:          "Test description",
 		}
 
-		err := service.CreateComputer(computer)
+		err := service.CreateComputer(WithRequestID(context.Background(), "req-1"), computer)
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
 		}
 	}
 
-	// Wait a bit for the goroutine to complete
-	time.Sleep(100 * time.Millisecond)
+	// Check that a notification was queued when the 3rd computer was added
+	if len(repo.outbox) != 1 {
+		t.Fatalf("Expected 1 outbox message, got %d", len(repo.outbox))
+	}
 
-	// Check that notification was sent when the 3rd computer was added
-	if len(notifyClient.notifications) != 1 {
-		t.Errorf("Expected 1 notification, got %d", len(notifyClient.notifications))
+	if repo.outbox[0].RequestID != "req-1" {
+		t.Errorf("Expected the request ID to be kept for delivery, got %q", repo.outbox[0].RequestID)
+	}
+
+	var notification notifications.Notification
+	if err := json.Unmarshal([]byte(repo.outbox[0].Payload), &notification); err != nil {
+		t.Fatalf("Failed to decode outbox payload: %v", err)
+	}
+	if notification.Level != "warning" {
+		t.Errorf("Expected warning level, got %s", notification.Level)
 	}
+	if notification.EmployeeAbbreviation != abbr {
+		t.Errorf("Expected employee %s, got %s", abbr, notification.EmployeeAbbreviation)
+	}
+}
 
-	if len(notifyClient.notifications) > 0 {
-		notification := notifyClient.notifications[0]
-		if notification.Level != "warning" {
-			t.Errorf("Expected warning level, got %s", notification.Level)
+func TestGetAllComputersLimits(t *testing.T) {
+	repo := newMockRepository()
+	service := NewComputerService(repo, repo.employees, repo.policies)
+
+	tests := []struct {
+		name      string
+		limit     int
+		wantLimit int
+	}{
+		{"default limit", 0, models.DefaultPageLimit},
+		{"explicit limit", 10, 10},
+		{"clamped limit", models.MaxPageLimit + 1, models.MaxPageLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.GetAllComputers(context.Background(), models.ComputerQueryOptions{Limit: tt.limit}); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if repo.lastQuery.Limit != tt.wantLimit {
+				t.Errorf("Expected limit %d, got %d", tt.wantLimit, repo.lastQuery.Limit)
+			}
+		})
+	}
+
+	if _, err := service.GetAllComputers(context.Background(), models.ComputerQueryOptions{Offset: -1}); err == nil {
+		t.Error("Expected error for negative offset")
+	}
+}
+
+func TestExportComputers(t *testing.T) {
+	repo := newMockRepository()
+	service := NewComputerService(repo, repo.employees, repo.policies)
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		service.CreateComputer(ctx, &models.Computer{
+			MACAddress:   fmt.Sprintf("00:11:22:33:44:%02d", i),
+			ComputerName: fmt.Sprintf("Computer %d", i),
+			IPAddress:    fmt.Sprintf("10.0.0.%d", i),
+		})
+	}
+
+	exported := 0
+	err := service.ExportComputers(ctx, models.ComputerQueryOptions{EmployeeAbbreviation: "abc"}, func(computer *models.Computer) error {
+		exported++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if exported != 3 || repo.lastQuery.EmployeeAbbreviation != "abc" {
+		t.Errorf("Expected 3 computers streamed with the filter, got %d and %+v", exported, repo.lastQuery)
+	}
+
+	err = service.ExportComputers(ctx, models.ComputerQueryOptions{EmployeeAbbreviation: "zzz"}, func(computer *models.Computer) error {
+		t.Error("Expected no computers for an unknown employee")
+		return nil
+	})
+	if !errors.Is(err, ErrEmployeeNotFound) {
+		t.Errorf("Expected ErrEmployeeNotFound, got %v", err)
+	}
+}
+
+func TestSearchComputers(t *testing.T) {
+	repo := newMockRepository()
+	service := NewComputerService(repo, repo.employees, repo.policies)
+
+	tests := []struct {
+		name      string
+		limit     int
+		wantLimit int
+	}{
+		{"default limit", 0, models.DefaultSearchLimit},
+		{"explicit limit", 5, 5},
+		{"clamped limit", models.MaxSearchLimit + 1, models.MaxSearchLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.SearchComputers(context.Background(), models.ComputerSearchOptions{Query: "latitude", Limit: tt.limit}); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if repo.lastSearch.Limit != tt.wantLimit {
+				t.Errorf("Expected limit %d, got %d", tt.wantLimit, repo.lastSearch.Limit)
+			}
+		})
+	}
+
+	invalid := []models.ComputerSearchOptions{
+		{Query: "  "},
+		{Query: strings.Repeat("word ", models.MaxSearchTerms+1)},
+		{Query: "latitude", Limit: -1},
+	}
+	for _, opts := range invalid {
+		var verr *ValidationError
+		if _, err := service.SearchComputers(context.Background(), opts); !errors.As(err, &verr) {
+			t.Errorf("Expected ValidationError for %+v, got %v", opts, err)
 		}
-		if notification.EmployeeAbbreviation != abbr {
-			t.Errorf("Expected employee %s, got %s", abbr, notification.EmployeeAbbreviation)
+	}
+}
+
+func TestPatchComputer(t *testing.T) {
+	abbr := "abc"
+
+	tests := []struct {
+		name      string
+		patchType models.PatchType
+		patch     string
+		wantErr   error
+		check     func(t *testing.T, c *models.Computer)
+	}{
+		{
+			name:      "merge patch keeps omitted fields",
+			patchType: models.MergePatch,
+			patch:     `{"computer_name": "Renamed"}`,
+			check: func(t *testing.T, c *models.Computer) {
+				if c.ComputerName != "Renamed" {
+					t.Errorf("Expected name Renamed, got %s", c.ComputerName)
+				}
+				if c.EmployeeAbbreviation == nil || *c.EmployeeAbbreviation != abbr {
+					t.Error("Expected employee assignment to be kept")
+				}
+			},
+		},
+		{
+			name:      "merge patch null unassigns",
+			patchType: models.MergePatch,
+			patch:     `{"employee_abbreviation": null}`,
+			check: func(t *testing.T, c *models.Computer) {
+				if c.EmployeeAbbreviation != nil {
+					t.Error("Expected employee to be unassigned")
+				}
+			},
+		},
+		{
+			name:      "json patch replace",
+			patchType: models.JSONPatch,
+			patch:     `[{"op": "test", "path": "/ip_address", "value": "192.168.1.100"}, {"op": "replace", "path": "/ip_address", "value": "10.0.0.1"}]`,
+			check: func(t *testing.T, c *models.Computer) {
+				if c.IPAddress != "10.0.0.1" {
+					t.Errorf("Expected IP 10.0.0.1, got %s", c.IPAddress)
+				}
+			},
+		},
+		{
+			name:      "json patch failed test",
+			patchType: models.JSONPatch,
+			patch:     `[{"op": "test", "path": "/ip_address", "value": "10.9.9.9"}]`,
+			wantErr:   ErrPatchTestFailed,
+		},
+		{
+			name:      "unknown field",
+			patchType: models.MergePatch,
+			patch:     `{"colour": "red"}`,
+			wantErr:   ErrInvalidPatch,
+		},
+		{
+			name:      "malformed patch",
+			patchType: models.JSONPatch,
+			patch:     `{"op": "replace"}`,
+			wantErr:   ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockRepository()
+			service := NewComputerService(repo, repo.employees, repo.policies)
+
+			computer := &models.Computer{
+				MACAddress:           "00:11:22:33:44:55",
+				ComputerName:         "Test Computer",
+				IPAddress:            "192.168.1.100",
+				EmployeeAbbreviation: &abbr,
+			}
+			if err := service.CreateComputer(context.Background(), computer); err != nil {
+				t.Fatalf("Failed to create computer: %v", err)
+			}
+
+			patched, err := service.PatchComputer(context.Background(), computer.ID, 0, tt.patchType, []byte(tt.patch))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if patched.ID != computer.ID {
+				t.Errorf("Expected ID %d, got %d", computer.ID, patched.ID)
+			}
+			tt.check(t, patched)
+		})
+	}
+}
+
+func TestUpdateComputerCannotSetDeletedAt(t *testing.T) {
+	computerService, _ := newSubnetTestServices(t, ComputerServiceOptions{})
+	ctx := context.Background()
+
+	computer := &models.Computer{MACAddress: "00:11:22:33:44:55", ComputerName: "Test Computer", IPAddress: "192.168.1.100"}
+	if err := computerService.CreateComputer(ctx, computer); err != nil {
+		t.Fatalf("Failed to create computer: %v", err)
+	}
+
+	_, err := computerService.PatchComputer(ctx, computer.ID, 0, models.MergePatch, []byte(`{"deleted_at": "2020-01-01T00:00:00Z"}`))
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Errors[0].Field != "deleted_at" {
+		t.Errorf("Expected deleted_at to be rejected by PATCH, got %v", err)
+	}
+
+	computer.DeletedAt = gorm.DeletedAt{Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true}
+	if err := computerService.UpdateComputer(ctx, computer); !errors.As(err, &verr) || verr.Errors[0].Field != "deleted_at" {
+		t.Errorf("Expected deleted_at to be rejected by PUT, got %v", err)
+	}
+
+	if _, err := computerService.GetComputerByID(ctx, computer.ID); err != nil {
+		t.Errorf("Expected the computer to stay visible, got: %v", err)
+	}
+}
+
+func TestPatchComputerNotFound(t *testing.T) {
+	repo := newMockRepository()
+	service := NewComputerService(repo, repo.employees, repo.policies)
+
+	_, err := service.PatchComputer(context.Background(), 42, 0, models.MergePatch, []byte(`{}`))
+	if !errors.Is(err, ErrComputerNotFound) {
+		t.Errorf("Expected ErrComputerNotFound, got %v", err)
+	}
+}
+
+func TestPatchComputerReassignmentNotification(t *testing.T) {
+	repo := newMockRepository()
+	service := NewComputerService(repo, repo.employees, repo.policies)
+
+	abbr := "abc"
+	for i := 1; i <= 2; i++ {
+		service.CreateComputer(context.Background(), &models.Computer{
+			MACAddress:           fmt.Sprintf("00:11:22:33:44:%02d", i),
+			ComputerName:         fmt.Sprintf("Test Computer %d", i),
+			IPAddress:            fmt.Sprintf("192.168.1.%d", i),
+			EmployeeAbbreviation: &abbr,
+		})
+	}
+	spare := &models.Computer{
+		MACAddress:   "00:11:22:33:44:99",
+		ComputerName: "Spare",
+		IPAddress:    "192.168.1.99",
+	}
+	service.CreateComputer(context.Background(), spare)
+
+	if _, err := service.PatchComputer(context.Background(), spare.ID, 0, models.MergePatch, []byte(`{"employee_abbreviation": "abc"}`)); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(repo.outbox) != 1 {
+		t.Errorf("Expected 1 outbox message, got %d", len(repo.outbox))
+	}
+}
+
+func TestUpdateComputerVersionConflict(t *testing.T) {
+	repo := newMockRepository()
+	service := NewComputerService(repo, repo.employees, repo.policies)
+
+	computer := &models.Computer{
+		MACAddress:   "00:11:22:33:44:55",
+		ComputerName: "Test Computer",
+		IPAddress:    "192.168.1.100",
+	}
+	service.CreateComputer(context.Background(), computer)
+
+	stale := *computer
+	stale.ComputerName = "Stale"
+	stale.Version = 5
+	if err := service.UpdateComputer(context.Background(), &stale); !errors.Is(err, models.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict, got %v", err)
+	}
+
+	current := *computer
+	current.Version = 0
+	current.ComputerName = "Current"
+	if err := service.UpdateComputer(context.Background(), &current); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if current.Version != 2 {
+		t.Errorf("Expected version 2, got %d", current.Version)
+	}
+
+	if _, err := service.PatchComputer(context.Background(), computer.ID, 1, models.MergePatch, []byte(`{}`)); !errors.Is(err, models.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict for stale patch, got %v", err)
+	}
+	if err := service.DeleteComputer(context.Background(), computer.ID, 1); !errors.Is(err, models.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict for stale delete, got %v", err)
+	}
+}
+
+func TestCreateComputerConcurrentAssignments(t *testing.T) {
+	database, err := db.InitDatabase(filepath.Join(t.TempDir(), "computers.db"), "sqlite")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	employeeRepo := models.NewEmployeeRepository(database)
+	service := NewComputerService(models.NewComputerRepository(database), employeeRepo, models.NewPolicyRepository(database))
+	employeeRepo.Create(context.Background(), &models.Employee{Abbreviation: "abc", Name: "Test", Active: true})
+
+	const workers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			abbr := "abc"
+			errs <- service.CreateComputer(context.Background(), &models.Computer{
+				MACAddress:           fmt.Sprintf("00:11:22:33:44:%02d", i),
+				ComputerName:         fmt.Sprintf("Computer %d", i),
+				IPAddress:            fmt.Sprintf("10.0.0.%d", i+1),
+				EmployeeAbbreviation: &abbr,
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	messages, err := models.NewOutboxRepository(database).GetAll(context.Background(), models.OutboxQueryOptions{})
+	if err != nil {
+		t.Fatalf("Failed to load outbox: %v", err)
+	}
+	if len(messages) != workers-2 {
+		t.Fatalf("Expected %d notifications, got %d", workers-2, len(messages))
+	}
+
+	// Every count from 3 to 10 is reported exactly once
+	seen := make(map[string]bool)
+	for _, message := range messages {
+		var notification notifications.Notification
+		json.Unmarshal([]byte(message.Payload), &notification)
+		if seen[notification.Message] {
+			t.Errorf("Duplicate notification: %s", notification.Message)
 		}
+		seen[notification.Message] = true
+	}
+}
+
+func TestRestoreComputer(t *testing.T) {
+	repo := newMockRepository()
+	service := NewComputerService(repo, repo.employees, repo.policies)
+	ctx := context.Background()
+
+	abbr := "abc"
+	computer := &models.Computer{MACAddress: "00:11:22:33:44:55", ComputerName: "Test", IPAddress: "10.0.0.1", EmployeeAbbreviation: &abbr}
+	service.CreateComputer(ctx, computer)
+
+	if _, err := service.RestoreComputer(ctx, computer.ID); !errors.Is(err, ErrComputerNotDeleted) {
+		t.Errorf("Expected ErrComputerNotDeleted, got %v", err)
+	}
+	if _, err := service.RestoreComputer(ctx, 42); !errors.Is(err, ErrComputerNotFound) {
+		t.Errorf("Expected ErrComputerNotFound, got %v", err)
+	}
+
+	if err := service.DeleteComputer(ctx, computer.ID, 0); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Another computer took over the MAC address in the meantime
+	other := &models.Computer{MACAddress: "00:11:22:33:44:55", ComputerName: "Other", IPAddress: "10.0.0.2"}
+	service.CreateComputer(ctx, other)
+	if _, err := service.RestoreComputer(ctx, computer.ID); !errors.Is(err, ErrMACAddressInUse) {
+		t.Errorf("Expected ErrMACAddressInUse, got %v", err)
+	}
+	service.DeleteComputer(ctx, other.ID, 0)
+
+	// A restored assignment is checked against the policy
+	repo.policies.Create(context.Background(), &models.AssignmentPolicy{Scope: "employee", Subject: "abc", Thresholds: []models.PolicyThreshold{{Count: 1, Action: "block"}}})
+	if _, err := service.RestoreComputer(ctx, computer.ID); !errors.Is(err, ErrAssignmentBlocked) {
+		t.Errorf("Expected ErrAssignmentBlocked, got %v", err)
+	}
+	repo.policies = newMockPolicyRepository()
+	service = NewComputerService(repo, repo.employees, repo.policies)
+
+	restored, err := service.RestoreComputer(ctx, computer.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if restored.DeletedAt.Valid {
+		t.Error("Expected restored computer not to be deleted")
+	}
+	if last := repo.audits[len(repo.audits)-1]; last.Action != models.AuditRestore || last.Changes["deleted_at"].To != nil {
+		t.Errorf("Expected restore audit entry, got %+v", last)
+	}
+}
+
+func TestPurgeComputers(t *testing.T) {
+	repo := newMockRepository()
+	service := NewComputerService(repo, repo.employees, repo.policies)
+	ctx := context.Background()
+
+	for i := 1; i <= 2; i++ {
+		computer := &models.Computer{MACAddress: fmt.Sprintf("00:11:22:33:44:%02d", i), ComputerName: "Test", IPAddress: "10.0.0.1"}
+		service.CreateComputer(ctx, computer)
+		service.DeleteComputer(ctx, computer.ID, 0)
+	}
+	repo.deleted[1].DeletedAt.Time = time.Now().Add(-2 * DefaultPurgeRetention)
+
+	purged, err := service.PurgeComputers(ctx, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if purged != 1 || len(repo.deleted) != 1 {
+		t.Errorf("Expected 1 computer purged and 1 kept, got %d purged and %d kept", purged, len(repo.deleted))
+	}
+	if last := repo.audits[len(repo.audits)-1]; last.Action != models.AuditPurge || last.EntityID != 1 {
+		t.Errorf("Expected purge audit entry for computer 1, got %+v", last)
+	}
+
+	negative := -time.Hour
+	if _, err := service.PurgeComputers(ctx, &negative); err == nil {
+		t.Error("Expected error for negative retention")
+	}
+
+	// A retention of zero purges every deleted computer
+	var zero time.Duration
+	if purged, err := service.PurgeComputers(ctx, &zero); err != nil || purged != 1 || len(repo.deleted) != 0 {
+		t.Errorf("Expected the remaining computer to be purged, got %d, %v", purged, err)
+	}
+}
+
+func TestPurgeComputersConfiguredRetention(t *testing.T) {
+	repo := newMockRepository()
+	service := NewComputerServiceWithOptions(repo, repo.employees, repo.policies, ComputerServiceOptions{PurgeRetention: time.Hour})
+	ctx := context.Background()
+
+	computer := &models.Computer{MACAddress: "00:11:22:33:44:01", ComputerName: "Test", IPAddress: "10.0.0.1"}
+	service.CreateComputer(ctx, computer)
+	service.DeleteComputer(ctx, computer.ID, 0)
+	repo.deleted[computer.ID].DeletedAt.Time = time.Now().Add(-2 * time.Hour)
+
+	if purged, err := service.PurgeComputers(ctx, nil); err != nil || purged != 1 {
+		t.Errorf("Expected the computer deleted before the configured retention to be purged, got %d, %v", purged, err)
 	}
 }
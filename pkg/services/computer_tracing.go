@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"greenbone-case-study/pkg/models"
+	"greenbone-case-study/pkg/tracing"
+	"net/netip"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedComputerService starts a span for every method of the service it wraps
+type tracedComputerService struct {
+	next models.ComputerService
+}
+
+// NewTracedComputerService wraps service so each call is traced as a child of
+// the span in its context
+func NewTracedComputerService(service models.ComputerService) models.ComputerService {
+	return &tracedComputerService{next: service}
+}
+
+func startServiceSpan(ctx context.Context, method string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "ComputerService."+method, trace.WithAttributes(attributes...))
+}
+
+func (s *tracedComputerService) CreateComputer(ctx context.Context, computer *models.Computer) error {
+	ctx, span := startServiceSpan(ctx, "CreateComputer")
+	err := s.next.CreateComputer(ctx, computer)
+	span.SetAttributes(attribute.Int64("computer.id", int64(computer.ID)))
+	tracing.End(span, err)
+	return err
+}
+
+func (s *tracedComputerService) GetAllComputers(ctx context.Context, opts models.ComputerQueryOptions) (*models.ComputerPage, error) {
+	ctx, span := startServiceSpan(ctx, "GetAllComputers")
+	page, err := s.next.GetAllComputers(ctx, opts)
+	tracing.End(span, err)
+	return page, err
+}
+
+func (s *tracedComputerService) SearchComputers(ctx context.Context, opts models.ComputerSearchOptions) ([]models.ComputerSearchHit, error) {
+	ctx, span := startServiceSpan(ctx, "SearchComputers", attribute.Int("search.terms", len(models.SearchTerms(opts.Query))))
+	hits, err := s.next.SearchComputers(ctx, opts)
+	span.SetAttributes(attribute.Int("search.hits", len(hits)))
+	tracing.End(span, err)
+	return hits, err
+}
+
+func (s *tracedComputerService) GetComputerByID(ctx context.Context, id uint) (*models.Computer, error) {
+	ctx, span := startServiceSpan(ctx, "GetComputerByID", attribute.Int64("computer.id", int64(id)))
+	computer, err := s.next.GetComputerByID(ctx, id)
+	tracing.End(span, err)
+	return computer, err
+}
+
+func (s *tracedComputerService) GetComputersByEmployee(ctx context.Context, abbr string, includeDeleted bool) ([]models.Computer, error) {
+	ctx, span := startServiceSpan(ctx, "GetComputersByEmployee", attribute.String("employee.abbreviation", abbr))
+	computers, err := s.next.GetComputersByEmployee(ctx, abbr, includeDeleted)
+	tracing.End(span, err)
+	return computers, err
+}
+
+func (s *tracedComputerService) FindDuplicateIPAddresses(ctx context.Context, network netip.Prefix) ([]models.DuplicateIPAddress, error) {
+	var attrs []attribute.KeyValue
+	if network.IsValid() {
+		attrs = append(attrs, attribute.String("network", network.String()))
+	}
+	ctx, span := startServiceSpan(ctx, "FindDuplicateIPAddresses", attrs...)
+	duplicates, err := s.next.FindDuplicateIPAddresses(ctx, network)
+	span.SetAttributes(attribute.Int("duplicates", len(duplicates)))
+	tracing.End(span, err)
+	return duplicates, err
+}
+
+func (s *tracedComputerService) UpdateComputer(ctx context.Context, computer *models.Computer) error {
+	ctx, span := startServiceSpan(ctx, "UpdateComputer", attribute.Int64("computer.id", int64(computer.ID)))
+	err := s.next.UpdateComputer(ctx, computer)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *tracedComputerService) PatchComputer(ctx context.Context, id uint, version uint, patchType models.PatchType, patch []byte) (*models.Computer, error) {
+	ctx, span := startServiceSpan(ctx, "PatchComputer", attribute.Int64("computer.id", int64(id)))
+	computer, err := s.next.PatchComputer(ctx, id, version, patchType, patch)
+	tracing.End(span, err)
+	return computer, err
+}
+
+func (s *tracedComputerService) DeleteComputer(ctx context.Context, id uint, version uint) error {
+	ctx, span := startServiceSpan(ctx, "DeleteComputer", attribute.Int64("computer.id", int64(id)))
+	err := s.next.DeleteComputer(ctx, id, version)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *tracedComputerService) RestoreComputer(ctx context.Context, id uint) (*models.Computer, error) {
+	ctx, span := startServiceSpan(ctx, "RestoreComputer", attribute.Int64("computer.id", int64(id)))
+	computer, err := s.next.RestoreComputer(ctx, id)
+	tracing.End(span, err)
+	return computer, err
+}
+
+func (s *tracedComputerService) PurgeComputers(ctx context.Context, olderThan *time.Duration) (int, error) {
+	ctx, span := startServiceSpan(ctx, "PurgeComputers")
+	purged, err := s.next.PurgeComputers(ctx, olderThan)
+	span.SetAttributes(attribute.Int("computer.purged", purged))
+	tracing.End(span, err)
+	return purged, err
+}
+
+func (s *tracedComputerService) ImportComputers(ctx context.Context, computers []models.Computer, opts models.ImportOptions) (*models.ImportResult, error) {
+	ctx, span := startServiceSpan(ctx, "ImportComputers",
+		attribute.Int("import.rows", len(computers)),
+		attribute.String("import.mode", opts.Mode),
+		attribute.Bool("import.dry_run", opts.DryRun))
+	result, err := s.next.ImportComputers(ctx, computers, opts)
+	if result != nil {
+		span.SetAttributes(attribute.Int("import.failed", result.Failed), attribute.Bool("import.applied", result.Applied))
+	}
+	tracing.End(span, err)
+	return result, err
+}
+
+func (s *tracedComputerService) ExportComputers(ctx context.Context, opts models.ComputerQueryOptions, fn func(computer *models.Computer) error) error {
+	ctx, span := startServiceSpan(ctx, "ExportComputers")
+	exported := 0
+	err := s.next.ExportComputers(ctx, opts, func(computer *models.Computer) error {
+		exported++
+		return fn(computer)
+	})
+	span.SetAttributes(attribute.Int("computer.exported", exported))
+	tracing.End(span, err)
+	return err
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"greenbone-case-study/pkg/tracing"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedComputerServiceFollowsNotificationDelivery(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	repo := newMockRepository()
+	service := NewTracedComputerService(NewComputerService(repo, repo.employees, repo.policies))
+
+	ctx, request := tracing.Tracer().Start(context.Background(), "POST /api/computers")
+	abbr := "abc"
+	for i := 1; i <= 3; i++ {
+		computer := &models.Computer{
+			MACAddress:           fmt.Sprintf("00:11:22:33:44:%02d", i),
+			ComputerName:         fmt.Sprintf("Test Computer %d", i),
+			IPAddress:            fmt.Sprintf("192.168.1.%d", i),
+			EmployeeAbbreviation: &abbr,
+		}
+		if err := service.CreateComputer(ctx, computer); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+	if _, err := service.GetComputerByID(ctx, 99); err == nil {
+		t.Fatal("Expected an error for an unknown computer")
+	}
+	request.End()
+
+	traceID := request.SpanContext().TraceID()
+	for _, span := range exporter.GetSpans() {
+		if span.SpanContext.TraceID() != traceID {
+			t.Errorf("Expected %s in the request trace", span.Name)
+		}
+	}
+	if spans := exporter.GetSpans(); len(spans) != 5 || spans[0].Name != "ComputerService.CreateComputer" || spans[3].Status.Description == "" {
+		t.Fatalf("Expected 4 service spans, the last one failed, and the request span, got %v", spans)
+	}
+
+	// The queued notification is delivered later as part of the same trace
+	if len(repo.outbox) != 1 || repo.outbox[0].TraceParent == "" {
+		t.Fatalf("Expected a notification with trace context, got %+v", repo.outbox)
+	}
+	exporter.Reset()
+	outbox := newMockOutboxRepository()
+	outbox.add(ComputerLimitNotification, repo.outbox[0].Payload).TraceParent = repo.outbox[0].TraceParent
+	if _, err := NewOutboxDispatcher(outbox, &mockNotificationClient{}).DispatchOnce(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "outbox.deliver" || spans[0].SpanContext.TraceID() != traceID {
+		t.Errorf("Expected the delivery in the request trace, got %v", spans)
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"net/mail"
+	"strings"
+)
+
+var (
+	// ErrEmployeeNotFound is returned when the requested employee does not exist
+	ErrEmployeeNotFound = errors.New("employee not found")
+	// ErrEmployeeExists is returned when creating an employee whose abbreviation is taken
+	ErrEmployeeExists = errors.New("employee already exists")
+	// ErrEmployeeHasComputers is returned when deleting an employee that still has computers assigned
+	ErrEmployeeHasComputers = errors.New("employee still has computers assigned")
+)
+
+type employeeService struct {
+	repo         models.EmployeeRepository
+	computerRepo models.ComputerRepository
+}
+
+// NewEmployeeService creates a new employee service
+func NewEmployeeService(repo models.EmployeeRepository, computerRepo models.ComputerRepository) models.EmployeeService {
+	return &employeeService{
+		repo:         repo,
+		computerRepo: computerRepo,
+	}
+}
+
+// CreateEmployee creates a new, active employee with validation
+func (s *employeeService) CreateEmployee(ctx context.Context, employee *models.Employee) error {
+	if err := s.validateEmployee(employee); err != nil {
+		return err
+	}
+
+	if _, err := s.repo.GetByAbbreviation(ctx, employee.Abbreviation); err == nil {
+		return fmt.Errorf("%w: %s", ErrEmployeeExists, employee.Abbreviation)
+	}
+
+	employee.Active = true
+	if err := s.repo.Create(ctx, employee); err != nil {
+		return fmt.Errorf("failed to create employee: %w", err)
+	}
+	return nil
+}
+
+// GetAllEmployees retrieves employees matching the given filters
+func (s *employeeService) GetAllEmployees(ctx context.Context, opts models.EmployeeQueryOptions) ([]models.Employee, error) {
+	employees, err := s.repo.GetAll(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get employees: %w", err)
+	}
+	return employees, nil
+}
+
+// GetEmployee retrieves an employee by abbreviation
+func (s *employeeService) GetEmployee(ctx context.Context, abbr string) (*models.Employee, error) {
+	if err := validateEmployeeAbbreviation(abbr); err != nil {
+		return nil, invalidField("abbreviation", err.Error())
+	}
+
+	employee, err := s.repo.GetByAbbreviation(ctx, abbr)
+	if err != nil {
+		return nil, notFound(ErrEmployeeNotFound, err)
+	}
+	return employee, nil
+}
+
+// UpdateEmployee updates an employee with validation
+func (s *employeeService) UpdateEmployee(ctx context.Context, employee *models.Employee) error {
+	existing, err := s.GetEmployee(ctx, employee.Abbreviation)
+	if err != nil {
+		return err
+	}
+
+	if err := s.validateEmployee(employee); err != nil {
+		return err
+	}
+	employee.CreatedAt = existing.CreatedAt
+
+	if err := s.repo.Update(ctx, employee); err != nil {
+		return fmt.Errorf("failed to update employee: %w", err)
+	}
+	return nil
+}
+
+// DeleteEmployee deletes an employee that has no computers assigned
+func (s *employeeService) DeleteEmployee(ctx context.Context, abbr string) error {
+	if _, err := s.GetEmployee(ctx, abbr); err != nil {
+		return err
+	}
+
+	count, err := s.computerRepo.CountByEmployee(ctx, abbr)
+	if err != nil {
+		return fmt.Errorf("failed to count employee computers: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: %d computers", ErrEmployeeHasComputers, count)
+	}
+
+	if err := s.repo.Delete(ctx, abbr); err != nil {
+		return fmt.Errorf("failed to delete employee: %w", err)
+	}
+	return nil
+}
+
+// validateEmployee validates employee input data and reports every invalid field
+func (s *employeeService) validateEmployee(employee *models.Employee) error {
+	verr := &ValidationError{}
+	if err := validateEmployeeAbbreviation(employee.Abbreviation); err != nil {
+		verr.Add("abbreviation", err.Error())
+	}
+	if strings.TrimSpace(employee.Name) == "" {
+		verr.Add("name", "is required")
+	}
+	if employee.Email != "" {
+		if _, err := mail.ParseAddress(employee.Email); err != nil {
+			verr.Add("email", "is not a valid address")
+		}
+	}
+	return verr.errorOrNil()
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"greenbone-case-study/pkg/models"
+	"slices"
+	"testing"
+)
+
+// Mock employee repository for testing
+type mockEmployeeRepository struct {
+	employees map[string]*models.Employee
+}
+
+func newMockEmployeeRepository(abbrs ...string) *mockEmployeeRepository {
+	repo := &mockEmployeeRepository{employees: make(map[string]*models.Employee)}
+	for _, abbr := range abbrs {
+		repo.employees[abbr] = &models.Employee{Abbreviation: abbr, Name: abbr, Active: true}
+	}
+	return repo
+}
+
+func (m *mockEmployeeRepository) Create(ctx context.Context, employee *models.Employee) error {
+	m.employees[employee.Abbreviation] = employee
+	return nil
+}
+
+func (m *mockEmployeeRepository) GetAll(ctx context.Context, opts models.EmployeeQueryOptions) ([]models.Employee, error) {
+	var result []models.Employee
+	for _, employee := range m.employees {
+		if opts.Active != nil && employee.Active != *opts.Active {
+			continue
+		}
+		if opts.Abbreviations != nil && !slices.Contains(opts.Abbreviations, employee.Abbreviation) {
+			continue
+		}
+		result = append(result, *employee)
+	}
+	return result, nil
+}
+
+func (m *mockEmployeeRepository) GetByAbbreviation(ctx context.Context, abbr string) (*models.Employee, error) {
+	employee, exists := m.employees[abbr]
+	if !exists {
+		return nil, errors.New("record not found")
+	}
+	return employee, nil
+}
+
+func (m *mockEmployeeRepository) Update(ctx context.Context, employee *models.Employee) error {
+	m.employees[employee.Abbreviation] = employee
+	return nil
+}
+
+func (m *mockEmployeeRepository) Delete(ctx context.Context, abbr string) error {
+	delete(m.employees, abbr)
+	return nil
+}
+
+func (m *mockEmployeeRepository) Count(ctx context.Context) (int64, error) {
+	return int64(len(m.employees)), nil
+}
+
+func TestCreateEmployee(t *testing.T) {
+	service := NewEmployeeService(newMockEmployeeRepository("abc"), newMockRepository())
+
+	employee := &models.Employee{Abbreviation: "mmu", Name: "Max Mustermann", Email: "max@example.com"}
+	if err := service.CreateEmployee(context.Background(), employee); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !employee.Active {
+		t.Error("Expected new employee to be active")
+	}
+
+	duplicate := &models.Employee{Abbreviation: "abc", Name: "Duplicate"}
+	if err := service.CreateEmployee(context.Background(), duplicate); !errors.Is(err, ErrEmployeeExists) {
+		t.Errorf("Expected ErrEmployeeExists, got %v", err)
+	}
+}
+
+func TestCreateEmployeeValidation(t *testing.T) {
+	service := NewEmployeeService(newMockEmployeeRepository(), newMockRepository())
+
+	tests := []struct {
+		name     string
+		employee *models.Employee
+	}{
+		{"invalid abbreviation", &models.Employee{Abbreviation: "ABCD", Name: "Test"}},
+		{"missing name", &models.Employee{Abbreviation: "abc"}},
+		{"invalid email", &models.Employee{Abbreviation: "abc", Name: "Test", Email: "not-an-email"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := service.CreateEmployee(context.Background(), tt.employee); err == nil {
+				t.Error("Expected validation error, got nil")
+			}
+		})
+	}
+}
+
+func TestDeleteEmployeeWithComputers(t *testing.T) {
+	employeeRepo := newMockEmployeeRepository("abc", "xyz")
+	computerRepo := newMockRepository()
+	service := NewEmployeeService(employeeRepo, computerRepo)
+
+	abbr := "abc"
+	computerRepo.Create(context.Background(), &models.Computer{MACAddress: "00:11:22:33:44:55", EmployeeAbbreviation: &abbr})
+
+	if err := service.DeleteEmployee(context.Background(), "abc"); !errors.Is(err, ErrEmployeeHasComputers) {
+		t.Errorf("Expected ErrEmployeeHasComputers, got %v", err)
+	}
+	if err := service.DeleteEmployee(context.Background(), "xyz"); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if err := service.DeleteEmployee(context.Background(), "nop"); !errors.Is(err, ErrEmployeeNotFound) {
+		t.Errorf("Expected ErrEmployeeNotFound, got %v", err)
+	}
+}
+
+func TestComputerAssignmentRequiresActiveEmployee(t *testing.T) {
+	repo := newMockRepository()
+	repo.employees = newMockEmployeeRepository("abc", "old")
+	repo.employees.employees["old"].Active = false
+	service := NewComputerService(repo, repo.employees, repo.policies)
+
+	for _, abbr := range []string{"zzz", "old"} {
+		abbr := abbr
+		computer := &models.Computer{
+			MACAddress:           "00:11:22:33:44:55",
+			ComputerName:         "Test Computer",
+			IPAddress:            "192.168.1.100",
+			EmployeeAbbreviation: &abbr,
+		}
+		if err := service.CreateComputer(context.Background(), computer); err == nil {
+			t.Errorf("Expected error assigning computer to %s", abbr)
+		}
+	}
+
+	if _, err := service.GetComputersByEmployee(context.Background(), "zzz", false); !errors.Is(err, ErrEmployeeNotFound) {
+		t.Errorf("Expected ErrEmployeeNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FieldError describes why a single input field is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when input fails validation. It lists every
+// invalid field, not just the first one found.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		messages[i] = fieldErr.Field + ": " + fieldErr.Message
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+// Add records an invalid field
+func (e *ValidationError) Add(field, message string) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Message: message})
+}
+
+// errorOrNil returns e if any field was recorded and nil otherwise
+func (e *ValidationError) errorOrNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// invalidField returns a ValidationError for a single field
+func invalidField(field, message string) error {
+	return &ValidationError{Errors: []FieldError{{Field: field, Message: message}}}
+}
+
+// errInvalidID is returned for a zero resource ID
+var errInvalidID = invalidField("id", "must be a positive integer")
+
+// validateLimitOffset checks the pagination parameters of a list request
+func validateLimitOffset(limit, offset int) error {
+	verr := &ValidationError{}
+	if limit < 0 {
+		verr.Add("limit", "must not be negative")
+	}
+	if offset < 0 {
+		verr.Add("offset", "must not be negative")
+	}
+	return verr.errorOrNil()
+}
+
+// notFound reports a failed lookup as sentinel, unless it failed because its
+// context was cancelled or timed out
+func notFound(sentinel, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", sentinel, err)
+}
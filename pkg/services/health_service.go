@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"greenbone-case-study/pkg/models"
+	"sync"
+	"time"
+)
+
+// defaultHealthCheckTimeout bounds each health check
+const defaultHealthCheckTimeout = 2 * time.Second
+
+type healthService struct {
+	checks  []models.HealthCheck
+	timeout time.Duration
+}
+
+// NewHealthService creates a health service that runs checks
+func NewHealthService(checks ...models.HealthCheck) models.HealthService {
+	return &healthService{checks: checks, timeout: defaultHealthCheckTimeout}
+}
+
+// CheckReadiness runs all checks concurrently, each bounded by the check
+// timeout, and reports their results in the order of the checks
+func (s *healthService) CheckReadiness(ctx context.Context) *models.HealthReport {
+	report := &models.HealthReport{
+		Status:     models.HealthUp,
+		Components: make([]models.ComponentHealth, len(s.checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		wg.Add(1)
+		go func(i int, check models.HealthCheck) {
+			defer wg.Done()
+			report.Components[i] = s.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, component := range report.Components {
+		switch {
+		case component.Status == models.HealthUp:
+		case component.Required:
+			report.Status = models.HealthDown
+		case report.Status == models.HealthUp:
+			report.Status = models.HealthDegraded
+		}
+	}
+	return report
+}
+
+// run runs one check and measures its latency
+func (s *healthService) run(ctx context.Context, check models.HealthCheck) models.ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	component := models.ComponentHealth{
+		Name:      check.Name,
+		Status:    models.HealthUp,
+		Required:  check.Required,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		component.Status = models.HealthDown
+		component.Error = err.Error()
+	}
+	return component
+}
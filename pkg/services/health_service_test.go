@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"greenbone-case-study/pkg/models"
+	"testing"
+)
+
+func TestCheckReadiness(t *testing.T) {
+	up := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("connection refused") }
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name   string
+		checks []models.HealthCheck
+		want   string
+	}{
+		{"all up", []models.HealthCheck{{Name: "database", Required: true, Check: up}}, models.HealthUp},
+		{"optional down", []models.HealthCheck{
+			{Name: "database", Required: true, Check: up},
+			{Name: "notifications", Check: down},
+		}, models.HealthDegraded},
+		{"required down", []models.HealthCheck{
+			{Name: "database", Required: true, Check: down},
+			{Name: "notifications", Check: down},
+		}, models.HealthDown},
+		{"required timed out", []models.HealthCheck{{Name: "database", Required: true, Check: slow}}, models.HealthDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &healthService{checks: tt.checks, timeout: defaultHealthCheckTimeout / 100}
+			report := service.CheckReadiness(context.Background())
+
+			if report.Status != tt.want {
+				t.Errorf("Expected status %s, got %s", tt.want, report.Status)
+			}
+			if len(report.Components) != len(tt.checks) {
+				t.Fatalf("Expected %d components, got %d", len(tt.checks), len(report.Components))
+			}
+			for i, component := range report.Components {
+				if component.Name != tt.checks[i].Name {
+					t.Errorf("Expected component %s at %d, got %s", tt.checks[i].Name, i, component.Name)
+				}
+				if (component.Status == models.HealthDown) != (component.Error != "") {
+					t.Errorf("Expected an error exactly for down components, got %+v", component)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"sync"
+	"time"
+)
+
+// inventoryStatsMaxAge is how long computed stats are served before they are
+// computed again, so frequent metrics scrapes share one set of queries
+const inventoryStatsMaxAge = 30 * time.Second
+
+type inventoryService struct {
+	repo         models.ComputerRepository
+	employeeRepo models.EmployeeRepository
+	policyRepo   models.PolicyRepository
+	maxAge       time.Duration
+
+	// mu is held while stats are computed, so concurrent scrapes wait for
+	// one computation instead of each running their own
+	mu         sync.Mutex
+	stats      models.InventoryStats
+	computedAt time.Time
+}
+
+// NewInventoryService creates a new inventory statistics service
+func NewInventoryService(repo models.ComputerRepository, employeeRepo models.EmployeeRepository, policyRepo models.PolicyRepository) models.InventoryService {
+	return &inventoryService{
+		repo:         repo,
+		employeeRepo: employeeRepo,
+		policyRepo:   policyRepo,
+		maxAge:       inventoryStatsMaxAge,
+	}
+}
+
+// GetInventoryStats returns the inventory stats, computing them again once
+// they are older than the service's maximum age. Failures are not cached.
+func (s *inventoryService) GetInventoryStats(ctx context.Context) (*models.InventoryStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.computedAt.IsZero() || time.Since(s.computedAt) >= s.maxAge {
+		stats, err := s.computeStats(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.stats = *stats
+		s.computedAt = time.Now()
+	}
+	stats := s.stats
+	return &stats, nil
+}
+
+// computeStats counts computers and employees. An employee is over the
+// threshold if their computer count reaches a warn or block threshold of the
+// policy that applies to them; only employees with computers are loaded to
+// find that policy.
+func (s *inventoryService) computeStats(ctx context.Context) (*models.InventoryStats, error) {
+	active, deleted, err := s.repo.Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count computers: %w", err)
+	}
+	employeeCount, err := s.employeeRepo.Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count employees: %w", err)
+	}
+
+	stats := &models.InventoryStats{
+		Computers:        active,
+		DeletedComputers: deleted,
+		Employees:        employeeCount,
+	}
+
+	counts, err := s.repo.CountByEmployees(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count employee computers: %w", err)
+	}
+	if len(counts) == 0 {
+		return stats, nil
+	}
+	abbrs := make([]string, 0, len(counts))
+	for abbr := range counts {
+		abbrs = append(abbrs, abbr)
+	}
+	employees, err := s.employeeRepo.GetAll(ctx, models.EmployeeQueryOptions{Abbreviations: abbrs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve employees: %w", err)
+	}
+	policies, err := s.policyRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load assignment policies: %w", err)
+	}
+
+	for i := range employees {
+		policy := mostSpecificPolicy(policies, &employees[i])
+		if block, warn := evaluatePolicy(policy, int(counts[employees[i].Abbreviation])); block != nil || warn != nil {
+			stats.EmployeesOverThreshold++
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"greenbone-case-study/pkg/models"
+	"testing"
+)
+
+func TestGetInventoryStats(t *testing.T) {
+	repo := newMockRepository()
+	repo.employees = newMockEmployeeRepository("abc", "def", "ghi")
+	repo.policies.Create(context.Background(), &models.AssignmentPolicy{
+		Scope:      models.PolicyScopeEmployee,
+		Subject:    "def",
+		Thresholds: []models.PolicyThreshold{{Count: 2, Action: models.ThresholdWarn, Level: "info"}},
+	})
+	// A department policy applies to no one outside the department
+	repo.policies.Create(context.Background(), &models.AssignmentPolicy{
+		Scope:      models.PolicyScopeDepartment,
+		Subject:    "Sales",
+		Thresholds: []models.PolicyThreshold{{Count: 1, Action: models.ThresholdBlock}},
+	})
+
+	// abc reaches the default threshold of 3, def their own threshold of 2
+	assignments := map[string]int{"abc": 3, "def": 2, "ghi": 1}
+	for abbr, count := range assignments {
+		for i := 0; i < count; i++ {
+			abbr := abbr
+			repo.computers[repo.nextID] = &models.Computer{ID: repo.nextID, EmployeeAbbreviation: &abbr}
+			repo.nextID++
+		}
+	}
+	repo.deleted[repo.nextID] = &models.Computer{ID: repo.nextID}
+
+	service := NewInventoryService(repo, repo.employees, repo.policies)
+	stats, err := service.GetInventoryStats(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	want := models.InventoryStats{Computers: 6, DeletedComputers: 1, Employees: 3, EmployeesOverThreshold: 2}
+	if *stats != want {
+		t.Errorf("Expected %+v, got %+v", want, *stats)
+	}
+	if repo.policies.applicableLoads != 0 {
+		t.Errorf("Expected policies to be loaded once, got %d loads per employee", repo.policies.applicableLoads)
+	}
+}
+
+func TestGetInventoryStatsCachesStats(t *testing.T) {
+	repo := newMockRepository()
+	abbr := "abc"
+	repo.computers[repo.nextID] = &models.Computer{ID: repo.nextID, EmployeeAbbreviation: &abbr}
+	repo.nextID++
+
+	service := NewInventoryService(repo, repo.employees, repo.policies).(*inventoryService)
+	stats, err := service.GetInventoryStats(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if stats.Computers != 1 {
+		t.Fatalf("Expected 1 computer, got %d", stats.Computers)
+	}
+
+	// Stats are served from the cache until they are older than maxAge
+	repo.computers[repo.nextID] = &models.Computer{ID: repo.nextID}
+	repo.nextID++
+	if stats, _ := service.GetInventoryStats(context.Background()); stats.Computers != 1 {
+		t.Errorf("Expected cached stats with 1 computer, got %d", stats.Computers)
+	}
+
+	service.computedAt = service.computedAt.Add(-service.maxAge)
+	if stats, _ := service.GetInventoryStats(context.Background()); stats.Computers != 2 {
+		t.Errorf("Expected recomputed stats with 2 computers, got %d", stats.Computers)
+	}
+}
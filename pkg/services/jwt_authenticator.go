@@ -0,0 +1,442 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// defaultRoleClaim is the claim holding roles unless configured otherwise
+	defaultRoleClaim = "roles"
+	// jwksRefreshInterval is how long a fetched key set is used before it is fetched again
+	jwksRefreshInterval = time.Hour
+	// jwksMinRefreshInterval limits refetches for tokens signed with unknown keys
+	jwksMinRefreshInterval = time.Minute
+	// jwksFetchTimeout bounds a single key set request
+	jwksFetchTimeout = 10 * time.Second
+)
+
+// jwtSigningMethods are the asymmetric algorithms tokens may be signed with
+var jwtSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// JWTConfig configures bearer token verification. Keys come from JWKSURL or
+// from KeyFile, which holds either a JWKS document or PEM public keys or
+// certificates.
+type JWTConfig struct {
+	JWKSURL  string
+	KeyFile  string
+	Issuer   string
+	Audience string
+	// RoleClaim names the claim holding the caller's roles, a string or a list
+	// of strings. Nested claims are separated by dots, e.g. realm_access.roles.
+	RoleClaim string
+	// RoleMapping maps claim values to roles. Without a mapping, claim values
+	// that are role names are used as they are.
+	RoleMapping map[string]string
+	// Leeway allows for clock skew when checking expiry and not-before times
+	Leeway time.Duration
+}
+
+// verificationKey is a public key of the key set and its key ID, if any
+type verificationKey struct {
+	kid string
+	key crypto.PublicKey
+}
+
+type jwtAuthenticator struct {
+	config JWTConfig
+	parser *jwt.Parser
+	client *http.Client
+
+	// fetches runs one JWKS request at a time, outside mu, and shares its
+	// result with every caller waiting for it
+	fetches   singleflight.Group
+	mu        sync.Mutex
+	keys      []verificationKey
+	fetchedAt time.Time
+}
+
+// NewJWTAuthenticator creates an authenticator for JWTs issued by an OIDC
+// provider. Tokens must be signed by a key of the configured key set and carry
+// the configured issuer and audience and an expiry.
+func NewJWTAuthenticator(config JWTConfig) (models.TokenAuthenticator, error) {
+	if (config.JWKSURL == "") == (config.KeyFile == "") {
+		return nil, errors.New("exactly one of JWKS URL and key file is required")
+	}
+	if config.Issuer == "" || config.Audience == "" {
+		return nil, errors.New("issuer and audience are required")
+	}
+	if config.RoleClaim == "" {
+		config.RoleClaim = defaultRoleClaim
+	}
+	for value, role := range config.RoleMapping {
+		if !models.ValidRole(role) {
+			return nil, fmt.Errorf("invalid role %q for claim value %q", role, value)
+		}
+	}
+
+	a := &jwtAuthenticator{
+		config: config,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(jwtSigningMethods),
+			jwt.WithIssuer(config.Issuer),
+			jwt.WithAudience(config.Audience),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(config.Leeway),
+		),
+		client: &http.Client{Timeout: jwksFetchTimeout},
+	}
+
+	// A key file is loaded once; a JWKS URL is fetched on first use
+	if config.KeyFile != "" {
+		keys, err := loadKeyFile(config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		a.keys = keys
+	}
+	return a, nil
+}
+
+// Authenticate verifies a JWT and returns its principal, or an error wrapping
+// ErrInvalidCredentials
+func (a *jwtAuthenticator) Authenticate(ctx context.Context, token string) (*models.Principal, error) {
+	claims := jwt.MapClaims{}
+	keyFunc := func(token *jwt.Token) (interface{}, error) { return a.keyFunc(ctx, token) }
+	if _, err := a.parser.ParseWithClaims(token, claims, keyFunc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	subject, _ := claims.GetSubject()
+	if subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+
+	return &models.Principal{
+		Subject: subject,
+		Role:    a.role(claims),
+		Method:  models.AuthMethodJWT,
+	}, nil
+}
+
+// keyFunc returns the keys a token may be signed with: those with its key ID,
+// or all keys if it has none. Keys without an ID, e.g. from PEM files, match
+// any token.
+func (a *jwtAuthenticator) keyFunc(ctx context.Context, token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	keys, err := a.keySet(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	var set jwt.VerificationKeySet
+	for _, key := range keys {
+		if kid == "" || key.kid == "" || key.kid == kid {
+			set.Keys = append(set.Keys, key.key)
+		}
+	}
+	if len(set.Keys) == 0 {
+		return nil, fmt.Errorf("no key with ID %q", kid)
+	}
+	return set, nil
+}
+
+// keySet returns the current keys. A JWKS URL is fetched again once the keys
+// are old, or when a token names a key ID that is not known yet, at most once
+// per jwksMinRefreshInterval so unknown key IDs cannot flood the provider.
+// Tokens whose key is known do not wait for a refresh of old keys.
+func (a *jwtAuthenticator) keySet(ctx context.Context, kid string) ([]verificationKey, error) {
+	a.mu.Lock()
+	keys, age := a.keys, time.Since(a.fetchedAt)
+	a.mu.Unlock()
+
+	if a.config.JWKSURL == "" {
+		return keys, nil
+	}
+
+	known := keys != nil && (kid == "" || hasKeyID(keys, kid))
+	stale := keys == nil || age >= jwksRefreshInterval
+	unknown := kid != "" && !hasKeyID(keys, kid) && age >= jwksMinRefreshInterval
+	switch {
+	case !stale && !unknown:
+		return keys, nil
+	case known:
+		a.fetches.DoChan(a.config.JWKSURL, func() (interface{}, error) {
+			return a.refresh(context.WithoutCancel(ctx))
+		})
+		return keys, nil
+	}
+
+	fetch := a.fetches.DoChan(a.config.JWKSURL, func() (interface{}, error) {
+		return a.refresh(ctx)
+	})
+	select {
+	case result := <-fetch:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Val.([]verificationKey), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refresh fetches the key set and stores it. While the provider is
+// unavailable the previous keys are kept and returned.
+func (a *jwtAuthenticator) refresh(ctx context.Context) ([]verificationKey, error) {
+	keys, err := a.fetchJWKS(ctx)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	// A fetch abandoned by its request does not count as an attempt
+	if ctx.Err() == nil {
+		a.fetchedAt = time.Now()
+	}
+	if err != nil {
+		if a.keys == nil {
+			return nil, err
+		}
+		return a.keys, nil
+	}
+	a.keys = keys
+	return keys, nil
+}
+
+// fetchJWKS downloads and parses the key set
+func (a *jwtAuthenticator) fetchJWKS(ctx context.Context) ([]verificationKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.config.JWKSURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var set jsonWebKeySet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+	return set.verificationKeys()
+}
+
+// role maps the role claim to the most privileged role it grants
+func (a *jwtAuthenticator) role(claims jwt.MapClaims) string {
+	var value interface{} = map[string]interface{}(claims)
+	for _, name := range strings.Split(a.config.RoleClaim, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = object[name]
+	}
+
+	var values []string
+	switch v := value.(type) {
+	case string:
+		values = strings.Fields(v)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	roles := make([]string, 0, len(values))
+	for _, v := range values {
+		if a.config.RoleMapping != nil {
+			v = a.config.RoleMapping[v]
+		}
+		roles = append(roles, v)
+	}
+	return models.HighestRole(roles)
+}
+
+// hasKeyID reports whether keys contain a key with the given ID
+func hasKeyID(keys []verificationKey, kid string) bool {
+	for _, key := range keys {
+		if key.kid == kid {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonWebKeySet is a JWKS document (RFC 7517)
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// jsonWebKey holds the public parameters of RSA, EC and Ed25519 keys
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// verificationKeys returns the signing keys of the set. Encryption keys and
+// unsupported key types are skipped.
+func (s jsonWebKeySet) verificationKeys() ([]verificationKey, error) {
+	var keys []verificationKey
+	for _, jwk := range s.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", jwk.Kid, err)
+		}
+		if key != nil {
+			keys = append(keys, verificationKey{kid: jwk.Kid, key: key})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("key set has no signing keys")
+	}
+	return keys, nil
+}
+
+// publicKey decodes the key, or returns nil for unsupported key types
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeKeyParam(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeKeyParam(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if len(e) > 4 {
+			return nil, errors.New("RSA exponent too large")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeKeyParam(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeKeyParam(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return key, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := decodeKeyParam(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, nil
+}
+
+// decodeKeyParam decodes a base64url key parameter
+func decodeKeyParam(param string) ([]byte, error) {
+	if param == "" {
+		return nil, errors.New("missing key parameter")
+	}
+	return base64.RawURLEncoding.DecodeString(param)
+}
+
+// loadKeyFile reads a JWKS document or PEM encoded public keys and certificates
+func loadKeyFile(path string) ([]verificationKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		var set jsonWebKeySet
+		if err := json.Unmarshal(data, &set); err != nil {
+			return nil, fmt.Errorf("failed to decode JWKS file: %w", err)
+		}
+		return set.verificationKeys()
+	}
+
+	var keys []verificationKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		var key crypto.PublicKey
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+				key = cert.PublicKey
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", strings.ToLower(block.Type), err)
+		}
+		keys = append(keys, verificationKey{key: key})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("key file has no public keys")
+	}
+	return keys, nil
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"greenbone-case-study/pkg/models"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://sso.example.com"
+	testAudience = "computer-management-api"
+)
+
+// testJWKSServer serves the public keys of its signing keys as a JWKS. While
+// slow is set, responses wait until release is closed.
+type testJWKSServer struct {
+	*httptest.Server
+	keys    map[string]*rsa.PrivateKey
+	fetches atomic.Int32
+	slow    atomic.Bool
+	release chan struct{}
+}
+
+func newTestJWKSServer(t *testing.T, kids ...string) *testJWKSServer {
+	s := &testJWKSServer{keys: make(map[string]*rsa.PrivateKey), release: make(chan struct{})}
+	for _, kid := range kids {
+		s.addKey(t, kid)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		if s.slow.Load() {
+			select {
+			case <-s.release:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		var set jsonWebKeySet
+		for kid, key := range s.keys {
+			set.Keys = append(set.Keys, jsonWebKey{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testJWKSServer) addKey(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	s.keys[kid] = key
+}
+
+// sign returns a token signed with the key kid, with valid standard claims
+// overridden by claims
+func (s *testJWKSServer) sign(t *testing.T, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, validClaims(claims))
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(s.keys[kid])
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func validClaims(claims jwt.MapClaims) jwt.MapClaims {
+	result := jwt.MapClaims{
+		"iss": testIssuer,
+		"aud": testAudience,
+		"sub": "alice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		if value == nil {
+			delete(result, name)
+		} else {
+			result[name] = value
+		}
+	}
+	return result
+}
+
+func TestJWTAuthenticatorClaims(t *testing.T) {
+	server := newTestJWKSServer(t, "key-1")
+	authenticator, err := NewJWTAuthenticator(JWTConfig{
+		JWKSURL:     server.URL,
+		Issuer:      testIssuer,
+		Audience:    testAudience,
+		RoleClaim:   "realm_access.roles",
+		RoleMapping: map[string]string{"it-admins": models.RoleAdmin, "helpdesk": models.RoleOperator},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	roles := func(values ...interface{}) map[string]interface{} {
+		return map[string]interface{}{"roles": values}
+	}
+
+	tests := []struct {
+		name     string
+		claims   jwt.MapClaims
+		wantErr  bool
+		wantRole string
+	}{
+		{"mapped role", jwt.MapClaims{"realm_access": roles("helpdesk")}, false, models.RoleOperator},
+		{"highest role wins", jwt.MapClaims{"realm_access": roles("helpdesk", "it-admins")}, false, models.RoleAdmin},
+		{"unmapped values grant nothing", jwt.MapClaims{"realm_access": roles("admin")}, false, ""},
+		{"no role claim", jwt.MapClaims{}, false, ""},
+		{"wrong issuer", jwt.MapClaims{"iss": "https://evil.example.com"}, true, ""},
+		{"wrong audience", jwt.MapClaims{"aud": "other-api"}, true, ""},
+		{"expired", jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}, true, ""},
+		{"no expiry", jwt.MapClaims{"exp": nil}, true, ""},
+		{"no subject", jwt.MapClaims{"sub": nil}, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := authenticator.Authenticate(context.Background(), server.sign(t, "key-1", tt.claims))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCredentials) {
+					t.Errorf("Expected ErrInvalidCredentials, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if principal.Subject != "alice" || principal.Role != tt.wantRole || principal.Method != models.AuthMethodJWT {
+				t.Errorf("Unexpected principal %+v", principal)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticatorKeyRotation(t *testing.T) {
+	server := newTestJWKSServer(t, "key-1")
+	authenticator, err := NewJWTAuthenticator(JWTConfig{JWKSURL: server.URL, Issuer: testIssuer, Audience: testAudience})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	a := authenticator.(*jwtAuthenticator)
+
+	claims := jwt.MapClaims{"roles": []interface{}{"viewer"}}
+	principal, err := a.Authenticate(context.Background(), server.sign(t, "key-1", claims))
+	if err != nil || principal.Role != models.RoleViewer {
+		t.Fatalf("Expected viewer principal, got %+v, %v", principal, err)
+	}
+
+	// A token from a key the provider rotated in is accepted once the key set
+	// may be refetched
+	server.addKey(t, "key-2")
+	rotated := server.sign(t, "key-2", claims)
+	if _, err := a.Authenticate(context.Background(), rotated); err == nil {
+		t.Error("Expected unknown key to be rejected right after a fetch")
+	}
+	a.fetchedAt = time.Now().Add(-jwksMinRefreshInterval)
+	if _, err := a.Authenticate(context.Background(), rotated); err != nil {
+		t.Errorf("Expected rotated key to be accepted, got: %v", err)
+	}
+	if fetches := server.fetches.Load(); fetches != 2 {
+		t.Errorf("Expected 2 JWKS fetches, got %d", fetches)
+	}
+
+	// Tokens signed by anyone else are rejected
+	forged := newTestJWKSServer(t, "key-1").sign(t, "key-1", claims)
+	if _, err := a.Authenticate(context.Background(), forged); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected forged token to be rejected, got: %v", err)
+	}
+
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, validClaims(claims))
+	unsigned, _ := none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if _, err := a.Authenticate(context.Background(), unsigned); err == nil {
+		t.Error("Expected unsigned token to be rejected")
+	}
+}
+
+func TestJWTAuthenticatorSlowProvider(t *testing.T) {
+	server := newTestJWKSServer(t, "key-1")
+	authenticator, err := NewJWTAuthenticator(JWTConfig{JWKSURL: server.URL, Issuer: testIssuer, Audience: testAudience})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	a := authenticator.(*jwtAuthenticator)
+
+	token := server.sign(t, "key-1", nil)
+	if _, err := a.Authenticate(context.Background(), token); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Old keys are refreshed in the background, tokens of known keys do not wait
+	server.slow.Store(true)
+	defer close(server.release)
+	a.mu.Lock()
+	a.fetchedAt = time.Now().Add(-jwksRefreshInterval)
+	a.mu.Unlock()
+
+	done := make(chan error)
+	go func() {
+		_, err := a.Authenticate(context.Background(), token)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected the cached key to verify the token, got: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a token of a known key not to wait for the provider")
+	}
+
+	// A token of an unknown key waits for the fetch only as long as its request
+	server.addKey(t, "key-2")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := a.Authenticate(ctx, server.sign(t, "key-2", nil)); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected the token to be rejected when its request ends, got: %v", err)
+	}
+}
+
+func TestJWTAuthenticatorKeyFile(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "sso.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	authenticator, err := NewJWTAuthenticator(JWTConfig{KeyFile: path, Issuer: testIssuer, Audience: testAudience})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, validClaims(jwt.MapClaims{"roles": "operator"})).SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	principal, err := authenticator.Authenticate(context.Background(), token)
+	if err != nil || principal.Role != models.RoleOperator {
+		t.Errorf("Expected operator principal, got %+v, %v", principal, err)
+	}
+}
+
+func TestNewJWTAuthenticatorConfig(t *testing.T) {
+	tests := []JWTConfig{
+		{Issuer: testIssuer, Audience: testAudience},
+		{JWKSURL: "https://sso.example.com/jwks", KeyFile: "sso.pem", Issuer: testIssuer, Audience: testAudience},
+		{JWKSURL: "https://sso.example.com/jwks", Audience: testAudience},
+		{JWKSURL: "https://sso.example.com/jwks", Issuer: testIssuer},
+		{JWKSURL: "https://sso.example.com/jwks", Issuer: testIssuer, Audience: testAudience, RoleMapping: map[string]string{"x": "root"}},
+	}
+	for _, config := range tests {
+		if _, err := NewJWTAuthenticator(config); err == nil {
+			t.Errorf("Expected error for %+v", config)
+		}
+	}
+}
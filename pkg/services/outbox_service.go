@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"greenbone-case-study/pkg/notifications"
+	"greenbone-case-study/pkg/tracing"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ComputerLimitNotification is the outbox kind for notifications about employees reaching a warn threshold
+const ComputerLimitNotification = "computer_limit"
+
+const (
+	defaultOutboxPollInterval = 5 * time.Second
+	defaultOutboxBatchSize    = 20
+	defaultOutboxMaxAttempts  = 10
+	defaultOutboxListLimit    = 100
+
+	// outboxLease is how long a claimed message is hidden from other dispatchers
+	outboxLease = 2 * time.Minute
+	// outboxRecordTimeout bounds recording the outcome of a delivery, which
+	// outlives the cancellation of the dispatcher
+	outboxRecordTimeout = 5 * time.Second
+	// outboxStuckAfter is the age after which an undelivered message counts as stuck
+	outboxStuckAfter = 15 * time.Minute
+	// outboxRetryBaseDelay and outboxRetryMaxDelay bound the exponential backoff between attempts
+	outboxRetryBaseDelay = 30 * time.Second
+	outboxRetryMaxDelay  = time.Hour
+)
+
+var (
+	// ErrOutboxMessageNotFound is returned when the requested outbox message does not exist
+	ErrOutboxMessageNotFound = errors.New("outbox message not found")
+	// ErrOutboxMessageDelivered is returned when replaying an already delivered message
+	ErrOutboxMessageDelivered = errors.New("outbox message already delivered")
+	// ErrOutboxMessageLeased is returned when replaying a message a dispatcher is delivering
+	ErrOutboxMessageLeased = errors.New("outbox message is being delivered")
+	// ErrOutboxMessageChanged is returned when a message changed while it was replayed
+	ErrOutboxMessageChanged = errors.New("outbox message changed concurrently")
+)
+
+type outboxService struct {
+	repo models.OutboxRepository
+}
+
+// NewOutboxService creates a new outbox service
+func NewOutboxService(repo models.OutboxRepository) models.OutboxService {
+	return &outboxService{repo: repo}
+}
+
+// GetMessages retrieves outbox messages matching the given filters
+func (s *outboxService) GetMessages(ctx context.Context, opts models.OutboxQueryOptions) ([]models.OutboxMessage, error) {
+	switch opts.Status {
+	case "", models.OutboxPending, models.OutboxDelivered, models.OutboxFailed:
+	default:
+		return nil, invalidField("status", "must be pending, delivered or failed")
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = defaultOutboxListLimit
+	}
+
+	messages, err := s.repo.GetAll(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outbox messages: %w", err)
+	}
+	return messages, nil
+}
+
+// GetStuckMessages retrieves pending messages that should have been delivered long ago
+func (s *outboxService) GetStuckMessages(ctx context.Context, limit int) ([]models.OutboxMessage, error) {
+	before := time.Now().Add(-outboxStuckAfter)
+	return s.GetMessages(ctx, models.OutboxQueryOptions{
+		Status:        models.OutboxPending,
+		CreatedBefore: &before,
+		Limit:         limit,
+	})
+}
+
+// ReplayMessage schedules a failed or stuck message for immediate redelivery
+// with a fresh attempt budget. Messages a dispatcher is delivering cannot be
+// replayed until their lease expires.
+func (s *outboxService) ReplayMessage(ctx context.Context, id uint) (*models.OutboxMessage, error) {
+	message, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, notFound(ErrOutboxMessageNotFound, err)
+	}
+	if message.Status == models.OutboxDelivered {
+		return nil, ErrOutboxMessageDelivered
+	}
+	now := time.Now()
+	if message.LeasedUntil != nil && message.LeasedUntil.After(now) {
+		return nil, ErrOutboxMessageLeased
+	}
+
+	replayed, err := s.repo.Replay(ctx, message, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay outbox message: %w", err)
+	}
+	if !replayed {
+		return nil, ErrOutboxMessageChanged
+	}
+	return message, nil
+}
+
+// OutboxDispatcher delivers pending outbox messages through a NotificationClient
+type OutboxDispatcher struct {
+	repo   models.OutboxRepository
+	client notifications.NotificationClient
+	logger *slog.Logger
+
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+
+	// stop ends polling, abort cancels deliveries in progress and done is
+	// closed when Run returns
+	stop, abort, done   chan struct{}
+	stopOnce, abortOnce sync.Once
+}
+
+// NewOutboxDispatcher creates a dispatcher with default settings
+func NewOutboxDispatcher(repo models.OutboxRepository, client notifications.NotificationClient) *OutboxDispatcher {
+	return &OutboxDispatcher{
+		repo:         repo,
+		client:       client,
+		logger:       slog.Default().With("component", "outbox"),
+		PollInterval: defaultOutboxPollInterval,
+		BatchSize:    defaultOutboxBatchSize,
+		MaxAttempts:  defaultOutboxMaxAttempts,
+		stop:         make(chan struct{}),
+		abort:        make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+// Run dispatches due messages every PollInterval until ctx is cancelled or
+// Shutdown is called
+func (d *OutboxDispatcher) Run(ctx context.Context) {
+	defer close(d.done)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-d.abort:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.DispatchOnce(ctx); err != nil && ctx.Err() == nil {
+			d.logger.ErrorContext(ctx, "Dispatch failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown stops Run from claiming further messages and waits for deliveries
+// in progress. If ctx ends first they are cancelled, and their messages are
+// retried by the next dispatcher once their lease expires. Run must have been
+// started.
+func (d *OutboxDispatcher) Shutdown(ctx context.Context) error {
+	d.stopOnce.Do(func() { close(d.stop) })
+
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		d.abortOnce.Do(func() { close(d.abort) })
+		<-d.done
+		return ctx.Err()
+	}
+}
+
+// stopping reports whether Shutdown has been called
+func (d *OutboxDispatcher) stopping() bool {
+	select {
+	case <-d.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// DispatchOnce delivers one batch of due messages and returns how many were delivered
+func (d *OutboxDispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	now := time.Now()
+	messages, err := d.repo.GetDue(ctx, now, d.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load due outbox messages: %w", err)
+	}
+
+	delivered := 0
+	for i := range messages {
+		if ctx.Err() != nil || d.stopping() {
+			break
+		}
+
+		// Deliveries run one after another, so each lease starts at its claim
+		message := &messages[i]
+		claimed, err := d.repo.Claim(ctx, message, time.Now().Add(outboxLease))
+		if err != nil {
+			return delivered, fmt.Errorf("failed to claim outbox message %d: %w", message.ID, err)
+		}
+		if !claimed {
+			continue
+		}
+
+		if d.deliver(ctx, message) {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// deliver sends a claimed message and records the outcome. The delivery is
+// traced and logged as part of the request that queued the message.
+func (d *OutboxDispatcher) deliver(ctx context.Context, message *models.OutboxMessage) bool {
+	if message.RequestID != "" {
+		ctx = WithRequestID(ctx, message.RequestID)
+	}
+	ctx, span := tracing.Tracer().Start(tracing.WithTraceParent(ctx, message.TraceParent), "outbox.deliver",
+		trace.WithAttributes(
+			attribute.Int64("outbox.message_id", int64(message.ID)),
+			attribute.String("outbox.kind", message.Kind),
+			attribute.Int("outbox.attempt", message.Attempts),
+		))
+	err := d.send(ctx, message)
+	tracing.End(span, err)
+	if err != nil && ctx.Err() != nil {
+		// Shutting down: the lease expires and the message is retried later
+		return false
+	}
+
+	// A message that was sent is recorded as such even when shutting down, so
+	// it is not sent again
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), outboxRecordTimeout)
+	defer cancel()
+
+	now := time.Now()
+	message.LeasedUntil = nil
+	if err == nil {
+		message.Status = models.OutboxDelivered
+		message.DeliveredAt = &now
+		message.LastError = ""
+	} else {
+		message.LastError = truncate(err.Error(), 1000)
+		if message.Attempts >= d.MaxAttempts {
+			message.Status = models.OutboxFailed
+			d.logger.ErrorContext(ctx, "Message failed permanently",
+				"message_id", message.ID, "attempts", message.Attempts, "error", err)
+		} else {
+			message.NextAttemptAt = now.Add(retryDelay(message.Attempts))
+			d.logger.WarnContext(ctx, "Message delivery failed, retrying",
+				"message_id", message.ID, "attempt", message.Attempts, "next_attempt_at", message.NextAttemptAt, "error", err)
+		}
+	}
+
+	if updateErr := d.repo.Update(recordCtx, message); updateErr != nil {
+		d.logger.ErrorContext(ctx, "Failed to record delivery state", "message_id", message.ID, "error", updateErr)
+	}
+	return err == nil
+}
+
+// send decodes the message payload and hands it to the notification client
+func (d *OutboxDispatcher) send(ctx context.Context, message *models.OutboxMessage) error {
+	switch message.Kind {
+	case ComputerLimitNotification:
+		var notification notifications.Notification
+		if err := json.Unmarshal([]byte(message.Payload), &notification); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		return d.client.SendNotificationWithContext(ctx, notification)
+	}
+	return fmt.Errorf("unknown outbox message kind %q", message.Kind)
+}
+
+// retryDelay returns the exponential backoff after the given number of attempts
+func retryDelay(attempts int) time.Duration {
+	delay := outboxRetryBaseDelay
+	for i := 1; i < attempts && delay < outboxRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > outboxRetryMaxDelay {
+		delay = outboxRetryMaxDelay
+	}
+	return delay
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,362 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"greenbone-case-study/pkg/models"
+	"greenbone-case-study/pkg/notifications"
+	"testing"
+	"time"
+)
+
+// Mock outbox repository for testing
+type mockOutboxRepository struct {
+	messages map[uint]*models.OutboxMessage
+	nextID   uint
+	leases   []time.Time
+}
+
+func newMockOutboxRepository() *mockOutboxRepository {
+	return &mockOutboxRepository{
+		messages: make(map[uint]*models.OutboxMessage),
+		nextID:   1,
+	}
+}
+
+func (m *mockOutboxRepository) add(kind, payload string) *models.OutboxMessage {
+	message := &models.OutboxMessage{
+		ID:            m.nextID,
+		Kind:          kind,
+		Payload:       payload,
+		Status:        models.OutboxPending,
+		NextAttemptAt: time.Now().Add(-time.Second),
+		CreatedAt:     time.Now(),
+	}
+	m.nextID++
+	m.messages[message.ID] = message
+	return message
+}
+
+func (m *mockOutboxRepository) GetByID(ctx context.Context, id uint) (*models.OutboxMessage, error) {
+	message, exists := m.messages[id]
+	if !exists {
+		return nil, errors.New("record not found")
+	}
+	copy := *message
+	return &copy, nil
+}
+
+func (m *mockOutboxRepository) GetAll(ctx context.Context, opts models.OutboxQueryOptions) ([]models.OutboxMessage, error) {
+	var result []models.OutboxMessage
+	for _, message := range m.messages {
+		if opts.Status != "" && message.Status != opts.Status {
+			continue
+		}
+		if opts.CreatedBefore != nil && !message.CreatedAt.Before(*opts.CreatedBefore) {
+			continue
+		}
+		result = append(result, *message)
+	}
+	return result, nil
+}
+
+func (m *mockOutboxRepository) GetDue(ctx context.Context, now time.Time, limit int) ([]models.OutboxMessage, error) {
+	var result []models.OutboxMessage
+	for _, message := range m.messages {
+		if message.Status == models.OutboxPending && !message.NextAttemptAt.After(now) {
+			result = append(result, *message)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockOutboxRepository) Claim(ctx context.Context, message *models.OutboxMessage, leaseUntil time.Time) (bool, error) {
+	stored := m.messages[message.ID]
+	if stored.Attempts != message.Attempts || stored.Status != models.OutboxPending {
+		return false, nil
+	}
+	m.leases = append(m.leases, leaseUntil)
+	stored.Attempts++
+	stored.NextAttemptAt = leaseUntil
+	stored.LeasedUntil = &leaseUntil
+	message.Attempts = stored.Attempts
+	message.NextAttemptAt = leaseUntil
+	message.LeasedUntil = &leaseUntil
+	return true, nil
+}
+
+func (m *mockOutboxRepository) Replay(ctx context.Context, message *models.OutboxMessage, now time.Time) (bool, error) {
+	stored := m.messages[message.ID]
+	if stored.Status == models.OutboxDelivered || stored.Attempts != message.Attempts ||
+		(stored.LeasedUntil != nil && stored.LeasedUntil.After(now)) {
+		return false, nil
+	}
+	stored.Status = models.OutboxPending
+	stored.Attempts = 0
+	stored.NextAttemptAt = now
+	stored.LeasedUntil = nil
+	*message = *stored
+	return true, nil
+}
+
+func (m *mockOutboxRepository) Update(ctx context.Context, message *models.OutboxMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	copy := *message
+	m.messages[message.ID] = &copy
+	return nil
+}
+
+const testNotificationPayload = `{"level":"warning","employeeAbbreviation":"abc","message":"Employee abc has been assigned 3 computers"}`
+
+func TestOutboxDispatcherDelivers(t *testing.T) {
+	repo := newMockOutboxRepository()
+	client := &mockNotificationClient{}
+	dispatcher := NewOutboxDispatcher(repo, client)
+
+	message := repo.add(ComputerLimitNotification, testNotificationPayload)
+
+	delivered, err := dispatcher.DispatchOnce(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if delivered != 1 || len(client.notifications) != 1 {
+		t.Fatalf("Expected 1 delivered notification, got %d", len(client.notifications))
+	}
+	if client.notifications[0].EmployeeAbbreviation != "abc" {
+		t.Errorf("Expected employee abc, got %s", client.notifications[0].EmployeeAbbreviation)
+	}
+
+	stored := repo.messages[message.ID]
+	if stored.Status != models.OutboxDelivered || stored.DeliveredAt == nil || stored.Attempts != 1 {
+		t.Errorf("Expected delivered message after 1 attempt, got %+v", stored)
+	}
+
+	// Delivered messages are not sent again
+	dispatcher.DispatchOnce(context.Background())
+	if len(client.notifications) != 1 {
+		t.Errorf("Expected no redelivery, got %d notifications", len(client.notifications))
+	}
+}
+
+func TestOutboxDispatcherRetriesAndFails(t *testing.T) {
+	repo := newMockOutboxRepository()
+	client := &mockNotificationClient{shouldFail: true}
+	dispatcher := NewOutboxDispatcher(repo, client)
+	dispatcher.MaxAttempts = 2
+
+	message := repo.add(ComputerLimitNotification, testNotificationPayload)
+
+	dispatcher.DispatchOnce(context.Background())
+	stored := repo.messages[message.ID]
+	if stored.Status != models.OutboxPending || stored.Attempts != 1 || stored.LastError == "" {
+		t.Fatalf("Expected pending message with error after first attempt, got %+v", stored)
+	}
+	if !stored.NextAttemptAt.After(time.Now()) {
+		t.Error("Expected next attempt to be scheduled in the future")
+	}
+
+	// Make the retry due and exhaust the attempts
+	stored.NextAttemptAt = time.Now().Add(-time.Second)
+	dispatcher.DispatchOnce(context.Background())
+	stored = repo.messages[message.ID]
+	if stored.Status != models.OutboxFailed || stored.Attempts != 2 {
+		t.Fatalf("Expected failed message after 2 attempts, got %+v", stored)
+	}
+
+	// Replay resets the message for delivery
+	client.shouldFail = false
+	service := NewOutboxService(repo)
+	if _, err := service.ReplayMessage(context.Background(), message.ID); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if delivered, _ := dispatcher.DispatchOnce(context.Background()); delivered != 1 {
+		t.Errorf("Expected replayed message to be delivered, got %d", delivered)
+	}
+	if _, err := service.ReplayMessage(context.Background(), message.ID); !errors.Is(err, ErrOutboxMessageDelivered) {
+		t.Errorf("Expected ErrOutboxMessageDelivered, got %v", err)
+	}
+}
+
+func TestOutboxServiceReplayConflicts(t *testing.T) {
+	repo := newMockOutboxRepository()
+	service := NewOutboxService(repo)
+	message := repo.add(ComputerLimitNotification, testNotificationPayload)
+
+	// A message being delivered cannot be replayed until its lease expires
+	claimed := *message
+	repo.Claim(context.Background(), &claimed, time.Now().Add(time.Minute))
+	if _, err := service.ReplayMessage(context.Background(), message.ID); !errors.Is(err, ErrOutboxMessageLeased) {
+		t.Errorf("Expected ErrOutboxMessageLeased, got %v", err)
+	}
+
+	expired := time.Now().Add(-time.Second)
+	message.LeasedUntil = &expired
+	replayed, err := service.ReplayMessage(context.Background(), message.ID)
+	if err != nil || replayed.Attempts != 0 || replayed.LeasedUntil != nil {
+		t.Fatalf("Expected the message to be replayed, got %+v, %v", replayed, err)
+	}
+
+	// A replay of a copy read before a claim loses the race
+	stale := *message
+	claimed = *message
+	repo.Claim(context.Background(), &claimed, time.Now().Add(-time.Second))
+	if replayed, _ := repo.Replay(context.Background(), &stale, time.Now()); replayed {
+		t.Error("Expected a stale replay to fail")
+	}
+}
+
+func TestOutboxDispatcherUnknownKind(t *testing.T) {
+	repo := newMockOutboxRepository()
+	dispatcher := NewOutboxDispatcher(repo, &mockNotificationClient{})
+	dispatcher.MaxAttempts = 1
+
+	message := repo.add("unknown", "{}")
+	dispatcher.DispatchOnce(context.Background())
+
+	if repo.messages[message.ID].Status != models.OutboxFailed {
+		t.Errorf("Expected unknown kind to fail, got %s", repo.messages[message.ID].Status)
+	}
+}
+
+// blockingNotificationClient holds each notification until release is closed
+// or its context ends
+type blockingNotificationClient struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingNotificationClient) SendNotification(notification notifications.Notification) error {
+	return c.SendNotificationWithContext(context.Background(), notification)
+}
+
+func (c *blockingNotificationClient) SendNotificationWithContext(ctx context.Context, notification notifications.Notification) error {
+	close(c.started)
+	select {
+	case <-c.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestOutboxDispatcherShutdownAwaitsDelivery(t *testing.T) {
+	repo := newMockOutboxRepository()
+	client := &blockingNotificationClient{started: make(chan struct{}), release: make(chan struct{})}
+	dispatcher := NewOutboxDispatcher(repo, client)
+
+	message := repo.add(ComputerLimitNotification, testNotificationPayload)
+	go dispatcher.Run(context.Background())
+	<-client.started
+
+	result := make(chan error)
+	go func() { result <- dispatcher.Shutdown(context.Background()) }()
+	close(client.release)
+
+	if err := <-result; err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if repo.messages[message.ID].Status != models.OutboxDelivered {
+		t.Errorf("Expected the delivery in progress to finish, got %s", repo.messages[message.ID].Status)
+	}
+}
+
+func TestOutboxDispatcherShutdownCancelsAfterDeadline(t *testing.T) {
+	repo := newMockOutboxRepository()
+	client := &blockingNotificationClient{started: make(chan struct{}), release: make(chan struct{})}
+	dispatcher := NewOutboxDispatcher(repo, client)
+
+	message := repo.add(ComputerLimitNotification, testNotificationPayload)
+	go dispatcher.Run(context.Background())
+	<-client.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := dispatcher.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	// The message is left to be retried, not counted as failed
+	stored := repo.messages[message.ID]
+	if stored.Status != models.OutboxPending || stored.LastError != "" {
+		t.Errorf("Expected the message to stay pending, got %+v", stored)
+	}
+}
+
+// callbackNotificationClient calls fn before each notification it accepts
+type callbackNotificationClient struct {
+	fn func()
+}
+
+func (c *callbackNotificationClient) SendNotification(notification notifications.Notification) error {
+	return c.SendNotificationWithContext(context.Background(), notification)
+}
+
+func (c *callbackNotificationClient) SendNotificationWithContext(ctx context.Context, notification notifications.Notification) error {
+	c.fn()
+	return nil
+}
+
+func TestOutboxDispatcherLeasesFromEachClaim(t *testing.T) {
+	repo := newMockOutboxRepository()
+	const delay = 20 * time.Millisecond
+	dispatcher := NewOutboxDispatcher(repo, &callbackNotificationClient{fn: func() { time.Sleep(delay) }})
+
+	repo.add(ComputerLimitNotification, testNotificationPayload)
+	repo.add(ComputerLimitNotification, testNotificationPayload)
+	if delivered, err := dispatcher.DispatchOnce(context.Background()); err != nil || delivered != 2 {
+		t.Fatalf("Expected 2 deliveries, got %d, %v", delivered, err)
+	}
+	if len(repo.leases) != 2 || repo.leases[1].Sub(repo.leases[0]) < delay {
+		t.Errorf("Expected the second lease to start after the first delivery, got %v", repo.leases)
+	}
+}
+
+func TestOutboxDispatcherRecordsDeliveryWhenCancelled(t *testing.T) {
+	repo := newMockOutboxRepository()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The dispatcher is cancelled after the notification was sent
+	dispatcher := NewOutboxDispatcher(repo, &callbackNotificationClient{fn: cancel})
+
+	message := repo.add(ComputerLimitNotification, testNotificationPayload)
+	dispatcher.DispatchOnce(ctx)
+
+	if stored := repo.messages[message.ID]; stored.Status != models.OutboxDelivered {
+		t.Errorf("Expected the sent message to be recorded as delivered, got %s", stored.Status)
+	}
+}
+
+func TestOutboxServiceStuckMessages(t *testing.T) {
+	repo := newMockOutboxRepository()
+	service := NewOutboxService(repo)
+
+	repo.add(ComputerLimitNotification, testNotificationPayload)
+	old := repo.add(ComputerLimitNotification, testNotificationPayload)
+	old.CreatedAt = time.Now().Add(-time.Hour)
+
+	stuck, err := service.GetStuckMessages(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(stuck) != 1 || stuck[0].ID != old.ID {
+		t.Errorf("Expected only message %d to be stuck, got %+v", old.ID, stuck)
+	}
+
+	if _, err := service.GetMessages(context.Background(), models.OutboxQueryOptions{Status: "bogus"}); err == nil {
+		t.Error("Expected error for invalid status")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	if retryDelay(1) != outboxRetryBaseDelay {
+		t.Errorf("Expected base delay, got %v", retryDelay(1))
+	}
+	if retryDelay(3) != 4*outboxRetryBaseDelay {
+		t.Errorf("Expected 4x base delay, got %v", retryDelay(3))
+	}
+	if retryDelay(50) != outboxRetryMaxDelay {
+		t.Errorf("Expected max delay, got %v", retryDelay(50))
+	}
+}
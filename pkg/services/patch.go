@@ -0,0 +1,93 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+var (
+	// ErrInvalidPatch is returned when a patch document cannot be parsed or applied
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrPatchTestFailed is returned when a JSON Patch "test" operation does not match
+	ErrPatchTestFailed = errors.New("patch test operation failed")
+	// ErrUnsupportedPatchType is returned for unknown patch media types
+	ErrUnsupportedPatchType = errors.New("unsupported patch type")
+)
+
+// PatchComputer applies a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902)
+// document to the stored computer and saves the result through UpdateComputer.
+// A non-zero version must match the stored version.
+func (s *computerService) PatchComputer(ctx context.Context, id uint, version uint, patchType models.PatchType, patch []byte) (*models.Computer, error) {
+	if id == 0 {
+		return nil, errInvalidID
+	}
+
+	existing, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, notFound(ErrComputerNotFound, err)
+	}
+	if version != 0 && version != existing.Version {
+		return nil, models.ErrVersionConflict
+	}
+
+	original, err := json.Marshal(existing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode computer: %w", err)
+	}
+
+	patched, err := applyPatch(patchType, original, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	var computer models.Computer
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&computer); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	// Identity, creation time and version cannot be changed through a patch
+	computer.ID = existing.ID
+	computer.CreatedAt = existing.CreatedAt
+	computer.Version = existing.Version
+
+	if err := s.UpdateComputer(ctx, &computer); err != nil {
+		return nil, err
+	}
+	return &computer, nil
+}
+
+// applyPatch applies a patch document of the given type to a JSON document
+func applyPatch(patchType models.PatchType, original, patch []byte) ([]byte, error) {
+	switch patchType {
+	case models.MergePatch:
+		patched, err := jsonpatch.MergePatch(original, patch)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		return patched, nil
+
+	case models.JSONPatch:
+		operations, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		patched, err := operations.Apply(original)
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil, fmt.Errorf("%w: %v", ErrPatchTestFailed, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+		return patched, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedPatchType, patchType)
+}
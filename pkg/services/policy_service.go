@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrPolicyNotFound is returned when the requested policy does not exist
+	ErrPolicyNotFound = errors.New("policy not found")
+	// ErrPolicyExists is returned when a policy for the same scope and subject already exists
+	ErrPolicyExists = errors.New("policy already exists")
+	// ErrAssignmentBlocked is returned when an assignment would reach a block threshold
+	ErrAssignmentBlocked = errors.New("assignment blocked by policy")
+)
+
+// Notification levels a warn threshold can use
+var notificationLevels = map[string]bool{
+	"info":     true,
+	"warning":  true,
+	"critical": true,
+}
+
+// defaultPolicy applies when no policy is configured: notify with level
+// warning once an employee has 3 or more computers
+var defaultPolicy = models.AssignmentPolicy{
+	Scope: models.PolicyScopeGlobal,
+	Thresholds: []models.PolicyThreshold{
+		{Count: 3, Action: models.ThresholdWarn, Level: "warning"},
+	},
+}
+
+type policyService struct {
+	repo models.PolicyRepository
+}
+
+// NewPolicyService creates a new policy service
+func NewPolicyService(repo models.PolicyRepository) models.PolicyService {
+	return &policyService{repo: repo}
+}
+
+// CreatePolicy creates a new policy with validation
+func (s *policyService) CreatePolicy(ctx context.Context, policy *models.AssignmentPolicy) error {
+	if err := validatePolicy(policy); err != nil {
+		return err
+	}
+
+	if _, err := s.repo.GetByScope(ctx, policy.Scope, policy.Subject); err == nil {
+		return fmt.Errorf("%w: %s %s", ErrPolicyExists, policy.Scope, policy.Subject)
+	}
+
+	policy.ID = 0
+	if err := s.repo.Create(ctx, policy); err != nil {
+		return fmt.Errorf("failed to create policy: %w", err)
+	}
+	return nil
+}
+
+// GetAllPolicies retrieves all policies
+func (s *policyService) GetAllPolicies(ctx context.Context) ([]models.AssignmentPolicy, error) {
+	policies, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get policies: %w", err)
+	}
+	return policies, nil
+}
+
+// GetPolicy retrieves a policy by ID
+func (s *policyService) GetPolicy(ctx context.Context, id uint) (*models.AssignmentPolicy, error) {
+	if id == 0 {
+		return nil, errInvalidID
+	}
+
+	policy, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, notFound(ErrPolicyNotFound, err)
+	}
+	return policy, nil
+}
+
+// UpdatePolicy replaces a policy and its thresholds with validation
+func (s *policyService) UpdatePolicy(ctx context.Context, policy *models.AssignmentPolicy) error {
+	existing, err := s.GetPolicy(ctx, policy.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := validatePolicy(policy); err != nil {
+		return err
+	}
+
+	if other, err := s.repo.GetByScope(ctx, policy.Scope, policy.Subject); err == nil && other.ID != policy.ID {
+		return fmt.Errorf("%w: %s %s", ErrPolicyExists, policy.Scope, policy.Subject)
+	}
+	policy.CreatedAt = existing.CreatedAt
+
+	if err := s.repo.Update(ctx, policy); err != nil {
+		return fmt.Errorf("failed to update policy: %w", err)
+	}
+	return nil
+}
+
+// DeletePolicy deletes a policy by ID
+func (s *policyService) DeletePolicy(ctx context.Context, id uint) error {
+	if _, err := s.GetPolicy(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete policy: %w", err)
+	}
+	return nil
+}
+
+// ApplyPolicies creates or replaces policies by scope and subject, e.g. from a
+// configuration file at startup. Policies not listed are left untouched.
+func (s *policyService) ApplyPolicies(ctx context.Context, policies []models.AssignmentPolicy) error {
+	for i := range policies {
+		policy := &policies[i]
+		if err := validatePolicy(policy); err != nil {
+			return fmt.Errorf("policy %d: %w", i, err)
+		}
+
+		existing, err := s.repo.GetByScope(ctx, policy.Scope, policy.Subject)
+		if err != nil {
+			if err := s.repo.Create(ctx, policy); err != nil {
+				return fmt.Errorf("failed to create policy %s %s: %w", policy.Scope, policy.Subject, err)
+			}
+			continue
+		}
+
+		policy.ID = existing.ID
+		policy.CreatedAt = existing.CreatedAt
+		if err := s.repo.Update(ctx, policy); err != nil {
+			return fmt.Errorf("failed to update policy %s %s: %w", policy.Scope, policy.Subject, err)
+		}
+	}
+	return nil
+}
+
+// validatePolicy validates and normalises policy input data and reports every
+// invalid field
+func validatePolicy(policy *models.AssignmentPolicy) error {
+	policy.Subject = strings.TrimSpace(policy.Subject)
+
+	verr := &ValidationError{}
+	switch policy.Scope {
+	case models.PolicyScopeGlobal:
+		if policy.Subject != "" {
+			verr.Add("subject", "must be empty for global policies")
+		}
+	case models.PolicyScopeDepartment:
+		if policy.Subject == "" {
+			verr.Add("subject", "department policies require the department as subject")
+		}
+	case models.PolicyScopeEmployee:
+		if err := validateEmployeeAbbreviation(policy.Subject); err != nil {
+			verr.Add("subject", err.Error())
+		}
+	default:
+		verr.Add("scope", fmt.Sprintf("invalid scope %q, expected global, department or employee", policy.Scope))
+	}
+
+	if len(policy.Thresholds) == 0 {
+		verr.Add("thresholds", "policy requires at least one threshold")
+	}
+
+	block := 0
+	warnCounts := make(map[int]bool)
+	for i := range policy.Thresholds {
+		threshold := &policy.Thresholds[i]
+		field := fmt.Sprintf("thresholds[%d]", i)
+		if threshold.Count < 1 {
+			verr.Add(field+".count", "must be at least 1")
+		}
+
+		switch threshold.Action {
+		case models.ThresholdWarn:
+			if threshold.Level == "" {
+				threshold.Level = "warning"
+			}
+			if !notificationLevels[threshold.Level] {
+				verr.Add(field+".level", fmt.Sprintf("invalid notification level %q, expected info, warning or critical", threshold.Level))
+			}
+			if warnCounts[threshold.Count] {
+				verr.Add(field+".count", fmt.Sprintf("duplicate warn threshold at %d computers", threshold.Count))
+			}
+			warnCounts[threshold.Count] = true
+		case models.ThresholdBlock:
+			if block != 0 {
+				verr.Add(field+".action", "policy may have only one block threshold")
+			}
+			if threshold.Level != "" {
+				verr.Add(field+".level", "block thresholds do not send notifications and take no level")
+			}
+			if block == 0 {
+				block = threshold.Count
+			}
+		default:
+			verr.Add(field+".action", fmt.Sprintf("invalid action %q, expected warn or block", threshold.Action))
+		}
+	}
+
+	if block != 0 {
+		for i, threshold := range policy.Thresholds {
+			if threshold.Action == models.ThresholdWarn && threshold.Count >= block {
+				verr.Add(fmt.Sprintf("thresholds[%d].count", i),
+					fmt.Sprintf("warn threshold at %d computers is never reached, assignments are blocked at %d", threshold.Count, block))
+			}
+		}
+	}
+	if err := verr.errorOrNil(); err != nil {
+		return err
+	}
+
+	sort.Slice(policy.Thresholds, func(i, j int) bool {
+		return policy.Thresholds[i].Count < policy.Thresholds[j].Count
+	})
+	return nil
+}
+
+// policyFor returns the most specific policy for an employee: their own,
+// their department's, the global one or the built-in default
+func policyFor(ctx context.Context, repo models.PolicyRepository, employee *models.Employee) (*models.AssignmentPolicy, error) {
+	policies, err := repo.GetApplicable(ctx, employee)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load assignment policies: %w", err)
+	}
+	return mostSpecificPolicy(policies, employee), nil
+}
+
+// mostSpecificPolicy returns the most specific of policies that applies to an
+// employee, or the built-in default if none does
+func mostSpecificPolicy(policies []models.AssignmentPolicy, employee *models.Employee) *models.AssignmentPolicy {
+	rank := map[string]int{
+		models.PolicyScopeGlobal:     1,
+		models.PolicyScopeDepartment: 2,
+		models.PolicyScopeEmployee:   3,
+	}
+
+	best := &defaultPolicy
+	for i := range policies {
+		policy := &policies[i]
+		switch {
+		case policy.Scope == models.PolicyScopeGlobal,
+			policy.Scope == models.PolicyScopeDepartment && policy.Subject == employee.Department,
+			policy.Scope == models.PolicyScopeEmployee && policy.Subject == employee.Abbreviation:
+		default:
+			continue
+		}
+		if best == &defaultPolicy || rank[policy.Scope] > rank[best.Scope] {
+			best = policy
+		}
+	}
+	return best
+}
+
+// evaluatePolicy checks a computer count against a policy. It returns the
+// block threshold if the count reaches it, and otherwise the highest warn
+// threshold reached, if any.
+func evaluatePolicy(policy *models.AssignmentPolicy, count int) (block, warn *models.PolicyThreshold) {
+	for i := range policy.Thresholds {
+		threshold := &policy.Thresholds[i]
+		if count < threshold.Count {
+			continue
+		}
+		switch threshold.Action {
+		case models.ThresholdBlock:
+			return threshold, nil
+		case models.ThresholdWarn:
+			if warn == nil || threshold.Count > warn.Count {
+				warn = threshold
+			}
+		}
+	}
+	return nil, warn
+}
+
+// describePolicy names a policy for error messages
+func describePolicy(policy *models.AssignmentPolicy) string {
+	if policy.Scope == models.PolicyScopeGlobal {
+		return "the global policy"
+	}
+	return fmt.Sprintf("the %s policy for %s", policy.Scope, policy.Subject)
+}
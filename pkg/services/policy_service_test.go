@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"greenbone-case-study/pkg/notifications"
+	"testing"
+)
+
+// Mock policy repository for testing
+type mockPolicyRepository struct {
+	policies map[uint]*models.AssignmentPolicy
+	nextID   uint
+	// applicableLoads counts GetApplicable calls
+	applicableLoads int
+}
+
+func newMockPolicyRepository() *mockPolicyRepository {
+	return &mockPolicyRepository{
+		policies: make(map[uint]*models.AssignmentPolicy),
+		nextID:   1,
+	}
+}
+
+func (m *mockPolicyRepository) Create(ctx context.Context, policy *models.AssignmentPolicy) error {
+	policy.ID = m.nextID
+	m.nextID++
+	m.policies[policy.ID] = policy
+	return nil
+}
+
+func (m *mockPolicyRepository) GetAll(ctx context.Context) ([]models.AssignmentPolicy, error) {
+	var result []models.AssignmentPolicy
+	for _, policy := range m.policies {
+		result = append(result, *policy)
+	}
+	return result, nil
+}
+
+func (m *mockPolicyRepository) GetByID(ctx context.Context, id uint) (*models.AssignmentPolicy, error) {
+	policy, exists := m.policies[id]
+	if !exists {
+		return nil, errors.New("record not found")
+	}
+	return policy, nil
+}
+
+func (m *mockPolicyRepository) GetByScope(ctx context.Context, scope, subject string) (*models.AssignmentPolicy, error) {
+	for _, policy := range m.policies {
+		if policy.Scope == scope && policy.Subject == subject {
+			return policy, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (m *mockPolicyRepository) GetApplicable(ctx context.Context, employee *models.Employee) ([]models.AssignmentPolicy, error) {
+	m.applicableLoads++
+	var result []models.AssignmentPolicy
+	for _, policy := range m.policies {
+		switch {
+		case policy.Scope == models.PolicyScopeGlobal,
+			policy.Scope == models.PolicyScopeDepartment && policy.Subject == employee.Department,
+			policy.Scope == models.PolicyScopeEmployee && policy.Subject == employee.Abbreviation:
+			result = append(result, *policy)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockPolicyRepository) Update(ctx context.Context, policy *models.AssignmentPolicy) error {
+	m.policies[policy.ID] = policy
+	return nil
+}
+
+func (m *mockPolicyRepository) Delete(ctx context.Context, id uint) error {
+	delete(m.policies, id)
+	return nil
+}
+
+func TestCreatePolicyValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  models.AssignmentPolicy
+		wantErr bool
+	}{
+		{
+			name: "valid department policy",
+			policy: models.AssignmentPolicy{Scope: "department", Subject: "IT", Thresholds: []models.PolicyThreshold{
+				{Count: 5, Action: "warn", Level: "info"},
+				{Count: 8, Action: "warn", Level: "critical"},
+				{Count: 10, Action: "block"},
+			}},
+		},
+		{
+			name:    "unknown scope",
+			policy:  models.AssignmentPolicy{Scope: "team", Subject: "x", Thresholds: []models.PolicyThreshold{{Count: 3, Action: "warn"}}},
+			wantErr: true,
+		},
+		{
+			name:    "global policy with subject",
+			policy:  models.AssignmentPolicy{Scope: "global", Subject: "IT", Thresholds: []models.PolicyThreshold{{Count: 3, Action: "warn"}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid employee subject",
+			policy:  models.AssignmentPolicy{Scope: "employee", Subject: "ABCD", Thresholds: []models.PolicyThreshold{{Count: 3, Action: "warn"}}},
+			wantErr: true,
+		},
+		{
+			name:    "no thresholds",
+			policy:  models.AssignmentPolicy{Scope: "global"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid level",
+			policy:  models.AssignmentPolicy{Scope: "global", Thresholds: []models.PolicyThreshold{{Count: 3, Action: "warn", Level: "loud"}}},
+			wantErr: true,
+		},
+		{
+			name: "two block thresholds",
+			policy: models.AssignmentPolicy{Scope: "global", Thresholds: []models.PolicyThreshold{
+				{Count: 3, Action: "block"},
+				{Count: 4, Action: "block"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "warn after block",
+			policy: models.AssignmentPolicy{Scope: "global", Thresholds: []models.PolicyThreshold{
+				{Count: 3, Action: "block"},
+				{Count: 4, Action: "warn"},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewPolicyService(newMockPolicyRepository())
+			err := service.CreatePolicy(context.Background(), &tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreatePolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreatePolicyDuplicate(t *testing.T) {
+	service := NewPolicyService(newMockPolicyRepository())
+
+	policy := models.AssignmentPolicy{Scope: "global", Thresholds: []models.PolicyThreshold{{Count: 3, Action: "warn"}}}
+	if err := service.CreatePolicy(context.Background(), &policy); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if policy.Thresholds[0].Level != "warning" {
+		t.Errorf("Expected default level warning, got %s", policy.Thresholds[0].Level)
+	}
+
+	duplicate := models.AssignmentPolicy{Scope: "global", Thresholds: []models.PolicyThreshold{{Count: 5, Action: "block"}}}
+	if err := service.CreatePolicy(context.Background(), &duplicate); !errors.Is(err, ErrPolicyExists) {
+		t.Errorf("Expected ErrPolicyExists, got %v", err)
+	}
+}
+
+func TestApplyPoliciesUpserts(t *testing.T) {
+	repo := newMockPolicyRepository()
+	service := NewPolicyService(repo)
+
+	policies := []models.AssignmentPolicy{
+		{Scope: "global", Thresholds: []models.PolicyThreshold{{Count: 3, Action: "warn"}}},
+	}
+	if err := service.ApplyPolicies(context.Background(), policies); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	policies = []models.AssignmentPolicy{
+		{Scope: "global", Thresholds: []models.PolicyThreshold{{Count: 4, Action: "block"}}},
+	}
+	if err := service.ApplyPolicies(context.Background(), policies); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(repo.policies) != 1 {
+		t.Fatalf("Expected 1 policy, got %d", len(repo.policies))
+	}
+	if repo.policies[1].Thresholds[0].Action != "block" {
+		t.Errorf("Expected policy to be replaced, got %+v", repo.policies[1])
+	}
+}
+
+func TestAssignmentPolicyPrecedence(t *testing.T) {
+	repo := newMockRepository()
+	repo.employees.employees["abc"].Department = "IT"
+	service := NewComputerService(repo, repo.employees, repo.policies)
+	policies := NewPolicyService(repo.policies)
+
+	for _, policy := range []models.AssignmentPolicy{
+		{Scope: "global", Thresholds: []models.PolicyThreshold{{Count: 1, Action: "block"}}},
+		{Scope: "department", Subject: "IT", Thresholds: []models.PolicyThreshold{{Count: 2, Action: "block"}}},
+		{Scope: "employee", Subject: "abc", Thresholds: []models.PolicyThreshold{
+			{Count: 2, Action: "warn", Level: "info"},
+			{Count: 3, Action: "warn", Level: "critical"},
+			{Count: 4, Action: "block"},
+		}},
+	} {
+		if err := policies.CreatePolicy(context.Background(), &policy); err != nil {
+			t.Fatalf("Failed to create policy: %v", err)
+		}
+	}
+
+	abbr := "abc"
+	for i := 1; i <= 4; i++ {
+		err := service.CreateComputer(context.Background(), &models.Computer{
+			MACAddress:           fmt.Sprintf("00:11:22:33:44:%02d", i),
+			ComputerName:         fmt.Sprintf("Computer %d", i),
+			IPAddress:            fmt.Sprintf("10.0.0.%d", i),
+			EmployeeAbbreviation: &abbr,
+		})
+		if i < 4 && err != nil {
+			t.Fatalf("Expected computer %d to be assigned, got: %v", i, err)
+		}
+		if i == 4 && !errors.Is(err, ErrAssignmentBlocked) {
+			t.Fatalf("Expected ErrAssignmentBlocked for computer 4, got %v", err)
+		}
+	}
+
+	// The employee policy applies: info at 2, critical at 3
+	var levels []string
+	for _, message := range repo.outbox {
+		var notification notifications.Notification
+		json.Unmarshal([]byte(message.Payload), &notification)
+		levels = append(levels, notification.Level)
+	}
+	if fmt.Sprint(levels) != "[info critical]" {
+		t.Errorf("Expected levels [info critical], got %v", levels)
+	}
+	if len(repo.computers) != 3 {
+		t.Errorf("Expected blocked computer not to be created, got %d computers", len(repo.computers))
+	}
+}
+
+func TestEvaluatePolicy(t *testing.T) {
+	policy := &models.AssignmentPolicy{Thresholds: []models.PolicyThreshold{
+		{Count: 3, Action: "warn", Level: "info"},
+		{Count: 5, Action: "warn", Level: "critical"},
+		{Count: 7, Action: "block"},
+	}}
+
+	tests := []struct {
+		count     int
+		wantLevel string
+		wantBlock bool
+	}{
+		{2, "", false},
+		{3, "info", false},
+		{4, "info", false},
+		{6, "critical", false},
+		{7, "", true},
+	}
+
+	for _, tt := range tests {
+		block, warn := evaluatePolicy(policy, tt.count)
+		level := ""
+		if warn != nil {
+			level = warn.Level
+		}
+		if (block != nil) != tt.wantBlock || level != tt.wantLevel {
+			t.Errorf("evaluatePolicy(%d) = block %v, level %q; want block %v, level %q",
+				tt.count, block != nil, level, tt.wantBlock, tt.wantLevel)
+		}
+	}
+}
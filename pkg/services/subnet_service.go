@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"greenbone-case-study/pkg/models"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrSubnetNotFound is returned when the requested subnet does not exist
+	ErrSubnetNotFound = errors.New("subnet not found")
+	// ErrSubnetOverlaps is returned when a subnet would share addresses with another one
+	ErrSubnetOverlaps = errors.New("subnet overlaps another subnet")
+	// ErrSubnetExhausted is returned when a subnet has no free address left to allocate
+	ErrSubnetExhausted = errors.New("subnet has no free address")
+	// ErrIPAddressInUse is returned when another computer already has an address of a subnet
+	ErrIPAddressInUse = errors.New("IP address is in use by another computer")
+)
+
+type subnetService struct {
+	repo         models.SubnetRepository
+	computerRepo models.ComputerRepository
+}
+
+// NewSubnetService creates a new subnet service
+func NewSubnetService(repo models.SubnetRepository, computerRepo models.ComputerRepository) models.SubnetService {
+	return &subnetService{
+		repo:         repo,
+		computerRepo: computerRepo,
+	}
+}
+
+// CreateSubnet creates a new subnet with validation
+func (s *subnetService) CreateSubnet(ctx context.Context, subnet *models.Subnet) error {
+	if err := validateSubnet(subnet); err != nil {
+		return err
+	}
+	subnet.ID = 0
+
+	return s.repo.Transaction(ctx, func(tx models.SubnetRepository) error {
+		if err := checkOverlap(ctx, tx, subnet); err != nil {
+			return err
+		}
+		if err := tx.Create(ctx, subnet); err != nil {
+			return fmt.Errorf("failed to create subnet: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetAllSubnets retrieves all subnets in address order
+func (s *subnetService) GetAllSubnets(ctx context.Context) ([]models.Subnet, error) {
+	subnets, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subnets: %w", err)
+	}
+	return subnets, nil
+}
+
+// GetSubnet retrieves a subnet by ID
+func (s *subnetService) GetSubnet(ctx context.Context, id uint) (*models.Subnet, error) {
+	if id == 0 {
+		return nil, errInvalidID
+	}
+
+	subnet, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, notFound(ErrSubnetNotFound, err)
+	}
+	return subnet, nil
+}
+
+// UpdateSubnet replaces a subnet with validation. Computers keep their
+// addresses, even if they no longer lie within the subnet.
+func (s *subnetService) UpdateSubnet(ctx context.Context, subnet *models.Subnet) error {
+	existing, err := s.GetSubnet(ctx, subnet.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := validateSubnet(subnet); err != nil {
+		return err
+	}
+	subnet.CreatedAt = existing.CreatedAt
+
+	return s.repo.Transaction(ctx, func(tx models.SubnetRepository) error {
+		if err := checkOverlap(ctx, tx, subnet); err != nil {
+			return err
+		}
+		if err := tx.Update(ctx, subnet); err != nil {
+			return fmt.Errorf("failed to update subnet: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteSubnet deletes a subnet by ID. Computers keep their addresses.
+func (s *subnetService) DeleteSubnet(ctx context.Context, id uint) error {
+	if _, err := s.GetSubnet(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete subnet: %w", err)
+	}
+	return nil
+}
+
+// GetSubnetUtilization counts the reserved, used and free addresses of a subnet
+func (s *subnetService) GetSubnetUtilization(ctx context.Context, id uint) (*models.SubnetUtilization, error) {
+	subnet, err := s.GetSubnet(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	used, computers, err := s.computerRepo.GetIPAddressesInNetwork(ctx, subnet.Prefix())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get addresses of subnet %s: %w", subnet.CIDR, err)
+	}
+	return subnet.Utilization(used, computers), nil
+}
+
+// checkOverlap returns ErrSubnetOverlaps if another subnet shares addresses
+// with subnet. It runs in the transaction that writes subnet, so no
+// overlapping subnet can be written in between.
+func checkOverlap(ctx context.Context, tx models.SubnetRepository, subnet *models.Subnet) error {
+	others, err := tx.GetOverlapping(ctx, subnet.Prefix())
+	if err != nil {
+		return fmt.Errorf("failed to check overlapping subnets: %w", err)
+	}
+	for _, other := range others {
+		if other.ID != subnet.ID {
+			return fmt.Errorf("%w: %s overlaps %s", ErrSubnetOverlaps, subnet.CIDR, other.CIDR)
+		}
+	}
+	return nil
+}
+
+// validateSubnet validates and normalises subnet input data and reports every
+// invalid field. The CIDR is masked and addresses are stored in canonical
+// form; reserved ranges are sorted and a range of one address has no end.
+func validateSubnet(subnet *models.Subnet) error {
+	subnet.Description = strings.TrimSpace(subnet.Description)
+
+	verr := &ValidationError{}
+	var prefix netip.Prefix
+	if subnet.CIDR == "" {
+		verr.Add("cidr", "is required")
+	} else if p, err := models.ParseIPNetwork(subnet.CIDR); err != nil {
+		verr.Add("cidr", "must be a network in CIDR notation, e.g. 10.20.0.0/24 or 2001:db8::/64")
+	} else {
+		prefix = p
+		subnet.CIDR = prefix.String()
+	}
+
+	if subnet.Gateway != "" {
+		if gateway, err := models.ParseIPAddress(subnet.Gateway); err != nil {
+			verr.Add("gateway", "must be a valid IPv4 or IPv6 address")
+		} else {
+			subnet.Gateway = gateway
+			if prefix.IsValid() && !prefix.Contains(netip.MustParseAddr(gateway)) {
+				verr.Add("gateway", fmt.Sprintf("must lie within %s", prefix))
+			}
+		}
+	}
+
+	type parsedRange struct {
+		index    int
+		from, to netip.Addr
+	}
+	var ranges []parsedRange
+	for i := range subnet.ReservedRanges {
+		r := &subnet.ReservedRanges[i]
+		field := fmt.Sprintf("reserved_ranges[%d]", i)
+		from, to, err := r.Addrs()
+		if err != nil {
+			verr.Add(field, err.Error())
+			continue
+		}
+		if prefix.IsValid() && (!prefix.Contains(from) || !prefix.Contains(to)) {
+			verr.Add(field, fmt.Sprintf("must lie within %s", prefix))
+			continue
+		}
+		r.Start, r.End = from.String(), to.String()
+		if from == to {
+			r.End = ""
+		}
+		ranges = append(ranges, parsedRange{i, from, to})
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].from.Less(ranges[j].from) })
+	for i := 1; i < len(ranges); i++ {
+		if prev := ranges[i-1]; !prev.to.Less(ranges[i].from) {
+			verr.Add(fmt.Sprintf("reserved_ranges[%d]", ranges[i].index),
+				fmt.Sprintf("overlaps reserved_ranges[%d]", prev.index))
+		}
+	}
+	if err := verr.errorOrNil(); err != nil {
+		return err
+	}
+
+	if subnet.ReservedRanges == nil {
+		subnet.ReservedRanges = models.IPRanges{}
+	}
+	sort.SliceStable(subnet.ReservedRanges, func(i, j int) bool {
+		from, _, _ := subnet.ReservedRanges[i].Addrs()
+		other, _, _ := subnet.ReservedRanges[j].Addrs()
+		return from.Less(other)
+	})
+	return nil
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"greenbone-case-study/internal/db"
+	"greenbone-case-study/pkg/models"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func newSubnetTestServices(t *testing.T, options ComputerServiceOptions) (models.ComputerService, models.SubnetService) {
+	t.Helper()
+
+	database, err := db.InitDatabase(filepath.Join(t.TempDir(), "computers.db"), "sqlite")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(database) })
+
+	return newComputerServiceOn(database, options), NewSubnetService(models.NewSubnetRepository(database), models.NewComputerRepository(database))
+}
+
+func newComputerServiceOn(database *gorm.DB, options ComputerServiceOptions) models.ComputerService {
+	return NewComputerServiceWithOptions(models.NewComputerRepository(database), models.NewEmployeeRepository(database),
+		models.NewPolicyRepository(database), options)
+}
+
+func autoComputer(i int, subnetID uint) *models.Computer {
+	return &models.Computer{
+		MACAddress:   fmt.Sprintf("00:11:22:33:44:%02x", i),
+		ComputerName: fmt.Sprintf("Computer %d", i),
+		IPAddress:    models.AutoIPAddress,
+		SubnetID:     &subnetID,
+	}
+}
+
+func TestCreateSubnetValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		subnet models.Subnet
+		fields []string
+	}{
+		{"missing CIDR", models.Subnet{}, []string{"cidr"}},
+		{"invalid CIDR", models.Subnet{CIDR: "10.20.0.0"}, []string{"cidr"}},
+		{"gateway outside", models.Subnet{CIDR: "10.20.0.0/24", Gateway: "10.20.1.1"}, []string{"gateway"}},
+		{"invalid gateway", models.Subnet{CIDR: "10.20.0.0/24", Gateway: "router"}, []string{"gateway"}},
+		{"range outside", models.Subnet{CIDR: "10.20.0.0/24", ReservedRanges: models.IPRanges{{Start: "10.20.0.250", End: "10.20.1.5"}}}, []string{"reserved_ranges[0]"}},
+		{"range reversed", models.Subnet{CIDR: "10.20.0.0/24", ReservedRanges: models.IPRanges{{Start: "10.20.0.9", End: "10.20.0.2"}}}, []string{"reserved_ranges[0]"}},
+		{"ranges overlap", models.Subnet{CIDR: "10.20.0.0/24", ReservedRanges: models.IPRanges{{Start: "10.20.0.5", End: "10.20.0.20"}, {Start: "10.20.0.2", End: "10.20.0.5"}}}, []string{"reserved_ranges[0]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, service := newSubnetTestServices(t, ComputerServiceOptions{})
+			err := service.CreateSubnet(context.Background(), &tt.subnet)
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Expected a ValidationError, got %v", err)
+			}
+			if len(verr.Errors) != len(tt.fields) {
+				t.Fatalf("Expected invalid fields %v, got %+v", tt.fields, verr.Errors)
+			}
+			for i, field := range tt.fields {
+				if verr.Errors[i].Field != field {
+					t.Errorf("Expected invalid fields %v, got %+v", tt.fields, verr.Errors)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateSubnetNormalisesAndRejectsOverlaps(t *testing.T) {
+	_, service := newSubnetTestServices(t, ComputerServiceOptions{})
+	ctx := context.Background()
+
+	subnet := &models.Subnet{
+		CIDR:           "10.20.0.5/24",
+		Gateway:        "::ffff:10.20.0.1",
+		ReservedRanges: models.IPRanges{{Start: "10.20.0.200", End: "10.20.0.200"}, {Start: "10.20.0.2", End: "10.20.0.9"}},
+	}
+	if err := service.CreateSubnet(ctx, subnet); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if subnet.CIDR != "10.20.0.0/24" || subnet.Gateway != "10.20.0.1" {
+		t.Errorf("Expected a canonical CIDR and gateway, got %s and %s", subnet.CIDR, subnet.Gateway)
+	}
+	if fmt.Sprint(subnet.ReservedRanges) != "[{10.20.0.2 10.20.0.9} {10.20.0.200 }]" {
+		t.Errorf("Expected sorted ranges, got %v", subnet.ReservedRanges)
+	}
+
+	if err := service.CreateSubnet(ctx, &models.Subnet{CIDR: "10.20.0.128/25"}); !errors.Is(err, ErrSubnetOverlaps) {
+		t.Errorf("Expected ErrSubnetOverlaps, got %v", err)
+	}
+	if err := service.CreateSubnet(ctx, &models.Subnet{CIDR: "10.0.0.0/8"}); !errors.Is(err, ErrSubnetOverlaps) {
+		t.Errorf("Expected ErrSubnetOverlaps for an enclosing subnet, got %v", err)
+	}
+
+	// A subnet does not overlap itself
+	subnet.CIDR = "10.20.0.0/23"
+	if err := service.UpdateSubnet(ctx, subnet); err != nil {
+		t.Errorf("Expected the subnet to grow, got %v", err)
+	}
+	if _, err := service.GetSubnet(ctx, 99); !errors.Is(err, ErrSubnetNotFound) {
+		t.Errorf("Expected ErrSubnetNotFound, got %v", err)
+	}
+}
+
+func TestCreateSubnetConcurrentOverlaps(t *testing.T) {
+	_, subnetService := newSubnetTestServices(t, ComputerServiceOptions{})
+
+	// Every subnet overlaps the others, but has a CIDR of its own
+	const workers = 8
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	created := 0
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := subnetService.CreateSubnet(context.Background(), &models.Subnet{CIDR: fmt.Sprintf("10.0.0.0/%d", 16+i)})
+			if err != nil && !errors.Is(err, ErrSubnetOverlaps) {
+				t.Errorf("Expected ErrSubnetOverlaps, got %v", err)
+			}
+			if err == nil {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if subnets, _ := subnetService.GetAllSubnets(context.Background()); created != 1 || len(subnets) != 1 {
+		t.Errorf("Expected exactly one of the overlapping subnets, got %d created and %d stored", created, len(subnets))
+	}
+}
+
+func TestCreateComputerAllocatesIPAddress(t *testing.T) {
+	computerService, subnetService := newSubnetTestServices(t, ComputerServiceOptions{})
+	ctx := context.Background()
+
+	subnet := &models.Subnet{CIDR: "10.20.0.0/29", Gateway: "10.20.0.1", ReservedRanges: models.IPRanges{{Start: "10.20.0.2"}}}
+	if err := subnetService.CreateSubnet(ctx, subnet); err != nil {
+		t.Fatalf("Failed to create subnet: %v", err)
+	}
+
+	// .0 and .7 are the network and broadcast address
+	var allocated []string
+	for i := 0; i < 4; i++ {
+		computer := autoComputer(i, subnet.ID)
+		if err := computerService.CreateComputer(ctx, computer); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		allocated = append(allocated, computer.IPAddress)
+	}
+	if fmt.Sprint(allocated) != "[10.20.0.3 10.20.0.4 10.20.0.5 10.20.0.6]" {
+		t.Errorf("Expected the free addresses in order, got %v", allocated)
+	}
+
+	if err := computerService.CreateComputer(ctx, autoComputer(4, subnet.ID)); !errors.Is(err, ErrSubnetExhausted) {
+		t.Fatalf("Expected ErrSubnetExhausted, got %v", err)
+	}
+
+	utilization, err := subnetService.GetSubnetUtilization(ctx, subnet.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if utilization.Allocatable.Int64() != 4 || utilization.Free.Int64() != 0 || utilization.Utilization != 100 {
+		t.Errorf("Expected a full subnet, got %+v", utilization)
+	}
+
+	// An update keeps an address within the subnet, deleting frees it
+	computer, _ := computerService.GetComputerByID(ctx, 2)
+	computer.IPAddress = models.AutoIPAddress
+	computer.SubnetID = &subnet.ID
+	if err := computerService.UpdateComputer(ctx, computer); err != nil || computer.IPAddress != "10.20.0.4" {
+		t.Errorf("Expected the address to be kept, got %s, %v", computer.IPAddress, err)
+	}
+	if err := computerService.DeleteComputer(ctx, 2, 0); err != nil {
+		t.Fatalf("Failed to delete computer: %v", err)
+	}
+	computer = autoComputer(5, subnet.ID)
+	if err := computerService.CreateComputer(ctx, computer); err != nil || computer.IPAddress != "10.20.0.4" {
+		t.Errorf("Expected the freed address, got %s, %v", computer.IPAddress, err)
+	}
+}
+
+func TestCreateComputerRejectsInvalidAllocation(t *testing.T) {
+	computerService, subnetService := newSubnetTestServices(t, ComputerServiceOptions{})
+	ctx := context.Background()
+	subnet := &models.Subnet{CIDR: "10.20.0.0/24", Gateway: "10.20.0.1", ReservedRanges: models.IPRanges{{Start: "10.20.0.100", End: "10.20.0.110"}}}
+	subnetService.CreateSubnet(ctx, subnet)
+
+	explicit := func(ip string) *models.Computer {
+		computer := autoComputer(1, subnet.ID)
+		computer.IPAddress = ip
+		return computer
+	}
+	tests := []struct {
+		name     string
+		computer *models.Computer
+		field    string
+	}{
+		{"auto without subnet", &models.Computer{MACAddress: "00:11:22:33:44:01", ComputerName: "PC", IPAddress: models.AutoIPAddress}, "subnet_id"},
+		{"unknown subnet", autoComputer(1, 99), "subnet_id"},
+		{"address outside the subnet", explicit("10.30.0.5"), "ip_address"},
+		{"network address", explicit("10.20.0.0"), "ip_address"},
+		{"broadcast address", explicit("10.20.0.255"), "ip_address"},
+		{"gateway", explicit("10.20.0.1"), "ip_address"},
+		{"reserved range", explicit("10.20.0.105"), "ip_address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := computerService.CreateComputer(ctx, tt.computer)
+			var verr *ValidationError
+			if !errors.As(err, &verr) || verr.Errors[0].Field != tt.field {
+				t.Errorf("Expected %s to be invalid, got %v", tt.field, err)
+			}
+		})
+	}
+}
+
+func TestCreateComputerRejectsAddressInUse(t *testing.T) {
+	computerService, subnetService := newSubnetTestServices(t, ComputerServiceOptions{})
+	ctx := context.Background()
+	subnet := &models.Subnet{CIDR: "10.20.0.0/24"}
+	subnetService.CreateSubnet(ctx, subnet)
+
+	computer := autoComputer(1, subnet.ID)
+	computer.IPAddress = "10.20.0.5"
+	if err := computerService.CreateComputer(ctx, computer); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	other := autoComputer(2, subnet.ID)
+	other.IPAddress = "10.20.0.5"
+	if err := computerService.CreateComputer(ctx, other); !errors.Is(err, ErrIPAddressInUse) {
+		t.Fatalf("Expected ErrIPAddressInUse, got %v", err)
+	}
+
+	// A computer keeps its own address
+	computer.ComputerName = "Renamed"
+	if err := computerService.UpdateComputer(ctx, computer); err != nil {
+		t.Errorf("Expected the address to be kept, got: %v", err)
+	}
+}
+
+func TestCreateComputerConcurrentAllocations(t *testing.T) {
+	computerService, subnetService := newSubnetTestServices(t, ComputerServiceOptions{})
+	subnet := &models.Subnet{CIDR: "10.20.0.0/24"}
+	subnetService.CreateSubnet(context.Background(), subnet)
+
+	const workers = 10
+	var wg sync.WaitGroup
+	addresses := make(chan string, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			computer := autoComputer(i, subnet.ID)
+			if err := computerService.CreateComputer(context.Background(), computer); err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+			addresses <- computer.IPAddress
+		}(i)
+	}
+	wg.Wait()
+	close(addresses)
+
+	seen := make(map[string]bool)
+	for ip := range addresses {
+		if seen[ip] {
+			t.Errorf("Address %s was allocated twice", ip)
+		}
+		seen[ip] = true
+	}
+}
+
+func TestStrictIPAddresses(t *testing.T) {
+	database, err := db.InitDatabase(filepath.Join(t.TempDir(), "computers.db"), "sqlite")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close(database) })
+	lenient := newComputerServiceOn(database, ComputerServiceOptions{})
+	service := newComputerServiceOn(database, ComputerServiceOptions{StrictIPAddresses: true})
+	subnetService := NewSubnetService(models.NewSubnetRepository(database), models.NewComputerRepository(database))
+	ctx := context.Background()
+
+	unmanaged := &models.Computer{MACAddress: "00:11:22:33:44:01", ComputerName: "Legacy", IPAddress: "192.168.1.5"}
+	if err := lenient.CreateComputer(ctx, unmanaged); err != nil {
+		t.Fatalf("Expected unmanaged addresses without strict mode, got %v", err)
+	}
+	subnetService.CreateSubnet(ctx, &models.Subnet{CIDR: "10.20.0.0/24"})
+
+	err = service.CreateComputer(ctx, &models.Computer{MACAddress: "00:11:22:33:44:02", ComputerName: "New", IPAddress: "192.168.1.6"})
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Errors[0].Field != "ip_address" {
+		t.Errorf("Expected an address outside managed subnets to be rejected, got %v", err)
+	}
+	if err := service.CreateComputer(ctx, &models.Computer{MACAddress: "00:11:22:33:44:03", ComputerName: "New", IPAddress: "10.20.0.6"}); err != nil {
+		t.Errorf("Expected a managed address to be accepted, got %v", err)
+	}
+
+	// Addresses that do not change are not checked
+	unmanaged.Description = "Still in use"
+	if err := service.UpdateComputer(ctx, unmanaged); err != nil {
+		t.Errorf("Expected an unchanged address to be accepted, got %v", err)
+	}
+	unmanaged.IPAddress = "192.168.1.7"
+	if err := service.UpdateComputer(ctx, unmanaged); !errors.As(err, &verr) {
+		t.Errorf("Expected a new unmanaged address to be rejected, got %v", err)
+	}
+}
+
+func TestImportComputersAllocatesIPAddresses(t *testing.T) {
+	computerService, subnetService := newSubnetTestServices(t, ComputerServiceOptions{})
+	ctx := context.Background()
+	subnet := &models.Subnet{CIDR: "10.20.0.0/30"}
+	subnetService.CreateSubnet(ctx, subnet)
+
+	computers := []models.Computer{*autoComputer(1, subnet.ID), *autoComputer(2, subnet.ID), *autoComputer(3, subnet.ID)}
+	result, err := computerService.ImportComputers(ctx, computers, models.ImportOptions{Mode: models.ImportBestEffort})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.Created != 2 || result.Failed != 1 || result.Rows[2].Errors[0].Field != "ip_address" {
+		t.Fatalf("Expected the third row to find the subnet exhausted, got %+v", result)
+	}
+	if computers[0].IPAddress != "10.20.0.1" || computers[1].IPAddress != "10.20.0.2" {
+		t.Errorf("Expected consecutive addresses, got %s and %s", computers[0].IPAddress, computers[1].IPAddress)
+	}
+}
@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"errors"
+	"greenbone-case-study/internal/db/callbacks"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey stores the span of a statement in its gorm instance
+const spanKey = "tracing:span"
+
+// gormPlugin starts a span for every statement, as a child of the span in the
+// statement's context
+type gormPlugin struct{}
+
+// NewGormPlugin returns a gorm plugin that traces statements. Register it
+// with db.Use.
+func NewGormPlugin() gorm.Plugin {
+	return gormPlugin{}
+}
+
+// Name identifies the plugin to gorm
+func (gormPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize registers callbacks around each of gorm's operations
+func (gormPlugin) Initialize(db *gorm.DB) error {
+	return callbacks.RegisterAround(db, "tracing", startSpan, func(string) func(*gorm.DB) { return endSpan })
+}
+
+func startSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		_, span := Tracer().Start(db.Statement.Context, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemKey.String(db.Dialector.Name()),
+				semconv.DBOperationName(operation),
+			))
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func endSpan(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+
+	if db.Statement.Table != "" {
+		span.SetAttributes(semconv.DBCollectionName(db.Statement.Table))
+	}
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+
+	// Lookups that find nothing are expected, not failures
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	End(span, err)
+}
@@ -0,0 +1,129 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestExporter records the spans of the global tracer provider until the
+// test ends
+func newTestExporter(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return exporter
+}
+
+func TestTraceParentRoundTrip(t *testing.T) {
+	newTestExporter(t)
+
+	if TraceParent(context.Background()) != "" {
+		t.Error("Expected no traceparent without a span")
+	}
+
+	ctx, span := Tracer().Start(context.Background(), "request")
+	defer span.End()
+
+	traceparent := TraceParent(ctx)
+	if !strings.HasPrefix(traceparent, "00-"+span.SpanContext().TraceID().String()+"-") {
+		t.Fatalf("Unexpected traceparent %q", traceparent)
+	}
+
+	restored := trace.SpanContextFromContext(WithTraceParent(context.Background(), traceparent))
+	if restored.TraceID() != span.SpanContext().TraceID() || restored.SpanID() != span.SpanContext().SpanID() {
+		t.Errorf("Expected the span to be restored, got %v", restored)
+	}
+	if trace.SpanContextFromContext(WithTraceParent(context.Background(), "garbage")).IsValid() {
+		t.Error("Expected an invalid traceparent to be ignored")
+	}
+
+	header := http.Header{}
+	Inject(ctx, header)
+	if header.Get("traceparent") != traceparent {
+		t.Errorf("Expected traceparent header %q, got %q", traceparent, header.Get("traceparent"))
+	}
+	if got := trace.SpanContextFromContext(Extract(context.Background(), header)); got.TraceID() != span.SpanContext().TraceID() {
+		t.Errorf("Expected trace %s to be extracted, got %s", span.SpanContext().TraceID(), got.TraceID())
+	}
+}
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func TestGormPluginTracesStatementsInContext(t *testing.T) {
+	exporter := newTestExporter(t)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.Use(NewGormPlugin()); err != nil {
+		t.Fatalf("Failed to register plugin: %v", err)
+	}
+	if err := db.AutoMigrate(&widget{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	exporter.Reset()
+
+	ctx, parent := Tracer().Start(context.Background(), "request")
+	if err := db.WithContext(ctx).Create(&widget{Name: "a"}).Error; err != nil {
+		t.Fatalf("Failed to create widget: %v", err)
+	}
+	var missing widget
+	db.WithContext(ctx).First(&missing, 42)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+	for _, span := range spans[:2] {
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("Expected %s to be a child of the request span", span.Name)
+		}
+	}
+	if spans[0].Name != "gorm.create" || spans[1].Name != "gorm.query" {
+		t.Errorf("Unexpected span names %s, %s", spans[0].Name, spans[1].Name)
+	}
+	if spans[1].Status.Code != codes.Unset {
+		t.Errorf("Expected a lookup without result not to be an error, got %v", spans[1].Status)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	var out bytes.Buffer
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterStdout, ServiceName: "test", Writer: &out})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	_, span := Tracer().Start(context.Background(), "exported")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.Contains(out.String(), `"Name":"exported"`) {
+		t.Errorf("Expected the span to be written, got %s", out.String())
+	}
+
+	if _, err := Setup(context.Background(), Config{Exporter: "zipkin"}); err == nil {
+		t.Error("Expected an unsupported exporter to be rejected")
+	}
+}